@@ -0,0 +1,48 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"io"
+)
+
+// MaxReadAll is the most bytes of documents ReadAll reads, so a large or
+// malicious stream can't use all memory. Use a Scanner to read more.
+const MaxReadAll = 256 * 1024 * 1024
+
+// ReadAll reads documents until EOF. Returns error if the documents total more
+// than MaxReadAll bytes.
+func ReadAll(rd io.Reader) ([]BSON, error) {
+	var docs []BSON
+	total := 0
+	for {
+		max := int32(maxDocLen)
+		if MaxReadAll-total < maxDocLen {
+			max = int32(MaxReadAll - total)
+		}
+		bs, err := readOne(rd, nil, max)
+		if err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("doc %v, %w", len(docs), err)
+		}
+		docs = append(docs, bs)
+		total += len(bs)
+	}
+}
+
+// WriteAll encodes the documents and writes them one after the other.
+func WriteAll(wr io.Writer, docs []Doc) error {
+	for i, doc := range docs {
+		bs, err := doc.Encode()
+		if err != nil {
+			return fmt.Errorf("doc %v, %w", i, err)
+		}
+		if _, err := wr.Write(bs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadAll(t *testing.T) {
+	docs := []Doc{
+		Map{"a": Int32(1)},
+		Slice{{"b", String("x")}},
+		Map{}.MustEncode(),
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := WriteAll(buf, docs); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	all, err := ReadAll(bytes.NewReader(b))
+	if err != nil || len(all) != len(docs) {
+		t.Fatal(err, all)
+	}
+	for i, doc := range docs {
+		if !reflect.DeepEqual(all[i], doc.MustEncode()) {
+			t.Fatal(i, all[i])
+		}
+	}
+
+	// Empty.
+	all, err = ReadAll(bytes.NewReader(nil))
+	if err != nil || len(all) != 0 {
+		t.Fatal(err, all)
+	}
+
+	// Truncated.
+	if _, err := ReadAll(bytes.NewReader(b[:len(b)-1])); !errors.Is(err,
+		ErrTruncated) {
+
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// The Append functions build a document element by element, without a Map or
+// Slice and without reflection. The encoding is the same as Encode. For
+// example:
+//   b, start := bson.AppendDocStart(nil)
+//   b = bson.AppendString(b, "name", "Alice")
+//   b, arr := bson.AppendArrayStart(b, "tags")
+//   b = bson.AppendString(b, "0", "a")
+//   b = bson.AppendDocEnd(b, arr)
+//   bs := bson.BSON(bson.AppendDocEnd(b, start))
+// Keys aren't checked, see Encoder RejectNULKeys.
+
+// AppendDocStart appends the start of a document. Returns the start to pass to
+// AppendDocEnd when the elements have been appended.
+func AppendDocStart(b []byte) ([]byte, int) {
+	return append(b, 0, 0, 0, 0), len(b)
+}
+
+// AppendDocEnd appends the end of the document or array which begins at start,
+// and sets its length.
+func AppendDocEnd(b []byte, start int) []byte {
+	b = append(b, 0x00)
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(b)-start))
+	return b
+}
+
+// AppendDocumentStart appends the start of a embedded document element. The
+// elements of the document are appended after it, then AppendDocEnd.
+func AppendDocumentStart(b []byte, key string) ([]byte, int) {
+	return AppendDocStart(appendKey(b, _EMBEDDED_DOCUMENT, key))
+}
+
+// AppendArrayStart appends the start of a Array element. The elements are
+// appended after it with keys "0", "1", etc, then AppendDocEnd.
+func AppendArrayStart(b []byte, key string) ([]byte, int) {
+	return AppendDocStart(appendKey(b, _ARRAY, key))
+}
+
+// AppendDocument appends a embedded document element. The doc must be a
+// complete BSON document, such as from MarshalBSON.
+func AppendDocument(b []byte, key string, doc []byte) []byte {
+	return append(appendKey(b, _EMBEDDED_DOCUMENT, key), doc...)
+}
+
+// AppendFloat appends a Float element.
+func AppendFloat(b []byte, key string, v float64) []byte {
+	b = appendKey(b, _FLOATING_POINT, key)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+}
+
+// AppendString appends a String element.
+func AppendString(b []byte, key, v string) []byte {
+	b = appendKey(b, _STRING, key)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(v)+1))
+	return append(append(b, v...), 0x00)
+}
+
+// AppendBinary appends a Binary element, with the generic subtype.
+func AppendBinary(b []byte, key string, v []byte) []byte {
+	b = appendKey(b, _BINARY_DATA, key)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+	return append(append(b, 0x00), v...)
+}
+
+// AppendBool appends a Bool element.
+func AppendBool(b []byte, key string, v bool) []byte {
+	b = appendKey(b, _BOOLEAN, key)
+	if v {
+		return append(b, 0x01)
+	}
+	return append(b, 0x00)
+}
+
+// AppendTime appends a UTCDateTime element, see NewUTCDateTime.
+func AppendTime(b []byte, key string, v time.Time) []byte {
+	b = appendKey(b, _UTC_DATETIME, key)
+	return binary.LittleEndian.AppendUint64(b, uint64(NewUTCDateTime(v)))
+}
+
+// AppendNull appends a Null element.
+func AppendNull(b []byte, key string) []byte {
+	return appendKey(b, _NULL_VALUE, key)
+}
+
+// AppendInt32 appends a Int32 element.
+func AppendInt32(b []byte, key string, v int32) []byte {
+	b = appendKey(b, _32BIT_INTEGER, key)
+	return binary.LittleEndian.AppendUint32(b, uint32(v))
+}
+
+// AppendInt64 appends a Int64 element.
+func AppendInt64(b []byte, key string, v int64) []byte {
+	b = appendKey(b, _64BIT_INTEGER, key)
+	return binary.LittleEndian.AppendUint64(b, uint64(v))
+}
+
+// AppendValue appends a element of any value which can be encoded, the same as
+// a value in a Map. This is for types which have no Append function of their
+// own.
+func AppendValue(b []byte, key string, v interface{}) ([]byte, error) {
+	buf := bytes.NewBuffer(b)
+	if err := defaultEncoder.begin().encodeField(buf, key, key, v); err != nil {
+		return b, err
+	}
+	return buf.Bytes(), nil
+}
+
+// appendKey appends the type and key of a element.
+func appendKey(b []byte, t byte, key string) []byte {
+	b = append(b, t)
+	return append(append(b, key...), 0x00)
+}
@@ -0,0 +1,64 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAppend(t *testing.T) {
+	now := time.Unix(1500000000, 5e6).UTC()
+	exp := Slice{
+		{"float", Float(-1.5)},
+		{"nan", Float(math.NaN())},
+		{"string", String("foo")},
+		{"empty", String("")},
+		{"doc", Slice{{"a", Int32(1)}}},
+		{"raw", Slice{{"b", Bool(false)}}},
+		{"array", Array{Int64(1), Null{}}},
+		{"binary", Binary{1, 2}},
+		{"bool", Bool(true)},
+		{"time", NewUTCDateTime(now)},
+		{"null", Null{}},
+		{"int32", Int32(-1)},
+		{"int64", Int64(math.MinInt64)},
+		{"oid", ObjectId("0123456789ab")},
+	}.MustEncode()
+
+	b, start := AppendDocStart([]byte{0xFF})
+	b = AppendFloat(b, "float", -1.5)
+	b = AppendFloat(b, "nan", math.NaN())
+	b = AppendString(b, "string", "foo")
+	b = AppendString(b, "empty", "")
+	b, doc := AppendDocumentStart(b, "doc")
+	b = AppendInt32(b, "a", 1)
+	b = AppendDocEnd(b, doc)
+	b = AppendDocument(b, "raw", Slice{{"b", Bool(false)}}.MustEncode())
+	b, arr := AppendArrayStart(b, "array")
+	b = AppendInt64(b, "0", 1)
+	b = AppendNull(b, "1")
+	b = AppendDocEnd(b, arr)
+	b = AppendBinary(b, "binary", []byte{1, 2})
+	b = AppendBool(b, "bool", true)
+	b = AppendTime(b, "time", now)
+	b = AppendNull(b, "null")
+	b = AppendInt32(b, "int32", -1)
+	b = AppendInt64(b, "int64", math.MinInt64)
+	b, err := AppendValue(b, "oid", ObjectId("0123456789ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = AppendDocEnd(b, start)
+	if b[0] != 0xFF || !bytes.Equal(b[1:], exp) {
+		t.Fatal(b, exp)
+	}
+
+	// Values which can't be encoded.
+	if _, err := AppendValue(nil, "a", make(chan int)); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
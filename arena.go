@@ -0,0 +1,133 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+// arenaBlockSize is the size (bytes) of the blocks a Arena allocates binaries
+// from. Larger values get a block of their own.
+const arenaBlockSize = 64 * 1024
+
+// Arena decodes documents with memory which is reused after Release, instead
+// of allocating new memory for each document. Binaries are copied in to large
+// blocks, Slices and Arrays are carved out of larger slices, and Maps are
+// cleared and reused. This cuts GC pressure in pipelines which decode many
+// documents in batches. Keys and strings are allocated normally, a Go string
+// must never change.
+//
+// Binaries and documents decoded by a Arena are only valid until Release.
+// After Release the memory is reused, so those which are still referenced
+// change. Copy them if they must outlive the batch, strings may be kept. A
+// Arena is not safe for concurrent use.
+type Arena struct {
+	block   []byte   // Strings and binaries are appended.
+	blocks  [][]byte // Full blocks, dropped by Release.
+	pairs   []Pair   // Slices are carved from pairs[pairOff:].
+	pairOff int
+	vals    []interface{} // Arrays are carved from vals[valOff:].
+	valOff  int
+	maps    []Map  // Maps handed out since Release.
+	free    []Map  // Cleared Maps to hand out.
+	stack   []Pair // Reused by the rawDecoder.
+}
+
+// NewArena returns a empty Arena.
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// Map decodes the BSON to a Map, the same as BSON Map.
+func (this *Arena) Map(bs BSON) (Map, error) {
+	d := rawDecoder{arena: this, stack: this.stack}
+	v, err := d.doc(bs, "", 0)
+	this.stack = d.stack[:0]
+	if err != nil {
+		return nil, err
+	}
+	return v.(Map), nil
+}
+
+// Slice decodes the BSON to a Slice, the same as BSON Slice.
+func (this *Arena) Slice(bs BSON) (Slice, error) {
+	d := rawDecoder{arena: this, slice: true, stack: this.stack}
+	v, err := d.doc(bs, "", 0)
+	this.stack = d.stack[:0]
+	if err != nil {
+		return nil, err
+	}
+	return v.(Slice), nil
+}
+
+// Release makes the memory of everything decoded since the last Release
+// available for reuse.
+func (this *Arena) Release() {
+	this.block = this.block[:0]
+	this.blocks = nil
+	for _, m := range this.maps {
+		for k := range m {
+			delete(m, k)
+		}
+	}
+	this.free = append(this.free, this.maps...)
+	this.maps = this.maps[:0]
+	for i := 0; i < this.pairOff; i++ {
+		this.pairs[i] = Pair{}
+	}
+	this.pairOff = 0
+	for i := 0; i < this.valOff; i++ {
+		this.vals[i] = nil
+	}
+	this.valOff = 0
+}
+
+// bytes returns a copy of b.
+func (this *Arena) bytes(b []byte) []byte {
+	if len(b) > arenaBlockSize/4 {
+		cp := append([]byte(nil), b...)
+		this.blocks = append(this.blocks, cp)
+		return cp
+	}
+	if cap(this.block)-len(this.block) < len(b) {
+		if cap(this.block) != 0 {
+			this.blocks = append(this.blocks, this.block)
+		}
+		this.block = make([]byte, 0, arenaBlockSize)
+	}
+	start := len(this.block)
+	this.block = append(this.block, b...)
+	return this.block[start:len(this.block):len(this.block)]
+}
+
+// newMap returns a empty Map.
+func (this *Arena) newMap() Map {
+	var m Map
+	if n := len(this.free); n != 0 {
+		m = this.free[n-1]
+		this.free = this.free[:n-1]
+	} else {
+		m = Map{}
+	}
+	this.maps = append(this.maps, m)
+	return m
+}
+
+// newSlice returns a Slice of length n.
+func (this *Arena) newSlice(n int) Slice {
+	if len(this.pairs)-this.pairOff < n {
+		this.pairs = make([]Pair, max(1024, n))
+		this.pairOff = 0
+	}
+	s := this.pairs[this.pairOff : this.pairOff+n : this.pairOff+n]
+	this.pairOff += n
+	return s
+}
+
+// newArray returns a Array of length n.
+func (this *Arena) newArray(n int) Array {
+	if len(this.vals)-this.valOff < n {
+		this.vals = make([]interface{}, max(1024, n))
+		this.valOff = 0
+	}
+	a := this.vals[this.valOff : this.valOff+n : this.valOff+n]
+	this.valOff += n
+	return a
+}
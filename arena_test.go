@@ -0,0 +1,172 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// allTypes is a document with every BSON type.
+var allTypes = Slice{
+	{"float", Float(1.5)},
+	{"string", String("foo")},
+	{"empty", String("")},
+	{"doc", Slice{{"a", Int32(1)}, {"b", Array{Slice{{"c", Null{}}}}}}},
+	{"array", Array{String("x"), Array{Bool(true)}, Int64(2)}},
+	{"binary", Binary{1, 2, 3}},
+	{"undefined", Undefined{}},
+	{"objectid", ObjectId("0123456789ab")},
+	{"bool", Bool(true)},
+	{"utcdatetime", UTCDateTime(1)},
+	{"null", Null{}},
+	{"regexp", Regexp{"a.*", "i"}},
+	{"dbpointer", DBPointer{"db.c", ObjectId("0123456789ab")}},
+	{"javascript", Javascript("f()")},
+	{"symbol", Symbol("s")},
+	{"scope", JavascriptScope{"g()", Slice{{"d", Int32(1)}}}},
+	{"int32", Int32(1)},
+	{"timestamp", Timestamp(1)},
+	{"int64", Int64(1)},
+	{"minkey", MinKey{}},
+	{"maxkey", MaxKey{}},
+	{"long", String(strings.Repeat("x", arenaBlockSize))},
+}
+
+func TestArena(t *testing.T) {
+	bs := allTypes.MustEncode()
+	expMap, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expSlice, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	arena := NewArena()
+	for i := 0; i < 3; i++ {
+		m, err := arena.Map(bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, expMap) {
+			t.Fatal(m)
+		}
+		s, err := arena.Slice(bs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(s, expSlice) {
+			t.Fatal(s)
+		}
+		arena.Release()
+	}
+
+	// Maps are reused after Release.
+	m0, err := arena.Map(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arena.Release()
+	m1, err := arena.Map(Map{"a": Int32(1)}.MustEncode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.ValueOf(m0).Pointer() != reflect.ValueOf(m1).Pointer() {
+		t.Fatal("Map not reused")
+	}
+
+	// Keys and strings don't change after Release.
+	arena.Release()
+	m2, err := arena.Map(Map{"key": String("foo")}.MustEncode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := Map{}
+	for k, v := range m2 {
+		cp[k] = v
+	}
+	arena.Release()
+	if _, err := arena.Map(Map{"abc": String("bar")}.MustEncode()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cp, Map{"key": String("foo")}) {
+		t.Fatal(cp)
+	}
+
+	// Invalid.
+	if _, err := arena.Map(BSON{5, 0, 0, 0, 1}); err == nil {
+		t.Fatal("Expected error.")
+	}
+	if _, err := arena.Map(append(Map{"a": Int32(1)}.MustEncode()[:7], 0)); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
+
+func BenchmarkArenaMap(b *testing.B) {
+	bs := Map{
+		"name": String("foo"),
+		"tags": Array{String("a"), String("b")},
+		"addr": Map{"city": String("bar"), "zip": Int32(1)},
+	}.MustEncode()
+	arena := NewArena()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := arena.Map(bs); err != nil {
+			b.Fatal(err)
+		}
+		arena.Release()
+	}
+}
+
+func BenchmarkBSONMap(b *testing.B) {
+	bs := Map{
+		"name": String("foo"),
+		"tags": Array{String("a"), String("b")},
+		"addr": Map{"city": String("bar"), "zip": Int32(1)},
+	}.MustEncode()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := bs.Map(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBSONView(t *testing.T) {
+	bs := allTypes.MustEncode()
+	expMap, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.MapView()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, expMap) {
+		t.Fatal(m)
+	}
+	s, err := bs.SliceView()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, allTypes) {
+		t.Fatal(s)
+	}
+
+	// Values alias the BSON.
+	for i := range bs {
+		if bs[i] == 'f' && bs[i+1] == 'o' && bs[i+2] == 'o' {
+			bs[i] = 'g'
+		}
+	}
+	if m["string"] != String("goo") {
+		t.Fatal(m["string"])
+	}
+	bin := m["binary"].(Binary)
+	if len(bin) != cap(bin) {
+		t.Fatal("Binary can be appended to in to the BSON")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// DecodeBatch decodes docs to Maps using up to workers goroutines, calling fn
+// with the index and Map of each. If workers <= 0 GOMAXPROCS is used. fn is
+// called concurrently and in no particular order. After the first error no
+// more docs are started, and the error of the lowest index doc is returned.
+func DecodeBatch(docs []BSON, workers int, fn func(i int, m Map) error) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(docs) {
+		workers = len(docs)
+	}
+	var (
+		mu     sync.Mutex
+		next   int
+		errIdx = -1
+		err    error
+		wg     sync.WaitGroup
+	)
+	// take returns the index of the next doc to decode, or false if done.
+	take := func() (int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil || next == len(docs) {
+			return 0, false
+		}
+		next++
+		return next - 1, true
+	}
+	fail := func(i int, e error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil || i < errIdx {
+			errIdx, err = i, e
+		}
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, ok := take(); ok; i, ok = take() {
+				m, e := docs[i].Map()
+				if e != nil {
+					fail(i, fmt.Errorf("doc %v, %v", i, e))
+					continue
+				}
+				if e := fn(i, m); e != nil {
+					fail(i, e)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return err
+}
@@ -0,0 +1,49 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeBatch(t *testing.T) {
+	docs := make([]BSON, 100)
+	for i := range docs {
+		docs[i] = Map{"i": Int64(i)}.MustEncode()
+	}
+	got := make([]Int64, len(docs))
+	err := DecodeBatch(docs, 4, func(i int, m Map) error {
+		got[i] = m["i"].(Int64)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range got {
+		if v != Int64(i) {
+			t.Fatal(i, v)
+		}
+	}
+
+	// Decode error.
+	docs[7] = BSON{1, 2, 3}
+	err = DecodeBatch(docs, 0, func(i int, m Map) error { return nil })
+	if err == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// Error from fn.
+	docs[7] = docs[6]
+	fnErr := errors.New("fn")
+	err = DecodeBatch(docs, 1, func(i int, m Map) error {
+		if i == 3 {
+			return fnErr
+		}
+		return nil
+	})
+	if err != fnErr {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A block stream is documents compressed in blocks, so that documents which
+// repeat the same keys and values compress well, while any document can be
+// read by decompressing only its block. Each block is:
+//   magic     "BSZ"
+//   codec     byte, see RegisterCodec
+//   docs      uint32, number of documents
+//   raw len   uint32, length of the documents
+//   data len  uint32, length of the compressed documents
+//   data      the compressed documents
+// All integers are little endian.
+
+const (
+	blockMagic      = "BSZ"
+	blockHeaderSize = 16
+
+	// defaultBlockSize is the default BlockWriter BlockSize.
+	defaultBlockSize = 64 * 1024
+
+	// maxBlockLen is the largest raw or compressed block.
+	maxBlockLen = 2 * maxDocLen
+)
+
+// Codec compresses blocks. It must be safe for concurrent use.
+type Codec interface {
+	// Encode appends the compressed src to dst.
+	Encode(dst, src []byte) ([]byte, error)
+
+	// Decode appends the decompressed src to dst.
+	Decode(dst, src []byte) ([]byte, error)
+}
+
+// Codec IDs. None, flate and snappy are built in. Zstd isn't in the standard
+// library, so it must be registered with a implementation, for example one
+// which wraps github.com/klauspost/compress/zstd.
+const (
+	CodecNone   byte = 0
+	CodecFlate  byte = 1
+	CodecSnappy byte = 2
+	CodecZstd   byte = 3
+)
+
+// codecs are the registered codecs, keyed by ID.
+var codecs sync.Map
+
+func init() {
+	RegisterCodec(CodecNone, noneCodec{})
+	RegisterCodec(CodecFlate, flateCodec{})
+	RegisterCodec(CodecSnappy, snappyCodec{})
+}
+
+// RegisterCodec registers the codec with a ID, which is stored in each block.
+// A nil codec removes it.
+func RegisterCodec(id byte, c Codec) {
+	if c == nil {
+		codecs.Delete(id)
+		return
+	}
+	codecs.Store(id, c)
+}
+
+// lookupCodec returns the codec with the ID.
+func lookupCodec(id byte) (Codec, error) {
+	c, ok := codecs.Load(id)
+	if !ok {
+		return nil, fmt.Errorf("Codec %v not registered.", id)
+	}
+	return c.(Codec), nil
+}
+
+type noneCodec struct{}
+
+func (noneCodec) Encode(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+func (noneCodec) Decode(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+type flateCodec struct{}
+
+func (flateCodec) Encode(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	zw, err := flate.NewWriter(buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(src); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCodec) Decode(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	zr := flate.NewReader(bytes.NewReader(src))
+	defer zr.Close()
+	if _, err := io.Copy(buf, io.LimitReader(zr, maxBlockLen+1)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// BlockPos is the position of a document in a block stream.
+type BlockPos struct {
+	Block int64 // Offset of the block in the stream.
+	Index int   // Index of the document in the block.
+}
+
+// BlockWriter writes a block stream.
+type BlockWriter struct {
+	// BlockSize is the length of documents in a block before it's compressed
+	// and written. Larger blocks compress better, smaller blocks are faster to
+	// read a single document from. Defaults to 64KB.
+	BlockSize int
+
+	wr    io.Writer
+	codec byte
+	c     Codec
+	off   int64  // Offset of the block being built.
+	raw   []byte // Documents of the block being built.
+	docs  int
+}
+
+// NewBlockWriter returns a BlockWriter which writes to wr, compressing with the
+// codec. Returns error if the codec isn't registered.
+func NewBlockWriter(wr io.Writer, codec byte) (*BlockWriter, error) {
+	c, err := lookupCodec(codec)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockWriter{wr: wr, codec: codec, c: c}, nil
+}
+
+// Write adds a document to the block being built, which is written when it's
+// full. Returns the position of the document.
+func (this *BlockWriter) Write(doc Doc) (BlockPos, error) {
+	bs, err := doc.Encode()
+	if err != nil {
+		return BlockPos{}, err
+	}
+	if len(this.raw)+len(bs) > maxBlockLen {
+		if err := this.Flush(); err != nil {
+			return BlockPos{}, err
+		}
+	}
+	pos := BlockPos{Block: this.off, Index: this.docs}
+	this.raw = append(this.raw, bs...)
+	this.docs++
+	size := this.BlockSize
+	if size <= 0 {
+		size = defaultBlockSize
+	}
+	if len(this.raw) >= size {
+		if err := this.Flush(); err != nil {
+			return BlockPos{}, err
+		}
+	}
+	return pos, nil
+}
+
+// Flush writes the block being built, even if it isn't full.
+func (this *BlockWriter) Flush() error {
+	if this.docs == 0 {
+		return nil
+	}
+	b := make([]byte, blockHeaderSize, blockHeaderSize+len(this.raw))
+	b, err := this.c.Encode(b, this.raw)
+	if err != nil {
+		return err
+	}
+	if len(b)-blockHeaderSize > maxBlockLen {
+		return errors.New("Block exceeded maximum size.")
+	}
+	copy(b, blockMagic)
+	b[3] = this.codec
+	binary.LittleEndian.PutUint32(b[4:], uint32(this.docs))
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(this.raw)))
+	binary.LittleEndian.PutUint32(b[12:], uint32(len(b)-blockHeaderSize))
+	if _, err := this.wr.Write(b); err != nil {
+		return err
+	}
+	this.off += int64(len(b))
+	this.raw = this.raw[:0]
+	this.docs = 0
+	return nil
+}
+
+// Close writes the block being built. It doesn't close the underlying writer.
+func (this *BlockWriter) Close() error {
+	return this.Flush()
+}
+
+// BlockReader reads a block stream.
+type BlockReader struct {
+	rd   io.Reader
+	raw  []byte // Rest of the documents of the current block.
+	docs int    // Documents left in raw.
+}
+
+// NewBlockReader returns a BlockReader which reads from rd.
+func NewBlockReader(rd io.Reader) *BlockReader {
+	return &BlockReader{rd: rd}
+}
+
+// Read reads the next document. Returns io.EOF at the end of the stream.
+func (this *BlockReader) Read() (BSON, error) {
+	for this.docs == 0 {
+		raw, docs, err := readBlock(this.rd)
+		if err != nil {
+			return nil, err
+		}
+		this.raw, this.docs = raw, docs
+	}
+	docLen, err := rawDocLen(this.raw, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Block %w.", ErrCorrupt)
+	}
+	bs := BSON(this.raw[:docLen:docLen])
+	this.raw = this.raw[docLen:]
+	this.docs--
+	return bs, nil
+}
+
+// ReadBlockDoc reads the document at a position in a block stream, as returned
+// by BlockWriter Write. Only the block it's in is decompressed.
+func ReadBlockDoc(ra io.ReaderAt, pos BlockPos) (BSON, error) {
+	raw, docs, err := readBlock(io.NewSectionReader(ra, pos.Block,
+		blockHeaderSize+maxBlockLen))
+	if err == io.EOF {
+		err = fmt.Errorf("Block %w.", ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pos.Index < 0 || pos.Index >= docs {
+		return nil, fmt.Errorf("Block doc %v %w.", pos.Index, ErrNotFound)
+	}
+	off := 0
+	for i := 0; ; i++ {
+		docLen, err := rawDocLen(raw, off)
+		if err != nil {
+			return nil, fmt.Errorf("Block %w.", ErrCorrupt)
+		}
+		if i == pos.Index {
+			return BSON(raw[off : off+docLen]), nil
+		}
+		off += docLen
+	}
+}
+
+// readBlock reads and decompresses a block. Returns the documents and the
+// number of them.
+func readBlock(rd io.Reader) ([]byte, int, error) {
+	var hdr [blockHeaderSize]byte
+	if _, err := io.ReadFull(rd, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Block %w.", ErrTruncated)
+		}
+		return nil, 0, err
+	}
+	if string(hdr[:3]) != blockMagic {
+		return nil, 0, fmt.Errorf("Block header %w.", ErrCorrupt)
+	}
+	c, err := lookupCodec(hdr[3])
+	if err != nil {
+		return nil, 0, err
+	}
+	docs := int(binary.LittleEndian.Uint32(hdr[4:]))
+	rawLen := int(binary.LittleEndian.Uint32(hdr[8:]))
+	dataLen := int(binary.LittleEndian.Uint32(hdr[12:]))
+	if rawLen > maxBlockLen || dataLen > maxBlockLen || docs*5 > rawLen {
+		return nil, 0, fmt.Errorf("Block header %w.", ErrCorrupt)
+	}
+	data := make([]byte, dataLen)
+	if _, err := io.ReadFull(rd, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Block %w.", ErrTruncated)
+		}
+		return nil, 0, err
+	}
+	raw, err := c.Decode(make([]byte, 0, rawLen), data)
+	if err != nil || len(raw) != rawLen {
+		return nil, 0, fmt.Errorf("Block data %w.", ErrCorrupt)
+	}
+	return raw, docs, nil
+}
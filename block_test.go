@@ -0,0 +1,108 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestBlockStream(t *testing.T) {
+	for _, codec := range []byte{CodecNone, CodecFlate, CodecSnappy} {
+		var buf bytes.Buffer
+		bw, err := NewBlockWriter(&buf, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bw.BlockSize = 256
+		var want []BSON
+		var pos []BlockPos
+		for i := 0; i < 100; i++ {
+			doc := Slice{{"i", Int32(i)}, {"name", String("repetitive")}}
+			p, err := bw.Write(doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, doc.MustEncode())
+			pos = append(pos, p)
+		}
+		if err := bw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if pos[len(pos)-1].Block == 0 {
+			t.Fatalf("Expected multiple blocks, codec %v.", codec)
+		}
+		if codec != CodecNone && buf.Len()*2 > len(want)*len(want[0]) {
+			t.Fatalf("Expected compression, %v bytes, codec %v.", buf.Len(),
+				codec)
+		}
+
+		br := NewBlockReader(bytes.NewReader(buf.Bytes()))
+		for i := range want {
+			bs, err := br.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(bs, want[i]) {
+				t.Fatalf("Expected %v, got %v, codec %v, doc %v.", want[i], bs,
+					codec, i)
+			}
+		}
+		if _, err := br.Read(); err != io.EOF {
+			t.Fatalf("Expected EOF, got %v, codec %v.", err, codec)
+		}
+
+		ra := bytes.NewReader(buf.Bytes())
+		for _, i := range []int{0, 37, 99} {
+			bs, err := ReadBlockDoc(ra, pos[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(bs, want[i]) {
+				t.Fatalf("Expected %v, got %v, codec %v, doc %v.", want[i], bs,
+					codec, i)
+			}
+		}
+		_, err = ReadBlockDoc(ra, BlockPos{Index: 1000})
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected not found, got %v, codec %v.", err, codec)
+		}
+	}
+}
+
+func TestBlockStreamErrors(t *testing.T) {
+	if _, err := NewBlockWriter(io.Discard, 0xFF); err == nil {
+		t.Fatal("Expected unregistered codec error.")
+	}
+	var buf bytes.Buffer
+	bw, err := NewBlockWriter(&buf, CodecFlate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bw.Write(Map{"a": Int32(1)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	br := NewBlockReader(bytes.NewReader(b[:len(b)-1]))
+	if _, err := br.Read(); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("Expected truncated, got %v.", err)
+	}
+	bad := append([]byte("XXX"), b[3:]...)
+	br = NewBlockReader(bytes.NewReader(bad))
+	if _, err := br.Read(); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Expected corrupt, got %v.", err)
+	}
+	bad = append([]byte(nil), b...)
+	bad[8]++ // Raw length.
+	br = NewBlockReader(bytes.NewReader(bad))
+	if _, err := br.Read(); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Expected corrupt, got %v.", err)
+	}
+}
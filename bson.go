@@ -5,9 +5,8 @@ package bson
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
-	"time"
+	"sort"
 )
 
 // Doc is a BSON document. Map, Slice, and BSON conform to this.
@@ -48,13 +47,21 @@ func (this BSON) MustEncode() BSON {
 	return this
 }
 
-// JSON transcodes the BSON document to JSON.
-func (this BSON) JSON() (string, error) {
-	m, err := this.Map()
+// String decodes the BSON and pretty prints it with BSON types, the same as
+// Slice. If the BSON can't be decoded the error and a hex dump are printed
+// instead, see Dump.
+func (this BSON) String() string {
+	s, err := this.Slice()
 	if err != nil {
-		return "", err
+		return fmt.Sprintf("BSON(%v)\n%v", err, Dump(this))
 	}
-	j, err := json.Marshal(m)
+	return s.String()
+}
+
+// JSON transcodes the BSON document to Extended JSON. The order of keys is
+// kept.
+func (this BSON) JSON() (string, error) {
+	j, err := this.MarshalJSON()
 	if err != nil {
 		return "", err
 	}
@@ -77,15 +84,100 @@ func (this BSON) Slice() (Slice, error) {
 	return ReadSlice(bytes.NewBuffer(this))
 }
 
+// Struct decodes the BSON to the struct pointed to by dst. See DecodeStruct.
+func (this BSON) Struct(dst interface{}) error {
+	return DecodeStruct(this, dst)
+}
+
 // Decode BSON to Slice, but don't decode nested docs. This is useful when it's
 // not necessary to decode the whole document.
 func (this BSON) SliceNoNest() (Slice, error) {
 	return ReadSliceNoNest(bytes.NewBuffer(this))
 }
 
+// MapDepth is the same as Map but only decodes documents n deep, deeper ones
+// are left encoded as BSON. The BSON is depth 1, so MapDepth(1) leaves all
+// nested documents encoded, the same as MapNoNest except for documents in
+// arrays. Arrays don't count towards the depth, a document in a array is one
+// deeper than the document the array is in. For example, a router which looks
+// at a envelope and passes the payload on:
+//   m, err := bs.MapDepth(2)
+//   ...
+//   payload := m["msg"].(bson.Map)["payload"].(bson.BSON)
+// A n <= 0 decodes all documents.
+func (this BSON) MapDepth(n int) (Map, error) {
+	d := rawDecoder{nest: n}
+	v, err := d.doc(this, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Map), nil
+}
+
+// SliceDepth is the same as MapDepth but decodes to a Slice.
+func (this BSON) SliceDepth(n int) (Slice, error) {
+	d := rawDecoder{nest: n, slice: true}
+	v, err := d.doc(this, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Slice), nil
+}
+
+// MapView decodes the BSON to a Map without copying keys, strings or binaries,
+// they alias the BSON instead. This is much cheaper when only a few values are
+// looked at. The BSON is owned by the view: it must not be modified or reused
+// (e.g. as the buffer for ReadOneBuf) while anything from the view is in use.
+// Copy values which must outlive the BSON, for example with strings.Clone.
+func (this BSON) MapView() (Map, error) {
+	d := rawDecoder{alias: true}
+	v, err := d.doc(this, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Map), nil
+}
+
+// SliceView is the same as MapView but decodes to a Slice.
+func (this BSON) SliceView() (Slice, error) {
+	d := rawDecoder{alias: true, slice: true}
+	v, err := d.doc(this, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Slice), nil
+}
+
+// SalvageMap decodes as much of a corrupt BSON as it can. When a element
+// can't be decoded the error is recorded and decoding continues with the next
+// element, which is found by skipping to the first position from which the
+// rest of the document is valid. Nested documents are salvaged the same way.
+// The errors are *DecodeError. This is for rescuing data from damaged files,
+// don't use it for normal decoding.
+func (this BSON) SalvageMap() (Map, []error) {
+	d := rawDecoder{salvage: true}
+	v, _ := d.doc(this, "", 0)
+	return v.(Map), d.errs
+}
+
+// SalvageSlice is the same as SalvageMap but decodes to a Slice.
+func (this BSON) SalvageSlice() (Slice, []error) {
+	d := rawDecoder{salvage: true, slice: true}
+	v, _ := d.doc(this, "", 0)
+	return v.(Slice), d.errs
+}
+
+// DecodeInto decodes the BSON to m, which is cleared first. Maps nested in m
+// are reused for nested documents with the same key, so that decoding many
+// similar documents doesn't allocate a new Map tree for each. The Maps must not
+// be shared with anything else.
+func (this BSON) DecodeInto(m Map) error {
+	return ReadMapInto(bytes.NewBuffer(this), m)
+}
+
 // Encode Map to BSON.
 func (this Map) Encode() (BSON, error) {
-	b, err := encodeMap("", this)
+	b, err := defaultEncoder.begin().encodeMap("", this)
 	if err != nil {
 		return nil, err
 	}
@@ -94,7 +186,7 @@ func (this Map) Encode() (BSON, error) {
 
 // MustEncode panics if Map cannot be encoded to BSON.
 func (this Map) MustEncode() BSON {
-	b, err := encodeMap("", this)
+	b, err := defaultEncoder.begin().encodeMap("", this)
 	if err != nil {
 		panic(err)
 	}
@@ -109,7 +201,12 @@ func print(v interface{}) string {
 	case Slice:
 		return vt.String()
 	case BSON:
-		return fmt.Sprintf("BSON(%v)", vt)
+		// Single line, the dump is only for String.
+		s, err := vt.Slice()
+		if err != nil {
+			return fmt.Sprintf("BSON(%x)", []byte(vt))
+		}
+		return fmt.Sprintf("BSON(%v)", s)
 	case Float:
 		return fmt.Sprintf("Float(%v)", vt)
 	case String:
@@ -134,7 +231,7 @@ func print(v interface{}) string {
 	case Bool:
 		return fmt.Sprintf("Bool(%v)", vt)
 	case UTCDateTime:
-		return fmt.Sprintf("UTCDateTime(%v)", time.Unix(0, int64(vt)*1000*1000))
+		return fmt.Sprintf("UTCDateTime(%v)", vt.Time())
 	case Null:
 		return "Null()"
 	case Regexp:
@@ -150,7 +247,7 @@ func print(v interface{}) string {
 	case Int32:
 		return fmt.Sprintf("Int32(%v)", vt)
 	case Timestamp:
-		return fmt.Sprintf("Timestamp(%v)", vt)
+		return fmt.Sprintf("Timestamp(%v, %v)", vt.T(), vt.I())
 	case Int64:
 		return fmt.Sprintf("Int64(%v)", vt)
 	case MinKey:
@@ -172,9 +269,36 @@ func (this Map) String() string {
 	return wr.String()
 }
 
+// SortedSlice converts the Map to a Slice sorted by key. Nested Maps, including
+// those in Arrays, are also converted. This is useful when a deterministic
+// encoding is needed.
+func (this Map) SortedSlice() Slice {
+	s := make(Slice, 0, len(this))
+	for k, v := range this {
+		s = append(s, Pair{Key: k, Val: sortedVal(v)})
+	}
+	s.SortKeys()
+	return s
+}
+
+// sortedVal converts nested Maps to sorted Slices.
+func sortedVal(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case Map:
+		return vt.SortedSlice()
+	case Array:
+		a := make(Array, len(vt))
+		for i, e := range vt {
+			a[i] = sortedVal(e)
+		}
+		return a
+	}
+	return v
+}
+
 // Encode Slice to BSON.
 func (this Slice) Encode() (BSON, error) {
-	b, err := encodeSlice("", this)
+	b, err := defaultEncoder.begin().encodeSlice("", this)
 	if err != nil {
 		return nil, err
 	}
@@ -183,13 +307,74 @@ func (this Slice) Encode() (BSON, error) {
 
 // MustEncode panics if Slice cannot be encoded to BSON.
 func (this Slice) MustEncode() BSON {
-	b, err := encodeSlice("", this)
+	b, err := defaultEncoder.begin().encodeSlice("", this)
 	if err != nil {
 		panic(err)
 	}
 	return b
 }
 
+// Get returns the value of the first pair with the key. Returns false if there
+// is no pair with the key.
+func (this Slice) Get(key string) (interface{}, bool) {
+	for _, pair := range this {
+		if pair.Key == key {
+			return pair.Val, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of the first pair with the key. If there is no pair
+// with the key then a pair is appended.
+func (this *Slice) Set(key string, val interface{}) {
+	for i := range *this {
+		if (*this)[i].Key == key {
+			(*this)[i].Val = val
+			return
+		}
+	}
+	*this = append(*this, Pair{Key: key, Val: val})
+}
+
+// Delete removes the first pair with the key. Returns true if a pair was
+// removed.
+func (this *Slice) Delete(key string) bool {
+	for i := range *this {
+		if (*this)[i].Key == key {
+			*this = append((*this)[:i], (*this)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// InsertAt inserts a pair at index i, shifting later pairs up. Panics if i is
+// out of range [0, len].
+func (this *Slice) InsertAt(i int, key string, val interface{}) {
+	if i < 0 || i > len(*this) {
+		panic("InsertAt index out of range.")
+	}
+	*this = append(*this, Pair{})
+	copy((*this)[i+1:], (*this)[i:])
+	(*this)[i] = Pair{Key: key, Val: val}
+}
+
+// Sort sorts the Slice in place using less. The sort is stable.
+func (this Slice) Sort(less func(a, b Pair) bool) {
+	sort.SliceStable(this, func(i, j int) bool {
+		return less(this[i], this[j])
+	})
+}
+
+// SortKeys sorts the Slice in place by key. The sort is stable so pairs with
+// duplicate keys keep their order.
+func (this Slice) SortKeys() {
+	this.Sort(func(a, b Pair) bool {
+		return a.Key < b.Key
+	})
+}
+
 // String pretty prints the Slice with BSON types.
 func (this Slice) String() string {
 	wr := bytes.NewBuffer(nil)
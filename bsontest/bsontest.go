@@ -0,0 +1,217 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+Package bsontest has helpers for testing code which uses BSON documents.
+
+AssertEqualDocs reports each difference between two documents by path, rather
+than printing both documents:
+
+	bsontest.AssertEqualDocs(t, want, got)
+
+	a.b: missing, want Int32(1)
+	a.c: Int64(2), want Int32(2)
+	d.0: String("y"), want String("x")
+
+Fixtures can be written as Extended JSON:
+
+	want := bsontest.Map(t, `{"a": {"$numberLong": "1"}}`)
+*/
+package bsontest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sbunce/bson"
+)
+
+// AssertEqualDocs fails the test with one error for each difference between
+// the documents, see Diff.
+func AssertEqualDocs(t testing.TB, want, got bson.Doc) {
+	t.Helper()
+	for _, d := range Diff(want, got) {
+		t.Error(d)
+	}
+}
+
+// Diff returns the differences between two documents, one per path, sorted by
+// path. Returns nil if they're equal. The differences are:
+//   "path: missing, want V", a key in want isn't in got.
+//   "path: unexpected V", a key in got isn't in want.
+//   "path: V, want W", a value isn't the same type or isn't equal.
+//   "path: order [b a], want [a b]", Slices have the keys in a different order.
+// Values are printed as Go literals. Documents can be Map, Slice or BSON, which
+// are compared by their elements. Nested documents are compared the same way,
+// and Arrays are compared by index.
+func Diff(want, got bson.Doc) []string {
+	var diffs []string
+	diffDoc(&diffs, "", want, got)
+	sort.Strings(diffs)
+	return diffs
+}
+
+// diffDoc appends the differences between the documents at path.
+func diffDoc(diffs *[]string, path string, want, got bson.Doc) {
+	wantS, err := slice(want)
+	if err != nil {
+		*diffs = append(*diffs, prefix(path)+"want "+err.Error())
+		return
+	}
+	gotS, err := slice(got)
+	if err != nil {
+		*diffs = append(*diffs, prefix(path)+"got "+err.Error())
+		return
+	}
+	gotM := make(map[string]interface{}, len(gotS))
+	for _, pair := range gotS {
+		gotM[pair.Key] = pair.Val
+	}
+	wantM := make(map[string]interface{}, len(wantS))
+	for _, pair := range wantS {
+		wantM[pair.Key] = pair.Val
+		p := join(path, pair.Key)
+		gv, ok := gotM[pair.Key]
+		if !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%vmissing, want %#v", prefix(p),
+				pair.Val))
+			continue
+		}
+		diffVal(diffs, p, pair.Val, gv)
+	}
+	for _, pair := range gotS {
+		if _, ok := wantM[pair.Key]; !ok {
+			*diffs = append(*diffs, fmt.Sprintf("%vunexpected %#v",
+				prefix(join(path, pair.Key)), pair.Val))
+		}
+	}
+
+	// Order only matters if both are ordered.
+	_, wantMap := want.(bson.Map)
+	_, gotMap := got.(bson.Map)
+	if wantMap || gotMap || len(wantS) != len(gotS) {
+		return
+	}
+	for i := range wantS {
+		if wantS[i].Key != gotS[i].Key {
+			*diffs = append(*diffs, fmt.Sprintf("%vorder %v, want %v",
+				prefix(path), keys(gotS), keys(wantS)))
+			return
+		}
+	}
+}
+
+// diffVal appends the difference between the values at path.
+func diffVal(diffs *[]string, path string, want, got interface{}) {
+	wantD, wantDoc := want.(bson.Doc)
+	gotD, gotDoc := got.(bson.Doc)
+	if wantDoc && gotDoc {
+		diffDoc(diffs, path, wantD, gotD)
+		return
+	}
+	wantA, wantArr := want.(bson.Array)
+	gotA, gotArr := got.(bson.Array)
+	if wantArr && gotArr {
+		for i := 0; i < len(wantA) || i < len(gotA); i++ {
+			p := join(path, strconv.Itoa(i))
+			switch {
+			case i >= len(gotA):
+				*diffs = append(*diffs, fmt.Sprintf("%vmissing, want %#v",
+					prefix(p), wantA[i]))
+			case i >= len(wantA):
+				*diffs = append(*diffs, fmt.Sprintf("%vunexpected %#v", prefix(p),
+					gotA[i]))
+			default:
+				diffVal(diffs, p, wantA[i], gotA[i])
+			}
+		}
+		return
+	}
+	if fmt.Sprintf("%T", want) != fmt.Sprintf("%T", got) ||
+		bson.Compare(want, got) != 0 {
+
+		*diffs = append(*diffs, fmt.Sprintf("%v%#v, want %#v", prefix(path),
+			got, want))
+	}
+}
+
+// slice returns the elements of a document.
+func slice(doc bson.Doc) (bson.Slice, error) {
+	switch dt := doc.(type) {
+	case bson.Slice:
+		return dt, nil
+	case bson.Map:
+		s := make(bson.Slice, 0, len(dt))
+		for k, v := range dt {
+			s = append(s, bson.Pair{Key: k, Val: v})
+		}
+		return s, nil
+	case nil:
+		return nil, nil
+	}
+	bs, err := doc.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return bs.Slice()
+}
+
+func keys(s bson.Slice) []string {
+	k := make([]string, len(s))
+	for i, pair := range s {
+		k[i] = pair.Key
+	}
+	return k
+}
+
+// join appends a key to a path, escaping it so the path is unambiguous.
+func join(path, key string) string {
+	if path == "" {
+		return bson.EscapeKey(key)
+	}
+	return path + "." + bson.EscapeKey(key)
+}
+
+// prefix returns the path as a prefix for a difference.
+func prefix(path string) string {
+	if path == "" {
+		return ""
+	}
+	return path + ": "
+}
+
+// Map returns the Extended JSON object as a Map. Nested documents are Maps.
+// Fails the test if the JSON is invalid.
+func Map(t testing.TB, json string) bson.Map {
+	t.Helper()
+	var m bson.Map
+	if err := m.UnmarshalJSON([]byte(json)); err != nil {
+		t.Fatalf("bsontest: %v in %v", err, strings.TrimSpace(json))
+	}
+	return m
+}
+
+// Slice returns the Extended JSON object as a Slice, with keys in order.
+// Nested documents are Slices. Fails the test if the JSON is invalid.
+func Slice(t testing.TB, json string) bson.Slice {
+	t.Helper()
+	var s bson.Slice
+	if err := s.UnmarshalJSON([]byte(json)); err != nil {
+		t.Fatalf("bsontest: %v in %v", err, strings.TrimSpace(json))
+	}
+	return s
+}
+
+// BSON returns the Extended JSON object encoded as BSON, with keys in order.
+// Fails the test if the JSON is invalid.
+func BSON(t testing.TB, json string) bson.BSON {
+	t.Helper()
+	bs, err := Slice(t, json).Encode()
+	if err != nil {
+		t.Fatalf("bsontest: %v", err)
+	}
+	return bs
+}
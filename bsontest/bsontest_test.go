@@ -0,0 +1,75 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bsontest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sbunce/bson"
+)
+
+// recorder records the errors of a test.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (this *recorder) Helper() {}
+
+func (this *recorder) Error(args ...interface{}) {
+	this.errs = append(this.errs, args[0].(string))
+}
+
+func TestDiff(t *testing.T) {
+	want := bson.Map{
+		"a":   bson.Map{"b": bson.Int32(1), "c": bson.Int32(2)},
+		"d":   bson.Array{bson.String("x"), bson.Bool(true)},
+		"e.f": bson.Null{},
+	}
+	got := bson.Slice{
+		{Key: "a", Val: bson.Slice{{Key: "c", Val: bson.Int64(2)}}},
+		{Key: "d", Val: bson.Array{bson.String("y")}},
+		{Key: "e.f", Val: bson.Null{}},
+		{Key: "g", Val: bson.Int32(3)},
+	}.MustEncode()
+	expect := []string{
+		"a.b: missing, want Int32(1)",
+		"a.c: Int64(2), want Int32(2)",
+		"d.0: String(\"y\"), want String(\"x\")",
+		"d.1: missing, want Bool(true)",
+		"g: unexpected Int32(3)",
+	}
+	rec := &recorder{TB: t}
+	AssertEqualDocs(rec, want, got)
+	if !reflect.DeepEqual(rec.errs, expect) {
+		t.Fatalf("%q", rec.errs)
+	}
+
+	// Order of Slices.
+	diffs := Diff(
+		bson.Slice{{Key: "a", Val: nil}, {Key: "b", Val: nil}},
+		bson.Slice{{Key: "b", Val: nil}, {Key: "a", Val: nil}})
+	if !reflect.DeepEqual(diffs, []string{"order [b a], want [a b]"}) {
+		t.Fatalf("%q", diffs)
+	}
+	if diffs := Diff(want, want); diffs != nil {
+		t.Fatalf("%q", diffs)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	s := Slice(t, `{"b": {"$numberLong": "1"}, "a": {"c": "x"}}`)
+	expect := bson.Slice{
+		{Key: "b", Val: bson.Int64(1)},
+		{Key: "a", Val: bson.Slice{{Key: "c", Val: bson.String("x")}}},
+	}
+	AssertEqualDocs(t, expect, s)
+	AssertEqualDocs(t, expect, BSON(t,
+		`{"b": {"$numberLong": "1"}, "a": {"c": "x"}}`))
+	m := Map(t, `{"a": {"c": "x"}}`)
+	if _, ok := m["a"].(bson.Map); !ok {
+		t.Fatalf("%#v", m)
+	}
+}
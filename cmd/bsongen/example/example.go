@@ -0,0 +1,37 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+// Package example has types with methods generated by bsongen.
+package example
+
+import (
+	"time"
+
+	"github.com/sbunce/bson"
+)
+
+//go:generate bsongen -type Person,Address
+
+// Person is encoded by generated code.
+type Person struct {
+	Id       bson.ObjectId `bson:"_id,required"`
+	Name     string        `bson:"name"`
+	Age      int32         `bson:"age,omitempty"`
+	Height   float64       `bson:"height"`
+	Logins   int64         `bson:"logins"`
+	Admin    bool          `bson:"admin,omitempty"`
+	Created  time.Time     `bson:"created"`
+	Avatar   []byte        `bson:"avatar,omitempty"`
+	Home     Address       `bson:"home"`
+	Work     *Address      `bson:"work"`
+	Tags     []string      `bson:"tags,omitempty"`
+	Timeout  time.Duration `bson:"timeout"`
+	Extra    bson.Map      `bson:"extra,omitempty"`
+	Password string        `bson:"-"`
+}
+
+// Address is encoded by generated code.
+type Address struct {
+	Street string `bson:"street"`
+	City   string `bson:"city"`
+}
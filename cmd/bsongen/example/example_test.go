@@ -0,0 +1,123 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package example
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sbunce/bson"
+)
+
+// plainPerson has no methods, so it's encoded with reflection.
+type plainPerson Person
+
+func testPerson() Person {
+	return Person{
+		Id:      bson.ObjectId("0123456789ab"),
+		Name:    "Alice",
+		Age:     30,
+		Height:  1.7,
+		Logins:  1 << 40,
+		Created: time.Unix(1500000000, 0).UTC(),
+		Avatar:  []byte{1, 2, 3},
+		Home:    Address{Street: "1 Main St", City: "Springfield"},
+		Work:    &Address{City: "Shelbyville"},
+		Tags:    []string{"a", "b"},
+		Timeout: time.Second,
+		Extra:   bson.Map{"x": bson.Int32(1)},
+	}
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	for _, p := range []Person{testPerson(), {Id: bson.ObjectId("0123456789ab")}} {
+		gen, err := bson.EncodeStruct(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := bson.EncodeStruct(plainPerson(p))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(gen, ref) {
+			t.Fatal(gen, ref)
+		}
+		var dst Person
+		if err := bson.DecodeStruct(gen, &dst); err != nil {
+			t.Fatal(err)
+		}
+		var refDst plainPerson
+		if err := bson.DecodeStruct(gen, &refDst); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(dst, Person(refDst)) {
+			t.Fatal(dst, refDst)
+		}
+		p.Password = ""
+		if !reflect.DeepEqual(dst, p) {
+			t.Fatal(dst, p)
+		}
+	}
+}
+
+func TestGeneratedCoerce(t *testing.T) {
+	bs, err := bson.Map{
+		"_id":     bson.Binary("0123456789ab"),
+		"name":    bson.Symbol("Bob"),
+		"logins":  bson.Int32(5),
+		"created": bson.Null{},
+		"work":    bson.Null{},
+		"tags":    bson.Array{bson.Symbol("x"), bson.Null{}},
+		"timeout": bson.String("2s"),
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := Person{Work: &Address{}}
+	if err := dst.UnmarshalBSON(bs); err != nil {
+		t.Fatal(err)
+	}
+	exp := Person{Id: bson.ObjectId("0123456789ab"), Name: "Bob", Logins: 5,
+		Tags: []string{"x", ""}, Timeout: 2 * time.Second}
+	if !reflect.DeepEqual(dst, exp) {
+		t.Fatal(dst, exp)
+	}
+}
+
+func TestGeneratedErrors(t *testing.T) {
+	bs, err := bson.Map{"name": bson.String("Bob")}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dst Person
+	err = dst.UnmarshalBSON(bs)
+	if err == nil || !strings.Contains(err.Error(), "required") {
+		t.Fatal(err)
+	}
+	bs, err = bson.Map{
+		"_id": bson.ObjectId("0123456789ab"),
+		"age": bson.String("old"),
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dst.UnmarshalBSON(bs)
+	if err == nil || !strings.HasPrefix(err.Error(), "age,") {
+		t.Fatal(err)
+	}
+	bs, err = bson.Map{
+		"_id":  bson.ObjectId("0123456789ab"),
+		"tags": bson.Array{bson.String("a"), bson.Int32(1)},
+	}.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dst.UnmarshalBSON(bs)
+	if err == nil || !strings.HasPrefix(err.Error(), "tags.1,") {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,285 @@
+// Code generated by bsongen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"github.com/sbunce/bson"
+	"strconv"
+	"time"
+)
+
+// MarshalBSON encodes Person to BSON.
+func (this Person) MarshalBSON() ([]byte, error) {
+	var err error
+	b, start := bson.AppendDocStart(nil)
+	if b, err = bson.AppendValue(b, "_id", this.Id); err != nil {
+		return nil, err
+	}
+	b = bson.AppendString(b, "name", this.Name)
+	if this.Age != 0 {
+		b = bson.AppendInt32(b, "age", this.Age)
+	}
+	b = bson.AppendFloat(b, "height", this.Height)
+	b = bson.AppendInt64(b, "logins", int64(this.Logins))
+	if this.Admin {
+		b = bson.AppendBool(b, "admin", this.Admin)
+	}
+	b = bson.AppendTime(b, "created", this.Created)
+	if len(this.Avatar) != 0 {
+		b = bson.AppendBinary(b, "avatar", this.Avatar)
+	}
+	{
+		bs, err := this.Home.MarshalBSON()
+		if err != nil {
+			return nil, fmt.Errorf("%v, %v", "home", err)
+		}
+		b = bson.AppendDocument(b, "home", bs)
+	}
+	if this.Work == nil {
+		b = bson.AppendNull(b, "work")
+	} else {
+		bs, err := this.Work.MarshalBSON()
+		if err != nil {
+			return nil, fmt.Errorf("%v, %v", "work", err)
+		}
+		b = bson.AppendDocument(b, "work", bs)
+	}
+	if len(this.Tags) != 0 {
+		var arr int
+		b, arr = bson.AppendArrayStart(b, "tags")
+		for i, e := range this.Tags {
+			b = bson.AppendString(b, strconv.Itoa(i), e)
+		}
+		b = bson.AppendDocEnd(b, arr)
+	}
+	b = bson.AppendInt64(b, "timeout", int64(this.Timeout))
+	if len(this.Extra) != 0 {
+		if b, err = bson.AppendValue(b, "extra", this.Extra); err != nil {
+			return nil, err
+		}
+	}
+	return bson.AppendDocEnd(b, start), nil
+}
+
+// UnmarshalBSON decodes BSON to Person.
+func (this *Person) UnmarshalBSON(b []byte) error {
+	it := bson.BSON(b).Iter()
+	haveId := false
+	this.Work = nil
+	for it.Next() {
+		switch it.Key() {
+		case "_id":
+			haveId = true
+			switch v := it.Value().(type) {
+			case bson.Binary:
+				this.Id = bson.ObjectId(v)
+			case bson.ObjectId:
+				this.Id = bson.ObjectId(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "_id", v, "bson.ObjectId")
+			}
+		case "name":
+			switch v := it.Value().(type) {
+			case bson.String:
+				this.Name = string(v)
+			case bson.Javascript:
+				this.Name = string(v)
+			case bson.Symbol:
+				this.Name = string(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "name", v, "string")
+			}
+		case "age":
+			switch v := it.Value().(type) {
+			case bson.Int32:
+				this.Age = int32(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "age", v, "int32")
+			}
+		case "height":
+			switch v := it.Value().(type) {
+			case bson.Float:
+				this.Height = float64(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "height", v, "float64")
+			}
+		case "logins":
+			switch v := it.Value().(type) {
+			case bson.Int32:
+				this.Logins = int64(v)
+			case bson.Int64:
+				this.Logins = int64(v)
+			case bson.UTCDateTime:
+				this.Logins = int64(v)
+			case bson.Timestamp:
+				this.Logins = int64(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "logins", v, "int64")
+			}
+		case "admin":
+			switch v := it.Value().(type) {
+			case bson.Bool:
+				this.Admin = bool(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "admin", v, "bool")
+			}
+		case "created":
+			switch v := it.Value().(type) {
+			case bson.UTCDateTime:
+				this.Created = v.Time()
+			case bson.Timestamp:
+				this.Created = v.Time()
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "created", v, "time.Time")
+			}
+		case "avatar":
+			switch v := it.Value().(type) {
+			case bson.Binary:
+				this.Avatar = []byte(v)
+			case bson.ObjectId:
+				this.Avatar = []byte(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "avatar", v, "[]byte")
+			}
+		case "home":
+			switch v := it.Value().(type) {
+			case bson.BSON:
+				if err := this.Home.UnmarshalBSON(v); err != nil {
+					return fmt.Errorf("%v, %v", "home", err)
+				}
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "home", v, "Address")
+			}
+		case "work":
+			switch v := it.Value().(type) {
+			case bson.BSON:
+				p := new(Address)
+				if err := p.UnmarshalBSON(v); err != nil {
+					return fmt.Errorf("%v, %v", "work", err)
+				}
+				this.Work = p
+			case bson.Null:
+				this.Work = nil
+			case bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "work", v, "*Address")
+			}
+		case "tags":
+			switch v := it.Value().(type) {
+			case bson.Array:
+				a := make([]string, len(v))
+				for i, e := range v {
+					switch e := e.(type) {
+					case bson.String:
+						a[i] = string(e)
+					case bson.Javascript:
+						a[i] = string(e)
+					case bson.Symbol:
+						a[i] = string(e)
+					case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+					default:
+						return fmt.Errorf("%v, cannot coerce %T to %v.", fmt.Sprint("tags.", i), e, "string")
+					}
+				}
+				this.Tags = a
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "tags", v, "[]string")
+			}
+		case "timeout":
+			switch v := it.Value().(type) {
+			case bson.Int32:
+				this.Timeout = time.Duration(v)
+			case bson.Int64:
+				this.Timeout = time.Duration(v)
+			case bson.UTCDateTime:
+				this.Timeout = time.Duration(v)
+			case bson.Timestamp:
+				this.Timeout = time.Duration(v)
+			case bson.String:
+				d, err := time.ParseDuration(string(v))
+				if err != nil {
+					return fmt.Errorf("%v, %v", "timeout", err)
+				}
+				this.Timeout = d
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "timeout", v, "time.Duration")
+			}
+		case "extra":
+			switch v := it.Value().(type) {
+			case bson.BSON:
+				m, err := v.MapNoNest()
+				if err != nil {
+					return fmt.Errorf("%v, %v", "extra", err)
+				}
+				this.Extra = m
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "extra", v, "bson.Map")
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	if !haveId {
+		return fmt.Errorf("%v, required field %v missing.", "_id", "Id")
+	}
+	return nil
+}
+
+// MarshalBSON encodes Address to BSON.
+func (this Address) MarshalBSON() ([]byte, error) {
+	b, start := bson.AppendDocStart(nil)
+	b = bson.AppendString(b, "street", this.Street)
+	b = bson.AppendString(b, "city", this.City)
+	return bson.AppendDocEnd(b, start), nil
+}
+
+// UnmarshalBSON decodes BSON to Address.
+func (this *Address) UnmarshalBSON(b []byte) error {
+	it := bson.BSON(b).Iter()
+	for it.Next() {
+		switch it.Key() {
+		case "street":
+			switch v := it.Value().(type) {
+			case bson.String:
+				this.Street = string(v)
+			case bson.Javascript:
+				this.Street = string(v)
+			case bson.Symbol:
+				this.Street = string(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "street", v, "string")
+			}
+		case "city":
+			switch v := it.Value().(type) {
+			case bson.String:
+				this.City = string(v)
+			case bson.Javascript:
+				this.City = string(v)
+			case bson.Symbol:
+				this.City = string(v)
+			case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:
+			default:
+				return fmt.Errorf("%v, cannot coerce %T to %v.", "city", v, "string")
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,726 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// bsonPath is the import path of the bson package.
+const bsonPath = "github.com/sbunce/bson"
+
+// kind is how a field is encoded and decoded.
+type kind int
+
+const (
+	kindFallback kind = iota // Reflection.
+	kindInt                  // int or int64, Int64.
+	kindInt32                // int32, Int32.
+	kindFloat                // float64, Float.
+	kindString               // string, String.
+	kindBool                 // bool, Bool.
+	kindBytes                // []byte, Binary.
+	kindTime                 // time.Time, UTCDateTime.
+	kindExact                // BSON type decoded only from the same type.
+	kindMap                  // bson.Map.
+	kindSlice                // bson.Slice.
+	kindBSON                 // bson.BSON.
+	kindNested               // Struct type generated by the same command.
+	kindDuration             // time.Duration, Int64.
+	kindScalars              // Slice of a scalar kind, Array.
+)
+
+// scalar returns true if the kind is allowed as the element of kindScalars.
+func (this kind) scalar() bool {
+	switch this {
+	case kindInt, kindInt32, kindFloat, kindString, kindBool, kindTime,
+		kindDuration:
+
+		return true
+	}
+	return false
+}
+
+// bsonTypes maps the BSON value types to the kind of the Go type they're
+// decoded the same as.
+var bsonTypes = map[string]kind{
+	"Float":           kindFloat,
+	"String":          kindString,
+	"Array":           kindExact,
+	"Binary":          kindBytes,
+	"Undefined":       kindExact,
+	"ObjectId":        kindBytes,
+	"Bool":            kindBool,
+	"UTCDateTime":     kindInt,
+	"Null":            kindExact,
+	"Regexp":          kindExact,
+	"DBPointer":       kindExact,
+	"Javascript":      kindString,
+	"Symbol":          kindString,
+	"JavascriptScope": kindExact,
+	"Int32":           kindInt32,
+	"Timestamp":       kindInt,
+	"Int64":           kindInt,
+	"MinKey":          kindExact,
+	"MaxKey":          kindExact,
+	"Map":             kindMap,
+	"Slice":           kindSlice,
+	"BSON":            kindBSON,
+}
+
+// field is a struct field to encode and decode.
+type field struct {
+	name      string // Go field name.
+	key       string // Document key.
+	kind      kind
+	typ       string // Go type, as written in the generated code.
+	int64     bool   // Is int64 rather than int, for kindInt.
+	ptr       bool   // Is a pointer, for kindFallback and kindNested.
+	elem      *field // Element, for kindScalars.
+	omitEmpty string // Condition for including the field, "" to always.
+	required  bool
+}
+
+// generator holds the parsed package.
+type generator struct {
+	pkg     string
+	types   map[string]*ast.TypeSpec // All types in the package.
+	methods map[string]map[string]bool
+	gen     map[string]bool // Types being generated.
+	imports map[string]string
+	buf     bytes.Buffer
+}
+
+// generate returns the source of the MarshalBSON and UnmarshalBSON methods for
+// the types, which are in the package in dir.
+func generate(dir string, typeNames []string) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in %v, found %v.", dir,
+			len(pkgs))
+	}
+	g := &generator{
+		types:   map[string]*ast.TypeSpec{},
+		methods: map[string]map[string]bool{},
+		gen:     map[string]bool{},
+		imports: map[string]string{"bson": bsonPath, "fmt": "fmt"},
+	}
+	files := map[string]*ast.File{}
+	for name, pkg := range pkgs {
+		g.pkg = name
+		for _, f := range pkg.Files {
+			g.collect(f, files)
+		}
+	}
+	for _, name := range typeNames {
+		g.gen[name] = true
+	}
+	var body bytes.Buffer
+	for _, name := range typeNames {
+		spec, ok := g.types[name]
+		if !ok {
+			return nil, fmt.Errorf("type %v not found.", name)
+		}
+		st, ok := spec.Type.(*ast.StructType)
+		if !ok {
+			return nil, fmt.Errorf("type %v is not a struct.", name)
+		}
+		fields, err := g.fields(name, st, files[name])
+		if err != nil {
+			return nil, err
+		}
+		g.buf.Reset()
+		g.marshal(name, fields)
+		g.unmarshal(name, fields)
+		body.Write(g.buf.Bytes())
+	}
+
+	// Header and imports.
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by bsongen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %v\n\nimport (\n", g.pkg)
+	var names []string
+	for name := range g.imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := g.imports[name]
+		if name == path || strings.HasSuffix(path, "/"+name) {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&out, "\t%v %q\n", name, path)
+		}
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(body.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code, %v", err)
+	}
+	return src, nil
+}
+
+// collect records the types and methods declared in the file.
+func (this *generator) collect(f *ast.File, files map[string]*ast.File) {
+	for _, decl := range f.Decls {
+		switch dt := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range dt.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					this.types[ts.Name.Name] = ts
+					files[ts.Name.Name] = f
+				}
+			}
+		case *ast.FuncDecl:
+			if dt.Recv == nil || len(dt.Recv.List) != 1 {
+				continue
+			}
+			recv := dt.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if id, ok := recv.(*ast.Ident); ok {
+				if this.methods[id.Name] == nil {
+					this.methods[id.Name] = map[string]bool{}
+				}
+				this.methods[id.Name][dt.Name.Name] = true
+			}
+		}
+	}
+}
+
+// fields returns the fields of the struct which are encoded.
+func (this *generator) fields(typeName string, st *ast.StructType,
+	file *ast.File) ([]field, error) {
+
+	imports := fileImports(file)
+	var fields []field
+	for _, af := range st.Fields.List {
+		names := af.Names
+		if names == nil {
+			// Embedded, the field name is the type name.
+			names = []*ast.Ident{ast.NewIdent(embeddedName(af.Type))}
+		}
+		for _, id := range names {
+			if !ast.IsExported(id.Name) {
+				continue
+			}
+			f := field{name: id.Name, key: id.Name}
+			var opts []string
+			if af.Tag != nil {
+				tag, err := strconv.Unquote(af.Tag.Value)
+				if err != nil {
+					return nil, err
+				}
+				tok := strings.Split(reflect.StructTag(tag).Get("bson"), ",")
+				if tok[0] == "-" {
+					continue
+				}
+				if tok[0] != "" {
+					f.key = tok[0]
+				}
+				opts = tok[1:]
+			}
+			if err := this.classify(&f, af.Type, imports); err != nil {
+				return nil, fmt.Errorf("%v.%v, %v", typeName, f.name, err)
+			}
+			for _, opt := range opts {
+				switch opt {
+				case "omitempty":
+					cond, err := this.emptyCond(af.Type, "this."+f.name, imports)
+					if err != nil {
+						return nil, fmt.Errorf("%v.%v, %v", typeName, f.name, err)
+					}
+					if cond == "" {
+						cond = "true"
+					}
+					f.omitEmpty = cond
+				case "required":
+					f.required = true
+				default:
+					return nil, fmt.Errorf("%v.%v, tag option %v not supported.",
+						typeName, f.name, opt)
+				}
+			}
+			fields = append(fields, f)
+		}
+	}
+	return fields, nil
+}
+
+// fileImports returns the import paths of the file keyed by name.
+func fileImports(file *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// embeddedName returns the field name of a embedded field.
+func embeddedName(e ast.Expr) string {
+	switch et := e.(type) {
+	case *ast.StarExpr:
+		return embeddedName(et.X)
+	case *ast.SelectorExpr:
+		return et.Sel.Name
+	case *ast.Ident:
+		return et.Name
+	}
+	return ""
+}
+
+// classify sets the kind and type of the field.
+func (this *generator) classify(f *field, e ast.Expr, imports map[string]string) error {
+	switch et := e.(type) {
+	case *ast.Ident:
+		switch et.Name {
+		case "int":
+			f.kind, f.typ = kindInt, "int"
+			return nil
+		case "int64":
+			f.kind, f.typ, f.int64 = kindInt, "int64", true
+			return nil
+		case "int32":
+			f.kind, f.typ = kindInt32, "int32"
+			return nil
+		case "float64":
+			f.kind, f.typ = kindFloat, "float64"
+			return nil
+		case "string":
+			f.kind, f.typ = kindString, "string"
+			return nil
+		case "bool":
+			f.kind, f.typ = kindBool, "bool"
+			return nil
+		}
+		if this.gen[et.Name] {
+			f.kind, f.typ = kindNested, et.Name
+			return nil
+		}
+	case *ast.SelectorExpr:
+		if x, ok := et.X.(*ast.Ident); ok {
+			switch imports[x.Name] {
+			case "time":
+				switch et.Sel.Name {
+				case "Time":
+					f.kind, f.typ = kindTime, "time.Time"
+					return nil
+				case "Duration":
+					typ, err := this.typeString(e, imports)
+					if err != nil {
+						return err
+					}
+					f.kind, f.typ = kindDuration, typ
+					return nil
+				}
+			case bsonPath:
+				if k, ok := bsonTypes[et.Sel.Name]; ok {
+					f.kind, f.typ = k, "bson."+et.Sel.Name
+					f.int64 = k == kindInt
+					return nil
+				}
+			}
+		}
+	case *ast.StarExpr:
+		if id, ok := et.X.(*ast.Ident); ok && this.gen[id.Name] {
+			f.kind, f.typ, f.ptr = kindNested, "*"+id.Name, true
+			return nil
+		}
+	case *ast.ArrayType:
+		if et.Len != nil {
+			break
+		}
+		if id, ok := et.Elt.(*ast.Ident); ok && id.Name == "byte" {
+			f.kind, f.typ = kindBytes, "[]byte"
+			return nil
+		}
+		// Elements of BSON types are encoded as themselves, not by a Append
+		// function.
+		elem := &field{}
+		if err := this.classify(elem, et.Elt, imports); err != nil ||
+			!elem.kind.scalar() || strings.HasPrefix(elem.typ, "bson.") {
+
+			break
+		}
+		typ, err := this.typeString(e, imports)
+		if err != nil {
+			return err
+		}
+		f.kind, f.typ, f.elem = kindScalars, typ, elem
+		return nil
+	}
+	typ, err := this.typeString(e, imports)
+	if err != nil {
+		return err
+	}
+	_, f.ptr = e.(*ast.StarExpr)
+	f.kind, f.typ = kindFallback, typ
+	return nil
+}
+
+// typeString returns the type as written in the generated code, and adds the
+// imports it needs.
+func (this *generator) typeString(e ast.Expr, imports map[string]string) (string,
+	error) {
+
+	var err error
+	ast.Inspect(e, func(n ast.Node) bool {
+		sel, ok := n.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		x, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		path, ok := imports[x.Name]
+		if !ok {
+			err = fmt.Errorf("unknown package %v.", x.Name)
+			return false
+		}
+		if p, ok := this.imports[x.Name]; ok && p != path {
+			err = fmt.Errorf("package name %v used for %v and %v.", x.Name, p, path)
+			return false
+		}
+		this.imports[x.Name] = path
+		return false
+	})
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, token.NewFileSet(), e); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// emptyCond returns a condition which is true if the value v of type e isn't
+// empty, the same as omitempty with the reflection based encoder. Returns "" if
+// the value is never empty.
+func (this *generator) emptyCond(e ast.Expr, v string,
+	imports map[string]string) (string, error) {
+
+	switch et := e.(type) {
+	case *ast.Ident:
+		if this.methods[et.Name]["IsZero"] {
+			return "!" + v + ".IsZero()", nil
+		}
+		switch et.Name {
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16",
+			"uint32", "uint64", "uintptr", "float32", "float64", "byte", "rune":
+
+			return v + " != 0", nil
+		case "string":
+			return v + ` != ""`, nil
+		case "bool":
+			return v, nil
+		}
+		if spec, ok := this.types[et.Name]; ok {
+			if _, ok := spec.Type.(*ast.StructType); ok {
+				return "", nil
+			}
+			return this.emptyCond(spec.Type, v, imports)
+		}
+	case *ast.StarExpr, *ast.InterfaceType, *ast.FuncType, *ast.ChanType:
+		return v + " != nil", nil
+	case *ast.ArrayType, *ast.MapType:
+		return "len(" + v + ") != 0", nil
+	case *ast.StructType:
+		return "", nil
+	case *ast.SelectorExpr:
+		x, ok := et.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch imports[x.Name] {
+		case "time":
+			switch et.Sel.Name {
+			case "Time":
+				return "!" + v + ".IsZero()", nil
+			case "Duration":
+				return v + " != 0", nil
+			}
+		case bsonPath:
+			switch et.Sel.Name {
+			case "Float", "Int32", "Int64", "UTCDateTime", "Timestamp":
+				return v + " != 0", nil
+			case "String", "Javascript", "Symbol":
+				return v + ` != ""`, nil
+			case "Bool":
+				return "bool(" + v + ")", nil
+			case "Binary", "ObjectId", "Array", "Map", "Slice", "BSON":
+				return "len(" + v + ") != 0", nil
+			}
+			if _, ok := bsonTypes[et.Sel.Name]; ok {
+				return "", nil
+			}
+		}
+	}
+	return "", errors.New("omitempty not supported for this type.")
+}
+
+// marshal writes the MarshalBSON method.
+func (this *generator) marshal(typeName string, fields []field) {
+	w := &this.buf
+	fmt.Fprintf(w, "\n// MarshalBSON encodes %v to BSON.\n", typeName)
+	fmt.Fprintf(w, "func (this %v) MarshalBSON() ([]byte, error) {\n", typeName)
+	for _, f := range fields {
+		if appendCall(f, "", "") == "" && f.kind != kindNested &&
+			f.kind != kindScalars {
+
+			fmt.Fprintf(w, "var err error\n")
+			break
+		}
+	}
+	fmt.Fprintf(w, "b, start := bson.AppendDocStart(nil)\n")
+	for _, f := range fields {
+		cond := f.omitEmpty != "" && f.omitEmpty != "true"
+		if cond {
+			fmt.Fprintf(w, "if %v {\n", f.omitEmpty)
+		}
+		this.appendField(f, cond)
+		if cond {
+			fmt.Fprintf(w, "}\n")
+		}
+	}
+	fmt.Fprintf(w, "return bson.AppendDocEnd(b, start), nil\n}\n")
+}
+
+// appendField writes the code which appends the field. If block then the code
+// is already in a block of its own, otherwise one is opened if variables are
+// declared.
+func (this *generator) appendField(f field, block bool) {
+	w := &this.buf
+	v := "this." + f.name
+	key := strconv.Quote(f.key)
+	if call := appendCall(f, v, key); call != "" {
+		fmt.Fprintf(w, "b = %v\n", call)
+		return
+	}
+	if !block && (f.kind == kindNested && !f.ptr || f.kind == kindScalars) {
+		fmt.Fprintf(w, "{\n")
+		defer fmt.Fprintf(w, "}\n")
+	}
+	switch f.kind {
+	case kindNested:
+		if f.ptr {
+			fmt.Fprintf(w, "if %v == nil {\nb = bson.AppendNull(b, %v)\n} else {\n",
+				v, key)
+			defer fmt.Fprintf(w, "}\n")
+		}
+		fmt.Fprintf(w, "bs, err := %v.MarshalBSON()\n", v)
+		this.checkMarshalErr(key)
+		fmt.Fprintf(w, "b = bson.AppendDocument(b, %v, bs)\n", key)
+	case kindScalars:
+		this.imports["strconv"] = "strconv"
+		fmt.Fprintf(w, "var arr int\nb, arr = bson.AppendArrayStart(b, %v)\n", key)
+		fmt.Fprintf(w, "for i, e := range %v {\nb = %v\n}\n", v,
+			appendCall(*f.elem, "e", "strconv.Itoa(i)"))
+		fmt.Fprintf(w, "b = bson.AppendDocEnd(b, arr)\n")
+	default:
+		fmt.Fprintf(w, "if b, err = bson.AppendValue(b, %v, %v); err != nil {\n",
+			key, v)
+		fmt.Fprintf(w, "return nil, err\n}\n")
+	}
+}
+
+// checkMarshalErr writes the code which returns err, if not nil, prefixed
+// with the key expression.
+func (this *generator) checkMarshalErr(key string) {
+	fmt.Fprintf(&this.buf,
+		"if err != nil {\nreturn nil, fmt.Errorf(\"%%v, %%v\", %v, err)\n}\n", key)
+}
+
+// appendCall returns the call of the Append function which appends v, the
+// value of the field, with the key expression. Returns "" if there's no
+// Append function for the field.
+func appendCall(f field, v, key string) string {
+	if strings.HasPrefix(f.typ, "bson.") {
+		// BSON types which are encoded as themselves, such as Javascript.
+		return ""
+	}
+	switch f.kind {
+	case kindInt, kindDuration:
+		return "bson.AppendInt64(b, " + key + ", int64(" + v + "))"
+	case kindInt32:
+		return "bson.AppendInt32(b, " + key + ", " + v + ")"
+	case kindFloat:
+		return "bson.AppendFloat(b, " + key + ", " + v + ")"
+	case kindString:
+		return "bson.AppendString(b, " + key + ", " + v + ")"
+	case kindBool:
+		return "bson.AppendBool(b, " + key + ", " + v + ")"
+	case kindBytes:
+		return "bson.AppendBinary(b, " + key + ", " + v + ")"
+	case kindTime:
+		return "bson.AppendTime(b, " + key + ", " + v + ")"
+	}
+	return ""
+}
+
+// unmarshal writes the UnmarshalBSON method.
+func (this *generator) unmarshal(typeName string, fields []field) {
+	w := &this.buf
+	fmt.Fprintf(w, "\n// UnmarshalBSON decodes BSON to %v.\n", typeName)
+	fmt.Fprintf(w, "func (this *%v) UnmarshalBSON(b []byte) error {\n", typeName)
+	fmt.Fprintf(w, "it := bson.BSON(b).Iter()\n")
+	for _, f := range fields {
+		if f.ptr {
+			// Nil if missing.
+			fmt.Fprintf(w, "this.%v = nil\n", f.name)
+		}
+		if f.required {
+			fmt.Fprintf(w, "have%v := false\n", f.name)
+		}
+	}
+	fmt.Fprintf(w, "for it.Next() {\nswitch it.Key() {\n")
+	for _, f := range fields {
+		fmt.Fprintf(w, "case %q:\n", f.key)
+		if f.required {
+			fmt.Fprintf(w, "have%v = true\n", f.name)
+		}
+		this.decodeField(f)
+	}
+	fmt.Fprintf(w, "}\n}\n")
+	fmt.Fprintf(w, "if err := it.Err(); err != nil {\nreturn err\n}\n")
+	for _, f := range fields {
+		if f.required {
+			fmt.Fprintf(w, "if !have%v {\n", f.name)
+			fmt.Fprintf(w,
+				"return fmt.Errorf(\"%%v, required field %%v missing.\", %q, %q)\n}\n",
+				f.key, f.name)
+		}
+	}
+	fmt.Fprintf(w, "return nil\n}\n")
+}
+
+// decodeField writes the code which decodes it.Value() to the field. The cases
+// are the same as the coercions done by the reflection based decoder.
+func (this *generator) decodeField(f field) {
+	w := &this.buf
+	dst := "this." + f.name
+	if f.kind == kindFallback {
+		fmt.Fprintf(w, "var w struct {\nV %v `bson:\"v\"`\n}\n", f.typ)
+		fmt.Fprintf(w, "bs, err := bson.Slice{{Key: \"v\", Val: it.Value()}}.Encode()\n")
+		fmt.Fprintf(w, "if err == nil {\nerr = bson.DecodeStruct(bs, &w)\n}\n")
+		fmt.Fprintf(w, "if err != nil {\nreturn fmt.Errorf(\"%%v, %%v\", %q, err)\n}\n",
+			f.key)
+		fmt.Fprintf(w, "%v = w.V\n", dst)
+		return
+	}
+	fmt.Fprintf(w, "switch v := it.Value().(type) {\n")
+	this.decodeCases(f, dst, "v", strconv.Quote(f.key))
+	fmt.Fprintf(w, "}\n")
+}
+
+// checkErr writes the code which returns err, if not nil, prefixed with the
+// key expression.
+func (this *generator) checkErr(key string) {
+	fmt.Fprintf(&this.buf,
+		"if err != nil {\nreturn fmt.Errorf(\"%%v, %%v\", %v, err)\n}\n", key)
+}
+
+// decodeCases writes the cases of a type switch on v which decode it to dst.
+// The key is the expression for the path in errors.
+func (this *generator) decodeCases(f field, dst, v, key string) {
+	w := &this.buf
+	conv := func(v string) string {
+		return f.typ + "(" + v + ")"
+	}
+	switch f.kind {
+	case kindInt, kindDuration:
+		fmt.Fprintf(w, "case bson.Int32:\n%v = %v\n", dst, conv(v))
+		fmt.Fprintf(w, "case bson.Int64:\n%v = %v\n", dst, conv(v))
+		if f.int64 || f.kind == kindDuration {
+			fmt.Fprintf(w, "case bson.UTCDateTime:\n%v = %v\n", dst, conv(v))
+			fmt.Fprintf(w, "case bson.Timestamp:\n%v = %v\n", dst, conv(v))
+		}
+		if f.kind == kindDuration {
+			fmt.Fprintf(w, "case bson.String:\n")
+			fmt.Fprintf(w, "d, err := time.ParseDuration(string(%v))\n", v)
+			this.checkErr(key)
+			fmt.Fprintf(w, "%v = d\n", dst)
+		}
+	case kindInt32:
+		fmt.Fprintf(w, "case bson.Int32:\n%v = %v\n", dst, conv(v))
+	case kindFloat:
+		fmt.Fprintf(w, "case bson.Float:\n%v = %v\n", dst, conv(v))
+	case kindString:
+		fmt.Fprintf(w, "case bson.String:\n%v = %v\n", dst, conv(v))
+		fmt.Fprintf(w, "case bson.Javascript:\n%v = %v\n", dst, conv(v))
+		fmt.Fprintf(w, "case bson.Symbol:\n%v = %v\n", dst, conv(v))
+	case kindBool:
+		fmt.Fprintf(w, "case bson.Bool:\n%v = %v\n", dst, conv(v))
+	case kindBytes:
+		fmt.Fprintf(w, "case bson.Binary:\n%v = %v\n", dst, conv(v))
+		fmt.Fprintf(w, "case bson.ObjectId:\n%v = %v\n", dst, conv(v))
+	case kindTime:
+		fmt.Fprintf(w, "case bson.UTCDateTime:\n%v = %v.Time()\n", dst, v)
+		fmt.Fprintf(w, "case bson.Timestamp:\n%v = %v.Time()\n", dst, v)
+	case kindExact:
+		fmt.Fprintf(w, "case %v:\n%v = %v\n", f.typ, dst, v)
+	case kindMap:
+		fmt.Fprintf(w, "case bson.BSON:\nm, err := %v.MapNoNest()\n", v)
+		this.checkErr(key)
+		fmt.Fprintf(w, "%v = m\n", dst)
+	case kindSlice:
+		fmt.Fprintf(w, "case bson.BSON:\ns, err := %v.Slice()\n", v)
+		this.checkErr(key)
+		fmt.Fprintf(w, "%v = s\n", dst)
+	case kindBSON:
+		fmt.Fprintf(w, "case bson.BSON:\n%v = %v\n", dst, v)
+	case kindNested:
+		fmt.Fprintf(w, "case bson.BSON:\n")
+		if f.ptr {
+			// Nil if Null, otherwise a new value so the old one isn't modified.
+			fmt.Fprintf(w, "p := new(%v)\n", strings.TrimPrefix(f.typ, "*"))
+			fmt.Fprintf(w, "if err := p.UnmarshalBSON(%v); err != nil {\n", v)
+			fmt.Fprintf(w, "return fmt.Errorf(\"%%v, %%v\", %v, err)\n}\n", key)
+			fmt.Fprintf(w, "%v = p\n", dst)
+			fmt.Fprintf(w, "case bson.Null:\n%v = nil\n", dst)
+		} else {
+			fmt.Fprintf(w, "if err := %v.UnmarshalBSON(%v); err != nil {\n", dst, v)
+			fmt.Fprintf(w, "return fmt.Errorf(\"%%v, %%v\", %v, err)\n}\n", key)
+		}
+	case kindScalars:
+		fmt.Fprintf(w, "case bson.Array:\na := make(%v, len(%v))\n", f.typ, v)
+		fmt.Fprintf(w, "for i, e := range %v {\nswitch e := e.(type) {\n", v)
+		this.decodeCases(*f.elem, "a[i]", "e",
+			fmt.Sprintf("fmt.Sprint(%q, i)", f.key+"."))
+		fmt.Fprintf(w, "}\n}\n%v = a\n", dst)
+	}
+	if f.kind == kindNested && f.ptr {
+		fmt.Fprintf(w, "case bson.Undefined, bson.MinKey, bson.MaxKey:\n")
+	} else {
+		fmt.Fprintf(w, "case bson.Null, bson.Undefined, bson.MinKey, bson.MaxKey:\n")
+	}
+	fmt.Fprintf(w, "default:\n")
+	fmt.Fprintf(w, "return fmt.Errorf(\"%%v, cannot coerce %%T to %%v.\", %v, %v, %q)\n",
+		key, v, f.typ)
+}
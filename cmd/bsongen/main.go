@@ -0,0 +1,73 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+Bsongen generates MarshalBSON and UnmarshalBSON methods for struct types, so
+that they're encoded and decoded without reflection.
+
+Usage:
+
+	bsongen -type T[,U...] [-output file] [dir]
+
+The dir defaults to the current directory. The output defaults to
+<first type>_bson.go in the dir. This is usually run with go:generate:
+
+	//go:generate bsongen -type Person,Address
+
+Fields of these types are encoded and decoded without reflection:
+
+	bool, int, int32, int64, float64, string, []byte, time.Time
+	time.Duration, encoded as Int64 nanoseconds
+	slices of the above, except []byte
+	BSON types (bson.Int32, bson.ObjectId, bson.Map, etc)
+	other struct types generated by the same command, and pointers to them
+
+The generated MarshalBSON builds the document with the bson.Append functions,
+and UnmarshalBSON decodes it one element at a time with bson.Iter. BSON types
+are appended with bson.AppendValue, and slices of them use reflection.
+
+Other fields are passed to the reflection based encoder and decoder, so they
+work the same as with bson.EncodeStruct and bson.DecodeStruct. The struct tags
+"-", omitempty and required are supported, other options are a error.
+
+The generated MarshalBSON doesn't see the Encoder options. A time.Duration is
+always nanoseconds and a NaN is always encoded, whatever the Duration and NaN
+policy of the Encoder.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	typeNames := flag.String("type", "", "comma separated list of type names")
+	output := flag.String("output", "", "output file name")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+	types := strings.Split(*typeNames, ",")
+	src, err := generate(dir, types)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bsongen:", err)
+		os.Exit(1)
+	}
+	name := *output
+	if name == "" {
+		name = filepath.Join(dir, strings.ToLower(types[0])+"_bson.go")
+	}
+	if err := os.WriteFile(name, src, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, "bsongen:", err)
+		os.Exit(1)
+	}
+}
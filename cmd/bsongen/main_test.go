@@ -0,0 +1,34 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// The example package must be regenerated when the generated code changes.
+func TestGenerateExample(t *testing.T) {
+	src, err := generate("example", []string{"Person", "Address"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp, err := os.ReadFile("example/person_bson.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, exp) {
+		t.Fatal("example/person_bson.go is out of date.")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	if _, err := generate("example", []string{"Missing"}); err == nil {
+		t.Fatal("Expected error for missing type.")
+	}
+	if _, err := generate(".", []string{"kind"}); err == nil {
+		t.Fatal("Expected error for non-struct type.")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+Bsonstruct generates Go struct types from sample BSON documents, to bootstrap
+typed models from existing collections or dumps.
+
+Usage:
+
+	bsonstruct -type T [-package p] [-output file] [file ...]
+
+The files are concatenated BSON documents, such as written by mongodump. Stdin
+is read if there are no files. Fields from all the documents are merged. A field
+which is missing from some documents, or is Null, is optional. Optional fields
+are pointers (except slices and interfaces) tagged with omitempty. Embedded
+documents become struct types named after the parent type and the field. A field
+with values of incompatible types is interface{}.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sbunce/bson"
+)
+
+func main() {
+	typeName := flag.String("type", "", "name of the generated type")
+	pkg := flag.String("package", "main", "package name")
+	output := flag.String("output", "", "output file name, default stdout")
+	flag.Parse()
+	if *typeName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	s := newSchema()
+	if flag.NArg() == 0 {
+		if err := s.read(os.Stdin); err != nil {
+			fatal(err)
+		}
+	}
+	for _, name := range flag.Args() {
+		f, err := os.Open(name)
+		if err != nil {
+			fatal(err)
+		}
+		err = s.read(f)
+		f.Close()
+		if err != nil {
+			fatal(err)
+		}
+	}
+	src, err := s.generate(*pkg, *typeName)
+	if err != nil {
+		fatal(err)
+	}
+	if *output == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "bsonstruct:", err)
+	os.Exit(1)
+}
+
+// read merges all the documents from rd.
+func (this *schema) read(rd io.Reader) error {
+	dec := bson.NewDecoder(rd)
+	for {
+		s, err := dec.DecodeSlice()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		this.add(s)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sbunce/bson"
+)
+
+func TestGenerate(t *testing.T) {
+	docs := []bson.Slice{
+		{
+			{Key: "_id", Val: bson.ObjectId("0123456789ab")},
+			{Key: "name", Val: bson.String("Alice")},
+			{Key: "age", Val: bson.Int32(30)},
+			{Key: "address", Val: bson.Slice{{Key: "city", Val: bson.String("Springfield")}}},
+			{Key: "tags", Val: bson.Array{bson.String("a")}},
+			{Key: "created", Val: bson.NewUTCDateTime(time.Unix(0, 0))},
+		},
+		{
+			{Key: "_id", Val: bson.ObjectId("0123456789ac")},
+			{Key: "name", Val: bson.String("Bob")},
+			{Key: "age", Val: bson.Int64(40)},
+			{Key: "address", Val: bson.Slice{
+				{Key: "city", Val: bson.String("Shelbyville")},
+				{Key: "zip", Val: bson.String("12345")},
+			}},
+			{Key: "created", Val: bson.Null{}},
+			{Key: "score", Val: bson.Float(1.5)},
+			{Key: "misc", Val: bson.String("x")},
+			{Key: "first-name", Val: bson.String("Bob")},
+		},
+		{
+			{Key: "_id", Val: bson.ObjectId("0123456789ad")},
+			{Key: "name", Val: bson.String("Carol")},
+			{Key: "age", Val: bson.Int32(50)},
+			{Key: "address", Val: bson.Slice{{Key: "city", Val: bson.String("Ogdenville")}}},
+			{Key: "created", Val: bson.NewUTCDateTime(time.Unix(0, 0))},
+			{Key: "misc", Val: bson.Int32(1)},
+		},
+	}
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		bs, err := doc.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(bs)
+	}
+	s := newSchema()
+	if err := s.read(&buf); err != nil {
+		t.Fatal(err)
+	}
+	src, err := s.generate("model", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := "// Code generated by bsonstruct.\n" +
+		"\n" +
+		"package model\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"github.com/sbunce/bson\"\n" +
+		"\t\"time\"\n" +
+		")\n" +
+		"\n" +
+		"// Person was generated from 3 documents.\n" +
+		"type Person struct {\n" +
+		"\tId        bson.ObjectId `bson:\"_id\"`\n" +
+		"\tName      string        `bson:\"name\"`\n" +
+		"\tAge       int64         `bson:\"age\"`\n" +
+		"\tAddress   PersonAddress `bson:\"address\"`\n" +
+		"\tTags      []string      `bson:\"tags,omitempty\"`\n" +
+		"\tCreated   *time.Time    `bson:\"created,omitempty\"`\n" +
+		"\tScore     *float64      `bson:\"score,omitempty\"`\n" +
+		"\tMisc      interface{}   `bson:\"misc,omitempty\"`\n" +
+		"\tFirstName *string       `bson:\"first-name,omitempty\"`\n" +
+		"}\n" +
+		"\n" +
+		"// PersonAddress was generated from 3 documents.\n" +
+		"type PersonAddress struct {\n" +
+		"\tCity string  `bson:\"city\"`\n" +
+		"\tZip  *string `bson:\"zip,omitempty\"`\n" +
+		"}\n"
+	if string(src) != exp {
+		t.Fatalf("got:\n%v\nexpected:\n%v", string(src), exp)
+	}
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"_id":        "Id",
+		"first_name": "FirstName",
+		"a.b":        "AB",
+		"2fa":        "F2fa",
+		"":           "F",
+	}
+	for key, exp := range tests {
+		if name := goName(key); name != exp {
+			t.Fatal(key, name, exp)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sbunce/bson"
+)
+
+// schema is the fields merged from documents.
+type schema struct {
+	count  int      // Number of documents.
+	keys   []string // In order first seen.
+	fields map[string]*fieldInfo
+}
+
+// fieldInfo is what's been seen of a key.
+type fieldInfo struct {
+	count int  // Number of documents which have the key, not counting Null.
+	null  bool // Null or Undefined seen.
+	val   valueInfo
+}
+
+// valueInfo is the types of values seen.
+type valueInfo struct {
+	types map[string]bool // Go types of scalars, "doc" and "array".
+	doc   *schema         // Embedded documents.
+	elem  *valueInfo      // Array elements.
+}
+
+func newSchema() *schema {
+	return &schema{fields: map[string]*fieldInfo{}}
+}
+
+// add merges a document.
+func (this *schema) add(s bson.Slice) {
+	this.count++
+	for _, pair := range s {
+		f, ok := this.fields[pair.Key]
+		if !ok {
+			f = &fieldInfo{}
+			this.fields[pair.Key] = f
+			this.keys = append(this.keys, pair.Key)
+		}
+		switch pair.Val.(type) {
+		case bson.Null, bson.Undefined:
+			f.null = true
+			continue
+		}
+		f.count++
+		f.val.add(pair.Val)
+	}
+}
+
+// add merges a value.
+func (this *valueInfo) add(v interface{}) {
+	if this.types == nil {
+		this.types = map[string]bool{}
+	}
+	switch vt := v.(type) {
+	case bson.Slice:
+		this.types["doc"] = true
+		if this.doc == nil {
+			this.doc = newSchema()
+		}
+		this.doc.add(vt)
+	case bson.Array:
+		this.types["array"] = true
+		if this.elem == nil {
+			this.elem = &valueInfo{}
+		}
+		for _, e := range vt {
+			switch e.(type) {
+			case bson.Null, bson.Undefined:
+				continue
+			}
+			this.elem.add(e)
+		}
+	case bson.Float:
+		this.types["float64"] = true
+	case bson.String, bson.Symbol, bson.Javascript:
+		this.types["string"] = true
+	case bson.Binary:
+		this.types["[]byte"] = true
+	case bson.Bool:
+		this.types["bool"] = true
+	case bson.UTCDateTime:
+		this.types["time.Time"] = true
+	case bson.Int32:
+		this.types["int32"] = true
+	case bson.Int64:
+		this.types["int64"] = true
+	default:
+		// ObjectId, Regexp, Timestamp, etc.
+		this.types[fmt.Sprintf("%T", v)] = true
+	}
+}
+
+// generator writes the struct types.
+type generator struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+	queue   []namedSchema
+}
+
+// namedSchema is a struct type to generate.
+type namedSchema struct {
+	name string
+	s    *schema
+}
+
+// generate returns the source of the struct types.
+func (this *schema) generate(pkg, typeName string) ([]byte, error) {
+	g := &generator{imports: map[string]bool{}}
+	g.queue = append(g.queue, namedSchema{typeName, this})
+	for len(g.queue) > 0 {
+		ns := g.queue[0]
+		g.queue = g.queue[1:]
+		g.structType(ns.name, ns.s)
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by bsonstruct.\n\npackage %v\n", pkg)
+	if len(g.imports) > 0 {
+		var paths []string
+		for path := range g.imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(&out, "\nimport (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		fmt.Fprintf(&out, ")\n")
+	}
+	out.Write(g.buf.Bytes())
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code, %v", err)
+	}
+	return src, nil
+}
+
+// structType writes a struct type.
+func (this *generator) structType(name string, s *schema) {
+	fmt.Fprintf(&this.buf, "\n// %v was generated from %v documents.\n", name,
+		s.count)
+	fmt.Fprintf(&this.buf, "type %v struct {\n", name)
+	used := map[string]bool{}
+	for _, key := range s.keys {
+		f := s.fields[key]
+		fieldName := goName(key)
+		for i := 2; used[fieldName]; i++ {
+			fieldName = goName(key) + strconv.Itoa(i)
+		}
+		used[fieldName] = true
+		typ := this.goType(name+fieldName, &f.val)
+		tag := key
+		if f.count < s.count {
+			// Optional.
+			if !strings.HasPrefix(typ, "[]") && typ != "interface{}" {
+				typ = "*" + typ
+			}
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&this.buf, "%v %v `bson:%q`\n", fieldName, typ, tag)
+	}
+	fmt.Fprintf(&this.buf, "}\n")
+}
+
+// goType returns the Go type for the values. Embedded documents are queued to
+// be generated as a struct type with the name.
+func (this *generator) goType(name string, v *valueInfo) string {
+	if v.types["int32"] && v.types["int64"] {
+		// Int32 is usually a int64 which was encoded with minsize.
+		delete(v.types, "int32")
+	}
+	if len(v.types) != 1 {
+		return "interface{}"
+	}
+	for typ := range v.types {
+		switch typ {
+		case "doc":
+			this.queue = append(this.queue, namedSchema{name, v.doc})
+			return name
+		case "array":
+			return "[]" + this.goType(name, v.elem)
+		case "time.Time":
+			this.imports["time"] = true
+		}
+		if strings.HasPrefix(typ, "bson.") {
+			this.imports["github.com/sbunce/bson"] = true
+		}
+		return typ
+	}
+	panic("unreachable")
+}
+
+// goName returns a exported Go identifier for the key. For example "_id" is Id
+// and "first_name" is FirstName.
+func goName(key string) string {
+	var buf bytes.Buffer
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		buf.WriteRune(r)
+	}
+	name := buf.String()
+	if name == "" || !unicode.IsUpper([]rune(name)[0]) {
+		name = "F" + name
+	}
+	return name
+}
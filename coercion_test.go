@@ -61,7 +61,7 @@ func TestReachCoerce(t *testing.T) {
 			"Javascript":  Javascript("foo"),
 			"Symbol":      Symbol("foo"),
 			"Int32":       Int32(123),
-			"Timestamp":   Timestamp(123),
+			"Timestamp":   NewTimestamp(123, 1),
 			"Int64":       Int64(123),
 		},
 	}
@@ -154,7 +154,7 @@ func TestReachCoerce(t *testing.T) {
 	if !ok {
 		t.Fatal("Expected to find 'UTCDateTime'.")
 	}
-	if uTest1.UnixNano() != 123*1e3 {
+	if uTest1.UnixNano() != 123*1e6 {
 		t.Fatal(uTest1.UnixNano())
 	}
 
@@ -212,28 +212,28 @@ func TestReachCoerce(t *testing.T) {
 
 	// Timestamp
 	var tsTest0 int64
-	ok, err = src.Reach(&tsTest0, "foo", "UTCDateTime")
+	ok, err = src.Reach(&tsTest0, "foo", "Timestamp")
 	if err != nil {
 		t.Fatal(err)
 	}
 	if !ok {
-		t.Fatal("Expected to find 'UTCDateTime.")
+		t.Fatal("Expected to find 'Timestamp.")
 	}
-	if tsTest0 != 123 {
+	if tsTest0 != 123<<32|1 {
 		t.Fatal(tsTest0)
 	}
 
 	// Timestamp
 	var tsTest1 time.Time
-	ok, err = src.Reach(&tsTest1, "foo", "UTCDateTime")
+	ok, err = src.Reach(&tsTest1, "foo", "Timestamp")
 	if err != nil {
 		t.Fatal(err)
 	}
 	if !ok {
-		t.Fatal("Expected to find 'UTCDateTime'.")
+		t.Fatal("Expected to find 'Timestamp'.")
 	}
-	if tsTest1.UnixNano() != 123*1e3 {
-		t.Fatal(tsTest1.UnixNano())
+	if tsTest1.Unix() != 123 {
+		t.Fatal(tsTest1.Unix())
 	}
 
 	// Int64
@@ -249,3 +249,57 @@ func TestReachCoerce(t *testing.T) {
 		t.Fatal(int64Test)
 	}
 }
+
+func TestMustReach(t *testing.T) {
+	m := Map{"foo": Map{"bar": Int32(1)}}
+	var i int
+	m.MustReach(&i, "foo", "bar")
+	if i != 1 {
+		t.Fatal(i)
+	}
+	for _, dot := range [][]string{{"foo", "baz"}, {"foo"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Expected panic.", dot)
+				}
+			}()
+			Slice{{"foo", Map{"bar": Int32(1)}}}.MustReach(&i, dot...)
+		}()
+	}
+}
+
+func TestReachOr(t *testing.T) {
+	m := Map{"port": Int32(1)}
+	var port int
+	if err := m.ReachOr(&port, 2, "port"); err != nil || port != 1 {
+		t.Fatal(err, port)
+	}
+	if err := m.ReachOr(&port, 2, "missing"); err != nil || port != 2 {
+		t.Fatal(err, port)
+	}
+	if err := m.ReachOr(&port, Int32(3), "missing"); err != nil || port != 3 {
+		t.Fatal(err, port)
+	}
+	if err := m.ReachOr(&port, "4", "missing"); err == nil {
+		t.Fatal("Expected error for def which can't be assigned.")
+	}
+	var s string
+	if err := m.ReachOr(&s, "x", "port"); err == nil {
+		t.Fatal("Expected coercion error.")
+	}
+}
+
+func TestReachAs(t *testing.T) {
+	m := Map{"a": Slice{{"b", Int64(1)}}}
+	v, ok, err := ReachAs[int64](m, "a", "b")
+	if !ok || err != nil || v != 1 {
+		t.Fatal(v, ok, err)
+	}
+	if _, ok, err := ReachAs[int64](m, "a", "c"); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	if _, _, err := ReachAs[string](m["a"].(Slice), "b"); err == nil {
+		t.Fatal("Expected coercion error.")
+	}
+}
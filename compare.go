@@ -0,0 +1,391 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// SortKey is a key used to sort documents.
+type SortKey struct {
+	Path []string // Path to the value, the same as with Reach.
+	Desc bool     // Sort descending.
+}
+
+// SortDocs sorts a []Map, []Slice or []BSON in place using MongoDB comparison
+// rules. Documents are sorted by the first key, then ties are broken by the
+// next key, etc. As with MongoDB, a missing field sorts as Null, an Array sorts
+// by its least element (or greatest when descending), and an empty Array sorts
+// before Null. The sort is stable.
+func SortDocs(docs interface{}, keys ...SortKey) error {
+	switch docst := docs.(type) {
+	case []Map:
+		vals := make([][]interface{}, len(docst))
+		for i, doc := range docst {
+			vals[i] = sortVals(doc, keys)
+		}
+		sortByVals(vals, keys, func(i, j int) {
+			docst[i], docst[j] = docst[j], docst[i]
+		})
+	case []Slice:
+		vals := make([][]interface{}, len(docst))
+		for i, doc := range docst {
+			vals[i] = sortVals(doc, keys)
+		}
+		sortByVals(vals, keys, func(i, j int) {
+			docst[i], docst[j] = docst[j], docst[i]
+		})
+	case []BSON:
+		vals := make([][]interface{}, len(docst))
+		for i, doc := range docst {
+			s, err := doc.Slice()
+			if err != nil {
+				return err
+			}
+			vals[i] = sortVals(s, keys)
+		}
+		sortByVals(vals, keys, func(i, j int) {
+			docst[i], docst[j] = docst[j], docst[i]
+		})
+	default:
+		return fmt.Errorf("cannot sort %T.", docs)
+	}
+	return nil
+}
+
+// sortVals reaches in to the document to get the values for the sort keys.
+func sortVals(doc interface{}, keys []SortKey) []interface{} {
+	vals := make([]interface{}, len(keys))
+	for i, key := range keys {
+		v, _ := reach(doc, splitPath(key.Path)...)
+		vals[i] = sortVal(v, key.Desc)
+	}
+	return vals
+}
+
+// emptyArray is the sort value of an empty Array. It sorts before Null.
+type emptyArray struct{}
+
+// sortVal returns the value used for sorting. Arrays sort by their least
+// element, or greatest if descending.
+func sortVal(v interface{}, desc bool) interface{} {
+	a, ok := v.(Array)
+	if !ok {
+		return v
+	}
+	if len(a) == 0 {
+		return emptyArray{}
+	}
+	best := a[0]
+	for _, e := range a[1:] {
+		c := Compare(e, best)
+		if (!desc && c < 0) || (desc && c > 0) {
+			best = e
+		}
+	}
+	return best
+}
+
+// sortByVals sorts by the precomputed sort values. Swap is called to keep the
+// documents in the same order as the values.
+func sortByVals(vals [][]interface{}, keys []SortKey, swap func(i, j int)) {
+	sort.Stable(&valSorter{vals: vals, keys: keys, swap: swap})
+}
+
+// valSorter implements sort.Interface for SortDocs.
+type valSorter struct {
+	vals [][]interface{}
+	keys []SortKey
+	swap func(i, j int)
+}
+
+func (this *valSorter) Len() int {
+	return len(this.vals)
+}
+
+func (this *valSorter) Less(i, j int) bool {
+	for k, key := range this.keys {
+		c := Compare(this.vals[i][k], this.vals[j][k])
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
+func (this *valSorter) Swap(i, j int) {
+	this.vals[i], this.vals[j] = this.vals[j], this.vals[i]
+	this.swap(i, j)
+}
+
+// Compare compares two values using MongoDB comparison rules. Returns -1 if a
+// is less than b, 0 if equal, and 1 if a is greater than b. Values of
+// different types are ordered by type:
+//
+//	MinKey
+//	Null, Undefined, missing (nil)
+//	Numbers (Int32, Int64, Float)
+//	Symbol, String
+//	Documents (Map, Slice, BSON)
+//	Array
+//	Binary
+//	ObjectId
+//	Bool
+//	UTCDateTime
+//	Timestamp
+//	Regexp
+//	DBPointer
+//	Javascript
+//	JavascriptScope
+//	MaxKey
+//
+// Values of the supported coercion types (e.g. int, string, time.Time) are
+// compared as the BSON type they encode to.
+func Compare(a, b interface{}) int {
+	a, b = canonical(a), canonical(b)
+	if ra, rb := compareRank(a), compareRank(b); ra != rb {
+		return compareInt(int64(ra), int64(rb))
+	}
+	switch at := a.(type) {
+	case Int32, Int64, Float:
+		return compareNumber(a, b)
+	case String, Symbol:
+		return compareString(reflect.ValueOf(a).String(),
+			reflect.ValueOf(b).String())
+	case Slice:
+		return compareSlice(at, b.(Slice))
+	case Array:
+		bt := b.(Array)
+		for i := 0; i < len(at) && i < len(bt); i++ {
+			if c := Compare(at[i], bt[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInt(int64(len(at)), int64(len(bt)))
+	case Binary:
+		bt := b.(Binary)
+		if c := compareInt(int64(len(at)), int64(len(bt))); c != 0 {
+			return c
+		}
+		return bytes.Compare(at, bt)
+	case ObjectId:
+		return bytes.Compare(at, b.(ObjectId))
+	case Bool:
+		bt := b.(Bool)
+		if at == bt {
+			return 0
+		} else if !at {
+			return -1
+		}
+		return 1
+	case UTCDateTime:
+		return compareInt(int64(at), int64(b.(UTCDateTime)))
+	case Timestamp:
+		bt := b.(Timestamp)
+		if uint64(at) < uint64(bt) {
+			return -1
+		} else if uint64(at) > uint64(bt) {
+			return 1
+		}
+		return 0
+	case Regexp:
+		bt := b.(Regexp)
+		if c := compareString(at.Pattern, bt.Pattern); c != 0 {
+			return c
+		}
+		return compareString(at.Options, bt.Options)
+	case DBPointer:
+		bt := b.(DBPointer)
+		if c := compareString(at.Name, bt.Name); c != 0 {
+			return c
+		}
+		return bytes.Compare(at.ObjectId, bt.ObjectId)
+	case Javascript:
+		return compareString(string(at), string(b.(Javascript)))
+	case JavascriptScope:
+		bt := b.(JavascriptScope)
+		if c := compareString(at.Javascript, bt.Javascript); c != 0 {
+			return c
+		}
+		return Compare(at.Scope, bt.Scope)
+	}
+	// Types which only have one value.
+	return 0
+}
+
+// canonical converts coercion types to BSON types, and documents to Slice.
+func canonical(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case Map:
+		return vt.SortedSlice()
+	case BSON:
+		s, err := vt.Slice()
+		if err != nil {
+			return Binary(vt)
+		}
+		return s
+	case Undefined:
+		return nil
+	case Null:
+		return nil
+	case bool:
+		return Bool(vt)
+	case int8:
+		return Int32(vt)
+	case int16:
+		return Int32(vt)
+	case int32:
+		return Int32(vt)
+	case int:
+		return Int64(vt)
+	case int64:
+		return Int64(vt)
+	case float64:
+		return Float(vt)
+	case string:
+		return String(vt)
+	case time.Time:
+		return NewUTCDateTime(vt)
+	case []byte:
+		return Binary(vt)
+	case []interface{}:
+		return Array(vt)
+	}
+	return v
+}
+
+// compareRank returns the type order of a canonical value.
+func compareRank(v interface{}) int {
+	switch v.(type) {
+	case MinKey:
+		return 1
+	case emptyArray:
+		return 2
+	case nil:
+		return 3
+	case Int32, Int64, Float:
+		return 4
+	case String, Symbol:
+		return 5
+	case Slice:
+		return 6
+	case Array:
+		return 7
+	case Binary:
+		return 8
+	case ObjectId:
+		return 9
+	case Bool:
+		return 10
+	case UTCDateTime:
+		return 11
+	case Timestamp:
+		return 12
+	case Regexp:
+		return 13
+	case DBPointer:
+		return 14
+	case Javascript:
+		return 15
+	case JavascriptScope:
+		return 16
+	case MaxKey:
+		return 18
+	}
+	// Unknown types sort just before MaxKey.
+	return 17
+}
+
+// compareNumber compares two numbers. NaN is less than all other numbers.
+func compareNumber(a, b interface{}) int {
+	ai, aInt := numberInt(a)
+	bi, bInt := numberInt(b)
+	if aInt && bInt {
+		return compareInt(ai, bi)
+	}
+	af, bf := numberFloat(a), numberFloat(b)
+	switch {
+	case math.IsNaN(af) && math.IsNaN(bf):
+		return 0
+	case math.IsNaN(af):
+		return -1
+	case math.IsNaN(bf):
+		return 1
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+// numberInt returns the value of an integer type. Returns false if not an
+// integer type.
+func numberInt(v interface{}) (int64, bool) {
+	switch vt := v.(type) {
+	case Int32:
+		return int64(vt), true
+	case Int64:
+		return int64(vt), true
+	}
+	return 0, false
+}
+
+// numberFloat returns the value of a number as a float64.
+func numberFloat(v interface{}) float64 {
+	switch vt := v.(type) {
+	case Int32:
+		return float64(vt)
+	case Int64:
+		return float64(vt)
+	case Float:
+		return float64(vt)
+	}
+	return 0
+}
+
+// compareSlice compares documents element by element. Elements are compared
+// by type, then key, then value.
+func compareSlice(a, b Slice) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		av, bv := canonical(a[i].Val), canonical(b[i].Val)
+		if c := compareInt(int64(compareRank(av)), int64(compareRank(bv))); c != 0 {
+
+			return c
+		}
+		if c := compareString(a[i].Key, b[i].Key); c != 0 {
+			return c
+		}
+		if c := Compare(av, bv); c != 0 {
+			return c
+		}
+	}
+	return compareInt(int64(len(a)), int64(len(b)))
+}
+
+func compareInt(a, b int64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareString(a, b string) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,101 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Each value should compare less than the next.
+var compareOrder = []interface{}{
+	MinKey{},
+	nil,
+	Float(math.NaN()),
+	Int32(-1),
+	Float(0.5),
+	Int64(1),
+	String("a"),
+	Symbol("b"),
+	Map{"a": Int32(1)},
+	Map{"a": Int32(2)},
+	Slice{{"a", Int32(2)}, {"b", Int32(0)}},
+	Array{Int32(1)},
+	Array{Int32(1), Int32(2)},
+	Binary{0x02},
+	Binary{0x00, 0x01},
+	ObjectId{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00},
+	Bool(false),
+	true,
+	UTCDateTime(0),
+	Timestamp(0),
+	Regexp{"a", ""},
+	MaxKey{},
+}
+
+func TestCompare(t *testing.T) {
+	for i := 0; i < len(compareOrder)-1; i++ {
+		a, b := compareOrder[i], compareOrder[i+1]
+		if c := Compare(a, b); c != -1 {
+			t.Fatal(a, b, c)
+		}
+		if c := Compare(b, a); c != 1 {
+			t.Fatal(b, a, c)
+		}
+	}
+	if c := Compare(Int32(1), Float(1)); c != 0 {
+		t.Fatal(c)
+	}
+	if c := Compare(nil, Null{}); c != 0 {
+		t.Fatal(c)
+	}
+}
+
+func TestSortDocs(t *testing.T) {
+	docs := []Map{
+		Map{"id": 0, "a": Int32(2), "b": String("x")},
+		Map{"id": 1, "b": String("y")},
+		Map{"id": 2, "a": Int32(1), "b": String("z")},
+		Map{"id": 3, "a": Int32(2), "b": String("w")},
+		Map{"id": 4, "a": Array{Int32(5), Int32(0)}, "b": String("v")},
+	}
+	if err := SortDocs(docs, SortKey{Path: []string{"a"}},
+		SortKey{Path: []string{"b"}, Desc: true}); err != nil {
+
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, doc := range docs {
+		ids = append(ids, doc["id"].(int))
+	}
+	if exp := []int{1, 4, 2, 0, 3}; !reflect.DeepEqual(ids, exp) {
+		t.Fatal(ids, exp)
+	}
+
+	// Descending, array sorts by greatest element.
+	bs := []BSON{
+		Map{"a": Int32(3)}.MustEncode(),
+		Map{"a": Array{Int32(5), Int32(0)}}.MustEncode(),
+		Map{"a": Int32(4)}.MustEncode(),
+	}
+	if err := SortDocs(bs, SortKey{Path: []string{"a"}, Desc: true}); err != nil {
+		t.Fatal(err)
+	}
+	var a Int32
+	if ok, err := mustMap(t, bs[2]).Reach(&a, "a"); !ok || err != nil ||
+		a != 3 {
+
+		t.Fatal(ok, err, a)
+	}
+}
+
+func mustMap(t *testing.T, bs BSON) Map {
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
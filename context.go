@@ -0,0 +1,70 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// deadlineReader is a reader whose blocked reads can be interrupted, such as a
+// net.Conn or os.File.
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// ReadOneContext is the same as ReadOne but the read is abandoned if ctx is
+// done, in which case the error is ctx.Err(). If rd has a SetReadDeadline
+// method (e.g. net.Conn) a blocked read is interrupted by setting the deadline,
+// which is cleared when ReadOneContext returns, replacing any deadline set by
+// the caller. If ctx can't be done (e.g. context.Background()) the deadline
+// isn't touched. Otherwise ctx is checked before each read of rd. After a abandoned read rd is part way through a
+// document, so it should be closed rather than read again.
+func ReadOneContext(ctx context.Context, rd io.Reader) (BSON, error) {
+	return readOneContext(ctx, rd, nil, maxDocLen)
+}
+
+// readOneContext is the same as ReadOneContext but with a buffer and maximum
+// document size, see readOne.
+func readOneContext(ctx context.Context, rd io.Reader, buf []byte,
+	max int32) (BSON, error) {
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if dr, ok := rd.(deadlineReader); ok && ctx.Done() != nil {
+		interrupted := make(chan struct{})
+		stop := context.AfterFunc(ctx, func() {
+			dr.SetReadDeadline(time.Unix(1, 0))
+			close(interrupted)
+		})
+		defer func() {
+			if !stop() {
+				// Don't clear the deadline before it's set.
+				<-interrupted
+			}
+			dr.SetReadDeadline(time.Time{})
+		}()
+	}
+	bs, err := readOne(&contextReader{ctx: ctx, rd: rd}, buf, max)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return bs, err
+}
+
+// contextReader returns ctx.Err() instead of reading once ctx is done.
+type contextReader struct {
+	ctx context.Context
+	rd  io.Reader
+}
+
+func (this *contextReader) Read(p []byte) (int, error) {
+	if err := this.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return this.rd.Read(p)
+}
@@ -0,0 +1,56 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadOneContext(t *testing.T) {
+	bs := Map{"a": Int32(1)}.MustEncode()
+	bs1, err := ReadOneContext(context.Background(), bytes.NewReader(bs))
+	if err != nil || !reflect.DeepEqual(bs, bs1) {
+		t.Fatal(err, bs1)
+	}
+
+	// Already done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ReadOneContext(ctx, bytes.NewReader(bs)); err != ctx.Err() {
+		t.Fatal(err)
+	}
+
+	// Blocked read on a slow peer is interrupted.
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go server.Write(bs[:6])
+	ctx, cancel = context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	if _, err := ReadOneContext(ctx, client); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+
+	// The deadline is cleared.
+	go server.Write(bs)
+	dec := NewDecoder(client)
+	if _, err := dec.DecodeContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A deadline set by the caller is kept if ctx can't be done.
+	client.SetReadDeadline(time.Unix(1, 0))
+	_, err = ReadOneContext(context.Background(), client)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatal("Expected deadline exceeded.", err)
+	}
+}
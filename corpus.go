@@ -0,0 +1,312 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CorpusOptions describe the documents generated by a Corpus.
+type CorpusOptions struct {
+	// Schema the documents match. Required keys are always present, other keys
+	// in Fields are present in about half the documents. Values are within the
+	// Min, Max, MinLen and MaxLen of their schema. A nil Schema generates
+	// random documents, see RandomSlice.
+	Schema *Schema
+
+	// Size is the approximate size of each document in bytes. Documents
+	// smaller than this have a Binary "_pad" key added, if the Schema isn't
+	// Closed and allows another key. Otherwise the top level String and Binary
+	// values are grown, within their MaxLen, so documents may be smaller if
+	// they can't grow enough. 0 for no padding.
+	Size int
+
+	// Cardinality is the number of distinct values at a path, for paths which
+	// shouldn't be unique (e.g. a foreign key). Paths are the same as
+	// PathSummary.
+	Cardinality map[string]int
+
+	// Seed so the same options generate the same documents.
+	Seed int64
+}
+
+// Corpus generates documents for load tests, so that performance work can be
+// done without production data. For example:
+//   c := bson.NewCorpus(bson.CorpusOptions{
+//       Schema: schema,
+//       Size:   1024,
+//       Cardinality: map[string]int{"user_id": 100},
+//   })
+//   err := c.WriteTo(wr, 1000000)
+type Corpus struct {
+	opts CorpusOptions
+	rnd  *rand.Rand
+	gen  *randomGen
+}
+
+// NewCorpus returns a Corpus.
+func NewCorpus(opts CorpusOptions) *Corpus {
+	rnd := rand.New(rand.NewSource(opts.Seed))
+	return &Corpus{
+		opts: opts,
+		rnd:  rnd,
+		gen:  newRandomGen(rnd, RandomOptions{}),
+	}
+}
+
+// Next returns the next document.
+func (this *Corpus) Next() Slice {
+	var s Slice
+	if this.opts.Schema == nil {
+		s = this.gen.slice(this.gen.opts.MaxDepth)
+	} else {
+		s = this.doc(this.opts.Schema, "")
+	}
+	if this.opts.Size > 0 {
+		s = this.pad(s)
+	}
+	return s
+}
+
+// pad grows the document to about the Size. A Binary "_pad" key is added if
+// the schema allows it, otherwise String and Binary values are grown up to
+// their MaxLen.
+func (this *Corpus) pad(s Slice) Slice {
+	n := this.opts.Size - len(s.MustEncode())
+	schema := this.opts.Schema
+	if schema == nil || (!schema.Closed && schema.Fields["_pad"] == nil &&
+		(schema.MaxLen == nil || len(s) < *schema.MaxLen)) {
+
+		// Type, "_pad" key, length, subtype.
+		const overhead = 1 + 5 + 4 + 1
+		if n -= overhead; n > 0 {
+			pad := make(Binary, n)
+			this.rnd.Read(pad)
+			s = append(s, Pair{Key: "_pad", Val: pad})
+		}
+		return s
+	}
+	for i := 0; i < len(s) && n > 0; i++ {
+		if _, ok := this.opts.Cardinality[s[i].Key]; ok {
+			// Growing the value would make it distinct.
+			continue
+		}
+		fs := schema.Fields[s[i].Key]
+		grow := n
+		switch vt := s[i].Val.(type) {
+		case String:
+			if fs != nil && fs.MaxLen != nil {
+				grow = min(grow, *fs.MaxLen-utf8.RuneCountInString(string(vt)))
+			}
+			if grow > 0 {
+				s[i].Val = vt + this.letters(grow)
+			}
+		case Binary:
+			if fs != nil && fs.MaxLen != nil {
+				grow = min(grow, *fs.MaxLen-len(vt))
+			}
+			if grow > 0 {
+				b := make(Binary, grow)
+				this.rnd.Read(b)
+				s[i].Val = append(vt, b...)
+			}
+		default:
+			continue
+		}
+		if grow > 0 {
+			n -= grow
+		}
+	}
+	return s
+}
+
+// WriteTo writes n documents to wr, one after the other.
+func (this *Corpus) WriteTo(wr io.Writer, n int) error {
+	for i := 0; i < n; i++ {
+		bs, err := this.Next().Encode()
+		if err != nil {
+			return err
+		}
+		if _, err := wr.Write(bs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// doc generates a embedded document. The number of keys is within the MinLen
+// and MaxLen of the schema. If MinLen is more than the keys in Fields, and the
+// schema isn't Closed, keys which aren't in Fields are added.
+func (this *Corpus) doc(schema *Schema, path string) Slice {
+	required := make(map[string]bool, len(schema.Required))
+	for _, k := range schema.Required {
+		required[k] = true
+	}
+	var optional []string
+	for k := range schema.Fields {
+		if !required[k] {
+			optional = append(optional, k)
+		}
+	}
+	sort.Strings(optional)
+
+	// Pick the number of keys, each optional key is present in about half the
+	// documents.
+	lo, hi := len(required), len(required)+len(optional)
+	if schema.MinLen != nil && *schema.MinLen > lo {
+		lo = *schema.MinLen
+	}
+	if schema.MaxLen != nil && *schema.MaxLen < hi {
+		hi = *schema.MaxLen
+	}
+	n := lo
+	if hi > lo {
+		n += this.rnd.Intn(hi - lo + 1)
+	}
+	keys := make([]string, 0, n)
+	for k := range required {
+		keys = append(keys, k)
+	}
+	for _, i := range this.rnd.Perm(len(optional)) {
+		if len(keys) >= n {
+			break
+		}
+		keys = append(keys, optional[i])
+	}
+	sort.Strings(keys)
+	s := make(Slice, 0, n)
+	for _, k := range keys {
+		s = append(s, Pair{Key: k, Val: this.value(schema.Fields[k],
+			catpath(path, k))})
+	}
+	for i := 0; len(s) < n && !schema.Closed; i++ {
+		k := "_k" + strconv.Itoa(i)
+		s = append(s, Pair{Key: k, Val: this.value(nil, catpath(path, k))})
+	}
+	return s
+}
+
+// value generates a value at path. If the path has a cardinality the value is
+// one of that many, which are generated from the same seed each time.
+func (this *Corpus) value(schema *Schema, path string) interface{} {
+	k := this.opts.Cardinality[path]
+	if k <= 0 {
+		return this.schemaValue(schema, path)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	seed := this.opts.Seed ^ int64(h.Sum64()) + int64(this.rnd.Intn(k))
+	rnd := this.rnd
+	this.rnd = rand.New(rand.NewSource(seed))
+	defer func() {
+		this.rnd = rnd
+	}()
+	return this.schemaValue(schema, path)
+}
+
+// schemaValue generates a value which matches the schema.
+func (this *Corpus) schemaValue(schema *Schema, path string) interface{} {
+	if schema == nil {
+		schema = &Schema{}
+	}
+	types := schema.Types
+	if len(types) == 0 {
+		switch {
+		case schema.Fields != nil || schema.Required != nil:
+			types = []Type{TypeEmbeddedDocument}
+		case schema.Items != nil:
+			types = []Type{TypeArray}
+		default:
+			types = []Type{TypeString}
+		}
+	}
+	switch t := types[this.rnd.Intn(len(types))]; t {
+	case TypeFloat:
+		return Float(this.number(schema))
+	case TypeInt32:
+		return Int32(this.integer(schema))
+	case TypeInt64:
+		return Int64(this.integer(schema))
+	case TypeString:
+		return this.letters(this.length(schema, 1, 16))
+	case TypeBinary:
+		b := make(Binary, this.length(schema, 0, 16))
+		this.rnd.Read(b)
+		return b
+	case TypeArray:
+		a := make(Array, this.length(schema, 0, 4))
+		for i := range a {
+			a[i] = this.value(schema.Items, path+"[]")
+		}
+		return a
+	case TypeEmbeddedDocument:
+		return this.doc(schema, path)
+	default:
+		g := randomGen{rnd: this.rnd, opts: this.gen.opts}
+		g.opts.Types = []Type{t}
+		return g.value(0)
+	}
+}
+
+// letters returns a String of n random letters.
+func (this *Corpus) letters(n int) String {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + this.rnd.Intn(26))
+	}
+	return String(b)
+}
+
+// number returns a number in the range of the schema, 0 to 1000 by default.
+func (this *Corpus) number(schema *Schema) float64 {
+	min, max := 0.0, 1000.0
+	if schema.Min != nil {
+		min = *schema.Min
+		if schema.Max == nil {
+			max = min + 1000
+		}
+	}
+	if schema.Max != nil {
+		max = *schema.Max
+		if schema.Min == nil {
+			min = max - 1000
+		}
+	}
+	return min + this.rnd.Float64()*(max-min)
+}
+
+// integer returns a whole number in the range of the schema.
+func (this *Corpus) integer(schema *Schema) float64 {
+	n := math.Floor(this.number(schema))
+	if schema.Min != nil && n < *schema.Min {
+		n = math.Ceil(*schema.Min)
+	}
+	return n
+}
+
+// length returns a length in the range of the schema, or min to max.
+func (this *Corpus) length(schema *Schema, min, max int) int {
+	if schema.MinLen != nil {
+		min = *schema.MinLen
+		if schema.MaxLen == nil || max < min {
+			max = min + max
+		}
+	}
+	if schema.MaxLen != nil {
+		max = *schema.MaxLen
+		if schema.MinLen == nil && min > max {
+			min = max
+		}
+	}
+	if max <= min {
+		return min
+	}
+	return min + this.rnd.Intn(max-min+1)
+}
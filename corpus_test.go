@@ -0,0 +1,138 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	min, max := 10.0, 20.0
+	minLen, maxLen := 2, 3
+	schema := &Schema{
+		Required: []string{"_id", "user"},
+		Fields: map[string]*Schema{
+			"_id":  {Types: []Type{TypeObjectId}},
+			"user": {Types: []Type{TypeInt64}, Min: &min, Max: &max},
+			"tags": {Items: &Schema{Types: []Type{TypeString}}, MinLen: &minLen,
+				MaxLen: &maxLen},
+			"addr": {Fields: map[string]*Schema{"city": {}}},
+		},
+	}
+	opts := CorpusOptions{
+		Schema:      schema,
+		Size:        200,
+		Cardinality: map[string]int{"user": 3, "tags[]": 2},
+		Seed:        1,
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := NewCorpus(opts).WriteTo(buf, 100); err != nil {
+		t.Fatal(err)
+	}
+	dec := NewDecoder(bytes.NewReader(buf.Bytes()))
+	users := map[Int64]bool{}
+	tags := map[String]bool{}
+	n := 0
+	for {
+		doc, err := dec.DecodeMap()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		pad := doc["_pad"]
+		delete(doc, "_pad")
+		vs, err := schema.Validate(doc)
+		if err != nil || len(vs) != 0 {
+			t.Fatal(err, vs, doc)
+		}
+		size := len(doc.MustEncode()) + len(pad.(Binary)) + 11
+		if size < 195 || size > 205 {
+			t.Fatal(size)
+		}
+		users[doc["user"].(Int64)] = true
+		if a, ok := doc["tags"].(Array); ok {
+			for _, v := range a {
+				tags[v.(String)] = true
+			}
+		}
+	}
+	if n != 100 || len(users) > 3 || len(tags) > 2 {
+		t.Fatal(n, users, tags)
+	}
+
+	// Same seed, same documents.
+	c0, c1 := NewCorpus(opts), NewCorpus(opts)
+	if !reflect.DeepEqual(c0.Next(), c1.Next()) {
+		t.Fatal("not deterministic")
+	}
+}
+
+func TestCorpusValidate(t *testing.T) {
+	zero, one, two, three, eight := 0, 1, 2, 3, 8
+	min, max := -5.0, 5.0
+	schemas := []*Schema{
+		// Closed, padding grows the String.
+		{
+			Required: []string{"_id", "name"},
+			Fields: map[string]*Schema{
+				"_id":  {Types: []Type{TypeObjectId}},
+				"name": {Types: []Type{TypeString}, MinLen: &one},
+				"n": {Types: []Type{TypeInt32, TypeFloat}, Min: &min,
+					Max: &max},
+			},
+			Closed: true,
+		},
+		// Key count bounds, top level and embedded.
+		{
+			Fields: map[string]*Schema{
+				"a": {},
+				"b": {Types: []Type{TypeBinary}, MaxLen: &eight},
+				"c": {},
+				"d": {
+					Fields: map[string]*Schema{
+						"x": {}, "y": {}, "z": {},
+					},
+					MinLen: &two,
+					MaxLen: &two,
+				},
+				"e": {
+					Types:  []Type{TypeEmbeddedDocument},
+					MinLen: &three,
+				},
+			},
+			MinLen: &two,
+			MaxLen: &three,
+		},
+		// Closed and full, padding can't add a key.
+		{
+			Fields: map[string]*Schema{
+				"a": {Types: []Type{TypeBinary}},
+				"b": {Types: []Type{TypeArray}, MaxLen: &zero},
+			},
+			MinLen: &two,
+			MaxLen: &two,
+		},
+	}
+	for i, schema := range schemas {
+		c := NewCorpus(CorpusOptions{Schema: schema, Size: 100, Seed: 1})
+		for j := 0; j < 100; j++ {
+			s := c.Next()
+			vs, err := schema.Validate(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vs) != 0 {
+				t.Fatalf("Schema %v doc %v, %v %v.", i, j, vs, s)
+			}
+			if i != 1 && len(s.MustEncode()) < 100 {
+				t.Fatalf("Schema %v doc %v not padded, %v.", i, j, s)
+			}
+		}
+	}
+}
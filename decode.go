@@ -12,6 +12,7 @@ import (
 	"math"
 	"reflect"
 	"sort"
+	"strconv"
 )
 
 // maxDocLen is max supported size (bytes) of a document.
@@ -19,6 +20,27 @@ const maxDocLen = 64 * 1024 * 1024
 
 // ReadOne BSON document.
 func ReadOne(rd io.Reader) (BSON, error) {
+	return ReadOneBuf(rd, nil)
+}
+
+// ReadOneBuf is the same as ReadOne but reads in to buf, which is only grown
+// if the document doesn't fit. The returned BSON shares memory with buf, so it
+// must be copied if it's kept when buf is reused. For example:
+//   var buf []byte
+//   for {
+//       bs, err := bson.ReadOneBuf(rd, buf)
+//       if err != nil {
+//           return err
+//       }
+//       buf = bs
+//       ...
+//   }
+func ReadOneBuf(rd io.Reader, buf []byte) (BSON, error) {
+	return readOne(rd, buf, maxDocLen)
+}
+
+// readOne is the same as ReadOneBuf but with a maximum document size.
+func readOne(rd io.Reader, buf []byte, max int32) (BSON, error) {
 	// Read length of document.
 	docLen, err := readInt32(rd)
 	if err != nil {
@@ -26,14 +48,23 @@ func ReadOne(rd io.Reader) (BSON, error) {
 	}
 
 	// Sanity check length.
-	if docLen > maxDocLen {
-		return nil, errors.New("Doc exceeded maximum size.")
+	if docLen > max {
+		return nil, fmt.Errorf("%w.", ErrDocTooLarge)
+	}
+	if docLen < 5 {
+		return nil, errors.New("Doc smaller than minimum size.")
 	}
 
 	// Read the document.
-	buf := make([]byte, int(docLen))
+	if cap(buf) < int(docLen) {
+		buf = make([]byte, int(docLen))
+	}
+	buf = buf[:docLen]
 	binary.LittleEndian.PutUint32(buf, uint32(docLen))
 	if _, err := io.ReadFull(rd, buf[4:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Doc %w, %w.", ErrTruncated, err)
+		}
 		return nil, err
 	}
 
@@ -49,10 +80,24 @@ func ReadMap(rd io.Reader) (m Map, err error) {
 		}
 	}()
 
-	return decodeMap(rd, "", true)
+	return decodeMap(rd, "", 0, true)
+}
+
+// ReadMapInto reads one Map in to m. See BSON DecodeInto.
+func ReadMapInto(rd io.Reader, m Map) (err error) {
+	// Just in case of programming mistake. Not intentionally used.
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprint(r))
+		}
+	}()
+
+	_, err = decodeMapInto(rd, "", 0, true, m)
+	return err
 }
 
-// ReadMapNoNest reads one Map, but doesn't decode nested documents.
+// ReadMapNoNest reads one Map, but doesn't decode nested documents. See BSON
+// MapDepth to decode some levels of nested documents.
 func ReadMapNoNest(rd io.Reader) (m Map, err error) {
 	// Just in case of programming mistake. Not intentionally used.
 	defer func() {
@@ -61,7 +106,7 @@ func ReadMapNoNest(rd io.Reader) (m Map, err error) {
 		}
 	}()
 
-	return decodeMap(rd, "", false)
+	return decodeMap(rd, "", 0, false)
 }
 
 // ReadSlice reads one Slice, but doesn't decode nested documents.
@@ -73,7 +118,7 @@ func ReadSlice(rd io.Reader) (s Slice, err error) {
 		}
 	}()
 
-	return decodeSlice(rd, "", true)
+	return decodeSlice(rd, "", 0, true)
 }
 
 // ReadSliceNoNest reads one Slice, but doesn't decode nested documents.
@@ -85,43 +130,437 @@ func ReadSliceNoNest(rd io.Reader) (s Slice, err error) {
 		}
 	}()
 
-	return decodeSlice(rd, "", false)
+	return decodeSlice(rd, "", 0, false)
+}
+
+// Fields reports which struct fields were present in a decoded document. Keys
+// are Go field names. Fields of nested structs are dotted (e.g. "Addr.City").
+type Fields map[string]bool
+
+// DecodeStruct decodes BSON to the struct pointed to by dst. Fields are matched
+// to keys with the same struct tags used by EncodeStruct. Values are coerced
+// the same as with Reach. Nested documents may be decoded to structs, Map,
+// Slice, BSON or maps with string keys. Arrays may be decoded to slices.
+//
+// A pointer field is set to nil if the key is missing or Null, otherwise it's
+// set to a new value. This tells a missing field apart from a zero value.
+func DecodeStruct(bs BSON, dst interface{}) error {
+	return decodeStructBSON(bs, dst, &decodeState{})
+}
+
+// DecodeStructFields is the same as DecodeStruct but also returns which fields
+// were present in the document. This is used to tell a field with a zero value
+// apart from a missing field.
+func DecodeStructFields(bs BSON, dst interface{}) (Fields, error) {
+	fields := Fields{}
+	if err := decodeStructBSON(bs, dst, &decodeState{fields: fields}); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// Decode decodes BSON to a value of type T. T may be a struct, a pointer to a
+// struct, a map with string keys, Map, Slice or BSON. Structs are decoded the
+// same as with DecodeStruct. For example:
+//   bs, err := ReadOne(rd)
+//   ...
+//   user, err := Decode[User](bs)
+func Decode[T any](bs BSON) (T, error) {
+	var dst T
+	var err error
+	switch p := any(&dst).(type) {
+	case *BSON:
+		*p = bs
+	case *Map:
+		*p, err = bs.Map()
+	case *Slice:
+		*p, err = bs.Slice()
+	default:
+		rv := reflect.ValueOf(p).Elem()
+		t := rv.Type()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+			return dst, fmt.Errorf("cannot decode to %T.", dst)
+		}
+		err = decodeVal("", "", bs, rv, &decodeState{})
+	}
+	return dst, err
+}
+
+// decodeState is the state of decoding a struct.
+type decodeState struct {
+	fields Fields // Present fields are recorded if not nil.
+	strict bool   // Keys without a field are a error.
+}
+
+// elem returns the state for decoding a element of a map or slice. Present
+// fields aren't recorded for elements.
+func (this *decodeState) elem() *decodeState {
+	return &decodeState{strict: this.strict}
+}
+
+// decodeStructBSON decodes BSON to the struct pointed to by dst.
+func decodeStructBSON(bs BSON, dst interface{}, st *decodeState) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("dst must be a non-nil pointer to a struct.")
+	}
+	rv = indirectAlloc(rv)
+	if ok, err := unmarshal("", bs, rv); ok {
+		return err
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("cannot decode to %T, expected struct.", dst)
+	}
+	m, err := bs.MapNoNest()
+	if err != nil {
+		return err
+	}
+	return decodeStruct("", "", m, rv, st, nil)
+}
+
+// decodeStruct decodes a Map to a struct. The path keeps track of where in the
+// document we are for error reporting, the fpath keeps track of the Go field
+// path for recording present fields. The keys are those of the outermost
+// struct when decoding a inline struct, or nil if not decoding a inline struct.
+func decodeStruct(path, fpath string, src Map, dst reflect.Value,
+	st *decodeState, keys map[string]bool) error {
+
+	plan := planStruct(dst.Type())
+	if keys == nil {
+		if st.strict && !plan.rest {
+			if err := checkUnknown(path, src, plan.keys); err != nil {
+				return err
+			}
+		}
+		keys = plan.keys
+	}
+	for _, f := range plan.fields {
+		field := dst.Field(f.index)
+		if f.inline {
+			if err := decodeInline(path, catpath(fpath, f.name), src, field, st,
+				keys);
+				err != nil {
+
+				return err
+			}
+			continue
+		}
+		v, ok := src[f.key]
+		if !ok {
+			if f.required {
+				return decodeErrorf(catpath(path, f.key),
+					"required field %v missing.", f.name)
+			}
+			if f.typ.Kind() == reflect.Ptr {
+				field.Set(reflect.Zero(f.typ))
+			}
+			continue
+		}
+		fp := catpath(fpath, f.name)
+		if st.fields != nil {
+			st.fields[fp] = true
+		}
+		if f.transform != "" {
+			var err error
+			if v, err = applyTransform(catpath(path, f.key), f.transform, v, true);
+				err != nil {
+
+				return err
+			}
+		}
+		if f.truncate {
+			var err error
+			if v, err = truncate(catpath(path, f.key), v, f.typ); err != nil {
+				return err
+			}
+		}
+		if err := decodeVal(catpath(path, f.key), fp, v, field, st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncate converts a Float, Int64 or Int32 to the integer type t (or the type
+// t points to). A Float is truncated toward zero. Returns error if the value
+// doesn't fit. Values which aren't numbers, or a t which isn't a integer, are
+// returned as is.
+func truncate(path string, v interface{}, t reflect.Type) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	default:
+		return v, nil
+	}
+	var n int64
+	switch vt := v.(type) {
+	case Float:
+		f := math.Trunc(float64(vt))
+		if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return nil, decodeErrorf(path, "%v overflows %v.", vt, t)
+		}
+		n = int64(f)
+	case Int64:
+		n = int64(vt)
+	case Int32:
+		n = int64(vt)
+	default:
+		return v, nil
+	}
+	if reflect.Zero(t).OverflowInt(n) {
+		return nil, decodeErrorf(path, "%v overflows %v.", n, t)
+	}
+	return reflect.ValueOf(n).Convert(t).Interface(), nil
+}
+
+// decodeInline decodes to a inline struct the keys of its fields, or to a
+// inline map or Slice the keys which aren't for any other field. Keys added to
+// a Slice are sorted because the order of the document isn't known.
+func decodeInline(path, fpath string, src Map, dst reflect.Value,
+	st *decodeState, keys map[string]bool) error {
+
+	t := dst.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(Slice{}):
+		var s Slice
+		for k, v := range src {
+			if !keys[k] {
+				s = append(s, Pair{Key: k, Val: v})
+			}
+		}
+		if s == nil {
+			return nil
+		}
+		sort.Slice(s, func(i, j int) bool { return s[i].Key < s[j].Key })
+		indirectAlloc(dst).Set(reflect.ValueOf(s))
+		if st.fields != nil {
+			st.fields[fpath] = true
+		}
+		return nil
+	case t.Kind() == reflect.Struct:
+		return decodeStruct(path, fpath, src, indirectAlloc(dst), st, keys)
+	case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+		var rv reflect.Value
+		for k, v := range src {
+			if keys[k] {
+				continue
+			}
+			if !rv.IsValid() {
+				rv = indirectAlloc(dst)
+			}
+			ev := reflect.New(t.Elem()).Elem()
+			if err := decodeVal(catpath(path, k), fpath, v, ev, st.elem());
+				err != nil {
+
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), ev)
+		}
+		if rv.IsValid() && st.fields != nil {
+			st.fields[fpath] = true
+		}
+		return nil
+	}
+	return decodeErrorf(fpath, "cannot inline %v.", dst.Type())
+}
+
+// checkUnknown returns error if the document has a key which isn't in keys.
+func checkUnknown(path string, src Map, keys map[string]bool) error {
+	var unknown []string
+	for k := range src {
+		if !keys[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return decodeErrorf(catpath(path, unknown[0]), "unknown field.")
+}
+
+// decodeVal decodes a value to dst, which must be settable.
+func decodeVal(path, fpath string, src interface{}, dst reflect.Value,
+	st *decodeState) error {
+
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		if src == nil {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		v, err := decodeAny(path, src)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	}
+	if dst.Kind() == reflect.Ptr {
+		// Nil if Null, otherwise a new value so the old one isn't modified.
+		if _, ok := src.(Null); ok {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		dst.Set(reflect.New(dst.Type().Elem()))
+	}
+	rv := indirectAlloc(dst)
+	if ok, err := unmarshal(path, src, rv); ok {
+		return err
+	}
+	if ok, err := registeredDecode(path, src, rv); ok {
+		return err
+	}
+	if bs, ok := src.(BSON); ok {
+		// Nested document left encoded, decode it for the destination type.
+		var err error
+		switch rv.Type() {
+		case reflect.TypeOf(BSON{}):
+			rv.Set(reflect.ValueOf(bs))
+			return nil
+		case reflect.TypeOf(Slice{}):
+			src, err = bs.Slice()
+		default:
+			src, err = bs.MapNoNest()
+		}
+		if err != nil {
+			return prefixDecodeError(path, err)
+		}
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if m, ok := src.(Map); ok {
+			return decodeStruct(path, fpath, m, rv, st, nil)
+		}
+	case reflect.Map:
+		m, ok := src.(Map)
+		if !ok || rv.Type() == reflect.TypeOf(Map{}) ||
+			rv.Type().Key().Kind() != reflect.String {
+
+			break
+		}
+		for k, v := range m {
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeVal(catpath(path, k), fpath, v, ev, st.elem());
+				err != nil {
+
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()), ev)
+		}
+		return nil
+	case reflect.Slice:
+		a, ok := src.(Array)
+		if !ok || rv.Type() == reflect.TypeOf(Array{}) {
+			break
+		}
+		sl := reflect.MakeSlice(rv.Type(), len(a), len(a))
+		for i, v := range a {
+			if err := decodeVal(catpath(path, strconv.Itoa(i)), fpath, v,
+				sl.Index(i), st.elem()); err != nil {
+
+				return err
+			}
+		}
+		rv.Set(sl)
+		return nil
+	}
+	if _, err := assign(rv.Addr().Interface(), src); err != nil {
+		return prefixDecodeError(path, err)
+	}
+	return nil
+}
+
+// decodeAny decodes documents left encoded as BSON to Maps, including those in
+// arrays, for a interface{} destination.
+func decodeAny(path string, src interface{}) (interface{}, error) {
+	switch srct := src.(type) {
+	case BSON:
+		m, err := srct.Map()
+		if err != nil {
+			return nil, prefixDecodeError(path, err)
+		}
+		return m, nil
+	case Array:
+		a := make(Array, len(srct))
+		for i, v := range srct {
+			e, err := decodeAny(catpath(path, strconv.Itoa(i)), v)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = e
+		}
+		return a, nil
+	}
+	return src, nil
 }
 
 // decodeMap decodes to a Map. The path is used to keep track of where we've
-// recursed to in the document. If nest is true then nested documents are
-// decoded.
-func decodeMap(rdTmp io.Reader, path string, nest bool) (Map, error) {
+// recursed to in the document, the base is the offset of the document in the
+// outermost one. If nest is true then nested documents are decoded.
+func decodeMap(rdTmp io.Reader, path string, base int, nest bool) (Map,
+	error) {
+
+	return decodeMapInto(rdTmp, path, base, nest, Map{})
+}
+
+// decodeMapInto is the same as decodeMap but decodes to dst, which is cleared
+// first. Maps nested in dst are reused for nested documents with the same key.
+func decodeMapInto(rdTmp io.Reader, path string, base int, nest bool,
+	dst Map) (Map, error) {
+
+	var old map[string]Map
+	for k, v := range dst {
+		if m, ok := v.(Map); ok && nest {
+			if old == nil {
+				old = map[string]Map{}
+			}
+			old[k] = m
+		}
+		delete(dst, k)
+	}
+
 	// Read doc length.
 	docLen, err := readInt32(rdTmp)
 	if err != nil {
 		return nil, err
 	}
-	if docLen > maxDocLen {
-		return nil, errors.New("Doc exceeded maximum size.")
+	if err := checkDocLen(rdTmp, docLen); err != nil {
+		return nil, decodeError(path, "", base, 0, err)
 	}
-	rd := bufio.NewReader(io.LimitReader(rdTmp, int64(docLen-4)))
+	rd := newDocReader(rdTmp, docLen)
 
 	// Read doc.
-	dst := Map{}
 	for {
+		// Offset of the element, for errors.
+		off := base + int(docLen) - rd.remaining()
 		eType, err := rd.ReadByte()
 		if err != nil {
-			return nil, err
+			return nil, decodeError(path, "", off, 0, err)
 		}
 		switch eType {
 		case 0x00:
+			if err := checkDocEnd(rd, docLen, off-base+1); err != nil {
+				return nil, decodeError(path, "", base, 0, err)
+			}
 			return dst, nil
 		case _FLOATING_POINT:
 			name, val, err := decodeFloat(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _STRING:
 			name, val, err := decodeString(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _EMBEDDED_DOCUMENT:
@@ -129,165 +568,179 @@ func decodeMap(rdTmp io.Reader, path string, nest bool) (Map, error) {
 			// name
 			name, err := readCstring(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, "", off, eType, err)
 			}
 			if !nest {
 				bs, err := ReadOne(rd)
 				if err != nil {
-					return nil, err
+					return nil, decodeError(path, name, off, eType, err)
 				}
 				dst[name] = bs
 			} else {
 				// value
-				val, err := decodeMap(rd, catpath(path, name), true)
+				reuse, ok := old[name]
+				if ok {
+					delete(old, name)
+				} else {
+					reuse = Map{}
+				}
+				val, err := decodeMapInto(rd, catpath(path, name),
+					off+1+len(name)+1, true, reuse)
 				if err != nil {
-					return nil, err
+					return nil, decodeError(path, name, off, eType, err)
 				}
 				dst[name] = val
 			}
 		case _ARRAY:
-			name, val, err := decodeArray(rd, path)
+			name, val, err := decodeArray(rd, path, off, false)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _BINARY_DATA:
 			name, val, err := decodeBinary(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _UNDEFINED:
 			name, val, err := decodeUndefined(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _OBJECT_ID:
 			name, val, err := decodeObjectId(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _BOOLEAN:
 			name, val, err := decodeBool(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _UTC_DATETIME:
 			name, val, err := decodeUTCDateTime(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _NULL_VALUE:
 			name, val, err := decodeNull(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _REGEXP:
 			name, val, err := decodeRegexp(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _DBPOINTER:
 			name, val, err := decodeDBPointer(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _JAVASCRIPT:
 			name, val, err := decodeJavascript(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _SYMBOL:
 			name, val, err := decodeSymbol(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _JAVASCRIPT_SCOPE:
-			name, val, err := decodeJavascriptScope(rd, path)
+			name, val, err := decodeJavascriptScope(rd, path, off, false)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _32BIT_INTEGER:
 			name, val, err := decodeInt32(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _TIMESTAMP:
 			name, val, err := decodeTimestamp(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _64BIT_INTEGER:
 			name, val, err := decodeInt64(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _MIN_KEY:
 			name, val, err := decodeMinKey(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		case _MAX_KEY:
 			name, val, err := decodeMaxKey(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst[name] = val
 		default:
-			return nil, fmt.Errorf("Unsupported type '%X'.", eType)
+			// The name is only for the error.
+			name, _ := readCstring(rd)
+			return nil, decodeError(path, name, off, eType,
+				fmt.Errorf("%w '%X'.", ErrUnsupportedType, eType))
 		}
 	}
-	return nil, nil
 }
 
-// decodeSlice decodes to a Slice. The path is used to keep track of where we've
-// recursed to in the document. If nest is true then nested documents are
-// decoded.
-func decodeSlice(rdTmp io.Reader, path string, nest bool) (Slice, error) {
+// decodeSlice decodes to a Slice. The path and base are the same as for
+// decodeMap. If nest is true then nested documents are decoded.
+func decodeSlice(rdTmp io.Reader, path string, base int, nest bool) (Slice,
+	error) {
 	// Read doc length.
 	docLen, err := readInt32(rdTmp)
 	if err != nil {
 		return nil, err
 	}
-	if docLen > maxDocLen {
-		return nil, errors.New("Doc exceeded maximum size.")
+	if err := checkDocLen(rdTmp, docLen); err != nil {
+		return nil, decodeError(path, "", base, 0, err)
 	}
-	rd := bufio.NewReader(io.LimitReader(rdTmp, int64(docLen-4)))
+	rd := newDocReader(rdTmp, docLen)
 
 	// Read doc.
 	dst := Slice{}
 	for {
+		// Offset of the element, for errors.
+		off := base + int(docLen) - rd.remaining()
 		eType, err := rd.ReadByte()
 		if err != nil {
-			return nil, err
+			return nil, decodeError(path, "", off, 0, err)
 		}
 		switch eType {
 		case 0x00:
+			if err := checkDocEnd(rd, docLen, off-base+1); err != nil {
+				return nil, decodeError(path, "", base, 0, err)
+			}
 			return dst, nil
 		case _FLOATING_POINT:
 			name, val, err := decodeFloat(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _STRING:
 			name, val, err := decodeString(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _EMBEDDED_DOCUMENT:
@@ -295,133 +748,197 @@ func decodeSlice(rdTmp io.Reader, path string, nest bool) (Slice, error) {
 			// name
 			name, err := readCstring(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, "", off, eType, err)
 			}
 			if !nest {
 				bs, err := ReadOne(rd)
 				if err != nil {
-					return nil, err
+					return nil, decodeError(path, name, off, eType, err)
 				}
 				dst = append(dst, Pair{Key: name, Val: bs})
 			} else {
 				// value
-				val, err := decodeSlice(rd, catpath(path, name), true)
+				val, err := decodeSlice(rd, catpath(path, name),
+					off+1+len(name)+1, true)
 				if err != nil {
-					return nil, err
+					return nil, decodeError(path, name, off, eType, err)
 				}
 				dst = append(dst, Pair{Key: name, Val: val})
 			}
 		case _ARRAY:
-			name, val, err := decodeArray(rd, path)
+			name, val, err := decodeArray(rd, path, off, true)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _BINARY_DATA:
 			name, val, err := decodeBinary(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _UNDEFINED:
 			name, val, err := decodeUndefined(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _OBJECT_ID:
 			name, val, err := decodeObjectId(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _BOOLEAN:
 			name, val, err := decodeBool(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _UTC_DATETIME:
 			name, val, err := decodeUTCDateTime(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _NULL_VALUE:
 			name, val, err := decodeNull(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _REGEXP:
 			name, val, err := decodeRegexp(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _DBPOINTER:
 			name, val, err := decodeDBPointer(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _JAVASCRIPT:
 			name, val, err := decodeJavascript(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _SYMBOL:
 			name, val, err := decodeSymbol(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _JAVASCRIPT_SCOPE:
-			name, val, err := decodeJavascriptScope(rd, path)
+			name, val, err := decodeJavascriptScope(rd, path, off, true)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _32BIT_INTEGER:
 			name, val, err := decodeInt32(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _TIMESTAMP:
 			name, val, err := decodeTimestamp(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _64BIT_INTEGER:
 			name, val, err := decodeInt64(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _MIN_KEY:
 			name, val, err := decodeMinKey(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		case _MAX_KEY:
 			name, val, err := decodeMaxKey(rd)
 			if err != nil {
-				return nil, err
+				return nil, decodeError(path, name, off, eType, err)
 			}
 			dst = append(dst, Pair{Key: name, Val: val})
 		default:
-			return nil, fmt.Errorf("Unsupported type '%X'.", eType)
+			// The name is only for the error.
+			name, _ := readCstring(rd)
+			return nil, decodeError(path, name, off, eType,
+				fmt.Errorf("%w '%X'.", ErrUnsupportedType, eType))
 		}
 	}
-	return nil, nil
 }
 
-// decodeArray decodes a BSON Array element.
-func decodeArray(rd *bufio.Reader, path string) (string, Array, error) {
+// docReader reads the elements of one document, after its length.
+type docReader struct {
+	*bufio.Reader
+	lr *io.LimitedReader
+}
+
+// newDocReader returns a docReader for a document of length docLen, whose
+// length has already been read from rd.
+func newDocReader(rd io.Reader, docLen int32) *docReader {
+	lr := &io.LimitedReader{R: rd, N: int64(docLen - 4)}
+	return &docReader{Reader: bufio.NewReader(lr), lr: lr}
+}
+
+// remaining returns the number of bytes of the document not yet read.
+func (this *docReader) remaining() int {
+	return int(this.lr.N) + this.Buffered()
+}
+
+// checkDocLen returns error if docLen isn't valid for a document read from rd.
+// If rd is a docReader the document must fit in what remains of it.
+func checkDocLen(rd io.Reader, docLen int32) error {
+	if docLen > maxDocLen {
+		return fmt.Errorf("%w.", ErrDocTooLarge)
+	}
+	if docLen < 5 {
+		return fmt.Errorf("Doc length %v invalid.", docLen)
+	}
+	if p, ok := rd.(*docReader); ok && int(docLen)-4 > p.remaining() {
+		return fmt.Errorf("Doc %w.", ErrTruncated)
+	}
+	return nil
+}
+
+// readLen reads a length field, which must be at least min and fit in what
+// remains of the document.
+func readLen(rd *docReader, min int32) (int, error) {
+	n, err := readInt32(rd)
+	if err != nil {
+		return 0, err
+	}
+	if n < min {
+		return 0, fmt.Errorf("Length %v invalid.", n)
+	}
+	if int(n) > rd.remaining() {
+		return 0, fmt.Errorf("Element %w.", ErrTruncated)
+	}
+	return int(n), nil
+}
+
+// checkDocEnd returns error if the null byte which ends a document of length
+// docLen was at n bytes, or if rd has more of the document.
+func checkDocEnd(rd *docReader, docLen int32, n int) error {
+	if rd.remaining() != 0 || int(docLen) != n {
+		return fmt.Errorf("Doc length %v but ended after %v bytes.", docLen, n)
+	}
+	return nil
+}
+
+// decodeArray decodes a BSON Array element. If slice is true then documents
+// in the Array are decoded to Slice, otherwise Map.
+func decodeArray(rd *docReader, path string, off int, slice bool) (string,
+	Array, error) {
+
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -429,28 +946,41 @@ func decodeArray(rd *bufio.Reader, path string) (string, Array, error) {
 	}
 
 	// value
-	doc, err := decodeMap(rd, path, true)
+	base := off + 1 + len(name) + 1
+	var doc Slice
+	if slice {
+		doc, err = decodeSlice(rd, catpath(path, name), base, true)
+	} else {
+		var m Map
+		m, err = decodeMap(rd, catpath(path, name), base, true)
+		for k, v := range m {
+			doc = append(doc, Pair{Key: k, Val: v})
+		}
+	}
 	if err != nil {
-		return "", nil, err
+		return name, nil, err
 	}
 
-	// BSON index names may not be ordered. Sort.
-	ns := make([]string, 0, len(doc))
-	for name, _ := range doc {
-		ns = append(ns, name)
-	}
-	sort.Strings(ns)
+	// BSON index names may not be ordered. Sort numerically.
+	sort.SliceStable(doc, func(i, j int) bool {
+		a, aerr := strconv.Atoi(doc[i].Key)
+		b, berr := strconv.Atoi(doc[j].Key)
+		if aerr != nil || berr != nil {
+			return doc[i].Key < doc[j].Key
+		}
+		return a < b
+	})
 
 	// Build slice.
-	slice := reflect.MakeSlice(reflect.TypeOf(Array{}), 0, len(ns))
-	for _, name := range ns {
-		slice = reflect.Append(slice, reflect.ValueOf(doc[name]))
+	a := make(Array, 0, len(doc))
+	for _, pair := range doc {
+		a = append(a, pair.Val)
 	}
-	return name, slice.Interface().(Array), nil
+	return name, a, nil
 }
 
 // decodeBinary decodes BSON Binary element.
-func decodeBinary(rd *bufio.Reader) (string, Binary, error) {
+func decodeBinary(rd *docReader) (string, Binary, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -458,26 +988,26 @@ func decodeBinary(rd *bufio.Reader) (string, Binary, error) {
 	}
 
 	// value
-	dataLen, err := readInt32(rd)
+	dataLen, err := readLen(rd, 0)
 	if err != nil {
-		return "", nil, err
+		return name, nil, err
 	}
 
 	// discard subtype
 	_, err = rd.ReadByte()
 	if err != nil {
-		return "", nil, err
+		return name, nil, err
 	}
 	b := make([]byte, dataLen)
 	_, err = io.ReadFull(rd, b)
 	if err != nil {
-		return "", nil, err
+		return name, nil, err
 	}
 	return name, Binary(b), nil
 }
 
 // decodeBool decodes BSON Bool element.
-func decodeBool(rd *bufio.Reader) (string, Bool, error) {
+func decodeBool(rd *docReader) (string, Bool, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -487,13 +1017,13 @@ func decodeBool(rd *bufio.Reader) (string, Bool, error) {
 	// value
 	b, err := rd.ReadByte()
 	if err != nil {
-		return "", false, err
+		return name, false, err
 	}
 	return name, Bool(b == 0x01), nil
 }
 
 // decodeDBPointer decodes BSON DBPointer.
-func decodeDBPointer(rd *bufio.Reader) (string, DBPointer, error) {
+func decodeDBPointer(rd *docReader) (string, DBPointer, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -503,18 +1033,18 @@ func decodeDBPointer(rd *bufio.Reader) (string, DBPointer, error) {
 	// value
 	Name, err := readString(rd)
 	if err != nil {
-		return "", DBPointer{}, err
+		return name, DBPointer{}, err
 	}
 	b := make([]byte, 12)
 	_, err = io.ReadFull(rd, b)
 	if err != nil {
-		return "", DBPointer{}, err
+		return name, DBPointer{}, err
 	}
 	return name, DBPointer{Name: Name, ObjectId: ObjectId(b)}, nil
 }
 
 // decodeFloat decodes BSON Float element.
-func decodeFloat(rd *bufio.Reader) (string, Float, error) {
+func decodeFloat(rd *docReader) (string, Float, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -522,25 +1052,15 @@ func decodeFloat(rd *bufio.Reader) (string, Float, error) {
 	}
 
 	// value
-	b := make([]byte, 8)
-	_, err = io.ReadFull(rd, b)
+	i64, err := readInt64(rd)
 	if err != nil {
-		return "", Float(0), err
+		return name, Float(0), err
 	}
-	var u uint64
-	u += uint64(b[7]) << 56
-	u += uint64(b[6]) << 48
-	u += uint64(b[5]) << 40
-	u += uint64(b[4]) << 32
-	u += uint64(b[3]) << 24
-	u += uint64(b[2]) << 16
-	u += uint64(b[1]) << 8
-	u += uint64(b[0])
-	return name, Float(math.Float64frombits(u)), nil
+	return name, Float(math.Float64frombits(uint64(i64))), nil
 }
 
 // decodeInt32 decodes BSON Int32 element.
-func decodeInt32(rd *bufio.Reader) (string, Int32, error) {
+func decodeInt32(rd *docReader) (string, Int32, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -550,13 +1070,13 @@ func decodeInt32(rd *bufio.Reader) (string, Int32, error) {
 	// value
 	i32, err := readInt32(rd)
 	if err != nil {
-		return "", 0, err
+		return name, 0, err
 	}
 	return name, Int32(i32), nil
 }
 
 // decodeInt64 decodes BSON Int64 element.
-func decodeInt64(rd *bufio.Reader) (string, Int64, error) {
+func decodeInt64(rd *docReader) (string, Int64, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -566,13 +1086,13 @@ func decodeInt64(rd *bufio.Reader) (string, Int64, error) {
 	// value
 	i64, err := readInt64(rd)
 	if err != nil {
-		return "", 0, err
+		return name, 0, err
 	}
 	return name, Int64(i64), nil
 }
 
 // decodeJavascript decodes BSON Javascript element.
-func decodeJavascript(rd *bufio.Reader) (string, Javascript, error) {
+func decodeJavascript(rd *docReader) (string, Javascript, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -582,13 +1102,16 @@ func decodeJavascript(rd *bufio.Reader) (string, Javascript, error) {
 	// value
 	s, err := readString(rd)
 	if err != nil {
-		return "", "", err
+		return name, "", err
 	}
 	return name, Javascript(s), nil
 }
 
-// decodeJavascriptScope decodes BSON JavascriptScope element.
-func decodeJavascriptScope(rd *bufio.Reader, path string) (string, JavascriptScope, error) {
+// decodeJavascriptScope decodes BSON JavascriptScope element. If slice is true
+// the scope is decoded to a Slice, otherwise a Map.
+func decodeJavascriptScope(rd *docReader, path string, off int,
+	slice bool) (string, JavascriptScope, error) {
+
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -596,23 +1119,42 @@ func decodeJavascriptScope(rd *bufio.Reader, path string) (string, JavascriptSco
 	}
 
 	// value
-	_, err = readInt32(rd)
+	codeLen, err := readInt32(rd)
 	if err != nil {
-		return "", JavascriptScope{}, err
+		return name, JavascriptScope{}, err
+	}
+	if codeLen < 4+4+1+5 || int(codeLen)-4 > rd.remaining() {
+		return name, JavascriptScope{},
+			fmt.Errorf("JavascriptScope length %v invalid.", codeLen)
 	}
 	js, err := readString(rd)
 	if err != nil {
-		return "", JavascriptScope{}, err
+		return name, JavascriptScope{}, err
 	}
-	m, err := decodeMap(rd, "", true)
+	b, err := rd.Peek(4)
 	if err != nil {
-		return "", JavascriptScope{}, err
+		return name, JavascriptScope{}, err
 	}
-	return name, JavascriptScope{Javascript: js, Scope: m}, nil
+	scopeLen := int(int32(binary.LittleEndian.Uint32(b)))
+	if int(codeLen) != 4+4+len(js)+1+scopeLen {
+		return name, JavascriptScope{},
+			fmt.Errorf("JavascriptScope length %v invalid.", codeLen)
+	}
+	base := off + 1 + len(name) + 1 + 4 + 4 + len(js) + 1
+	var scope Doc
+	if slice {
+		scope, err = decodeSlice(rd, catpath(path, name), base, true)
+	} else {
+		scope, err = decodeMap(rd, catpath(path, name), base, true)
+	}
+	if err != nil {
+		return name, JavascriptScope{}, err
+	}
+	return name, JavascriptScope{Javascript: js, Scope: scope}, nil
 }
 
 // decodeMaxKey decodes BSON MaxKey element.
-func decodeMaxKey(rd *bufio.Reader) (string, MaxKey, error) {
+func decodeMaxKey(rd *docReader) (string, MaxKey, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -622,7 +1164,7 @@ func decodeMaxKey(rd *bufio.Reader) (string, MaxKey, error) {
 }
 
 // decodeMinKey decodes BSON JavascriptMinKey element.
-func decodeMinKey(rd *bufio.Reader) (string, MinKey, error) {
+func decodeMinKey(rd *docReader) (string, MinKey, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -632,7 +1174,7 @@ func decodeMinKey(rd *bufio.Reader) (string, MinKey, error) {
 }
 
 // decodeNull decodes BSON Null element.
-func decodeNull(rd *bufio.Reader) (string, Null, error) {
+func decodeNull(rd *docReader) (string, Null, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -642,7 +1184,7 @@ func decodeNull(rd *bufio.Reader) (string, Null, error) {
 }
 
 // decodeObjectId decodes BSON ObjectId element.
-func decodeObjectId(rd *bufio.Reader) (string, ObjectId, error) {
+func decodeObjectId(rd *docReader) (string, ObjectId, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -653,13 +1195,13 @@ func decodeObjectId(rd *bufio.Reader) (string, ObjectId, error) {
 	b := make([]byte, 12)
 	_, err = io.ReadFull(rd, b)
 	if err != nil {
-		return "", nil, err
+		return name, nil, err
 	}
 	return name, ObjectId(b), nil
 }
 
 // decodeRegexp decodes BSON Regexp element.
-func decodeRegexp(rd *bufio.Reader) (string, Regexp, error) {
+func decodeRegexp(rd *docReader) (string, Regexp, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -669,19 +1211,19 @@ func decodeRegexp(rd *bufio.Reader) (string, Regexp, error) {
 	// pattern
 	pattern, err := readCstring(rd)
 	if err != nil {
-		return "", Regexp{}, err
+		return name, Regexp{}, err
 	}
 
 	// options
 	options, err := readCstring(rd)
 	if err != nil {
-		return "", Regexp{}, err
+		return name, Regexp{}, err
 	}
 	return name, Regexp{Pattern: pattern, Options: options}, nil
 }
 
 // decodeString decodes BSON String element.
-func decodeString(rd *bufio.Reader) (string, String, error) {
+func decodeString(rd *docReader) (string, String, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -691,13 +1233,13 @@ func decodeString(rd *bufio.Reader) (string, String, error) {
 	// value
 	s, err := readString(rd)
 	if err != nil {
-		return "", "", err
+		return name, "", err
 	}
 	return name, String(s), nil
 }
 
 // decodeSymbol decodes BSON Symbol element.
-func decodeSymbol(rd *bufio.Reader) (string, Symbol, error) {
+func decodeSymbol(rd *docReader) (string, Symbol, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -707,13 +1249,13 @@ func decodeSymbol(rd *bufio.Reader) (string, Symbol, error) {
 	// value
 	s, err := readString(rd)
 	if err != nil {
-		return "", "", err
+		return name, "", err
 	}
 	return name, Symbol(s), nil
 }
 
 // decodeTimestamp decodes BSON Timestamp element.
-func decodeTimestamp(rd *bufio.Reader) (string, Timestamp, error) {
+func decodeTimestamp(rd *docReader) (string, Timestamp, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -723,13 +1265,13 @@ func decodeTimestamp(rd *bufio.Reader) (string, Timestamp, error) {
 	// value
 	i64, err := readInt64(rd)
 	if err != nil {
-		return "", 0, err
+		return name, 0, err
 	}
 	return name, Timestamp(i64), nil
 }
 
 // decodeUndefined decodes BSON Undefined element.
-func decodeUndefined(rd *bufio.Reader) (string, Undefined, error) {
+func decodeUndefined(rd *docReader) (string, Undefined, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -739,7 +1281,7 @@ func decodeUndefined(rd *bufio.Reader) (string, Undefined, error) {
 }
 
 // decodeUTCDateTime decodes BSON UTCDateTime element.
-func decodeUTCDateTime(rd *bufio.Reader) (string, UTCDateTime, error) {
+func decodeUTCDateTime(rd *docReader) (string, UTCDateTime, error) {
 	// name
 	name, err := readCstring(rd)
 	if err != nil {
@@ -749,13 +1291,13 @@ func decodeUTCDateTime(rd *bufio.Reader) (string, UTCDateTime, error) {
 	// value
 	i64, err := readInt64(rd)
 	if err != nil {
-		return "", 0, err
+		return name, 0, err
 	}
 	return name, UTCDateTime(i64), nil
 }
 
 // readCString reads one BSON C string. This is not a BSON element.
-func readCstring(rd *bufio.Reader) (string, error) {
+func readCstring(rd *docReader) (string, error) {
 	s, err := rd.ReadString(0x00)
 	if err != nil {
 		return "", err
@@ -765,37 +1307,37 @@ func readCstring(rd *bufio.Reader) (string, error) {
 
 // readBSONInt32 reads one int32. This is not a BSON element.
 func readInt32(rd io.Reader) (int32, error) {
-	var i int32
-	if err := binary.Read(rd, binary.LittleEndian, &i); err != nil {
+	var b [4]byte
+	if _, err := io.ReadFull(rd, b[:]); err != nil {
 		return 0, err
 	}
-	return i, nil
+	return int32(binary.LittleEndian.Uint32(b[:])), nil
 }
 
 // readInt64 reads one int64. This is not a BSON element.
 func readInt64(rd io.Reader) (int64, error) {
-	var i int64
-	if err := binary.Read(rd, binary.LittleEndian, &i); err != nil {
+	var b [8]byte
+	if _, err := io.ReadFull(rd, b[:]); err != nil {
 		return 0, err
 	}
-	return i, nil
+	return int64(binary.LittleEndian.Uint64(b[:])), nil
 }
 
 // readString reads one string. This is not a BSON element.
-func readString(rd *bufio.Reader) (string, error) {
+func readString(rd *docReader) (string, error) {
 	// Read string length.
-	var sLen int32
-	if err := binary.Read(rd, binary.LittleEndian, &sLen); err != nil {
+	sLen, err := readLen(rd, 1)
+	if err != nil {
 		return "", err
 	}
-	if sLen == 0 {
-		return "", nil
-	}
 
 	// Read string.
 	b := make([]byte, sLen)
 	if _, err := io.ReadFull(rd, b); err != nil {
 		return "", err
 	}
+	if b[len(b)-1] != 0x00 {
+		return "", errors.New("String not null terminated.")
+	}
 	return string(b[:len(b)-1]), nil
 }
@@ -0,0 +1,205 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Decoder reads and decodes documents from a stream.
+type Decoder struct {
+	// Hooks are called in order on each document after it's read. The first
+	// hook is passed the raw BSON. A hook may return any Doc type. For example,
+	// a hook which decrypts may return BSON, and a hook which migrates a schema
+	// may return a Map.
+	Hooks []Hook
+
+	// DisallowUnknownFields makes DecodeStruct return error if the document
+	// has a key which isn't for any field of the struct, including in nested
+	// structs. A struct with a inline map or remain field takes any key.
+	DisallowUnknownFields bool
+
+	// MaxAlloc limits the bytes allocated to decode one document, if > 0. This
+	// counts the encoded document, and for DecodeMap, DecodeSlice and
+	// DecodeNative the keys, elements, strings, binaries and nested documents
+	// decoded from it. Set this when decoding untrusted input.
+	MaxAlloc int
+
+	// NestDepth limits the depth of documents decoded by DecodeMap, DecodeSlice
+	// and DecodeNative, if > 0. Deeper documents are left encoded as BSON, see
+	// BSON MapDepth.
+	NestDepth int
+
+	rd io.Reader
+}
+
+// NewDecoder returns a Decoder which reads from rd.
+func NewDecoder(rd io.Reader) *Decoder {
+	return &Decoder{rd: rd}
+}
+
+// Decode reads one document from the stream and passes it through the hooks.
+func (this *Decoder) Decode() (Doc, error) {
+	bs, err := readOne(this.rd, nil, this.maxDocLen())
+	if err != nil {
+		return nil, err
+	}
+	return this.Unmarshal(bs)
+}
+
+// DecodeContext is the same as Decode but the read is abandoned if ctx is
+// done, see ReadOneContext.
+func (this *Decoder) DecodeContext(ctx context.Context) (Doc, error) {
+	bs, err := readOneContext(ctx, this.rd, nil, this.maxDocLen())
+	if err != nil {
+		return nil, err
+	}
+	return this.Unmarshal(bs)
+}
+
+// maxDocLen returns the largest document which may be read.
+func (this *Decoder) maxDocLen() int32 {
+	if this.MaxAlloc > 0 && this.MaxAlloc < maxDocLen {
+		return int32(this.MaxAlloc)
+	}
+	return maxDocLen
+}
+
+// DecodeBSON is the same as Decode but converts the result to BSON.
+func (this *Decoder) DecodeBSON() (BSON, error) {
+	doc, err := this.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return doc.Encode()
+}
+
+// DecodeMap is the same as Decode but converts the result to a Map.
+func (this *Decoder) DecodeMap() (Map, error) {
+	doc, err := this.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return this.toMap(doc)
+}
+
+// DecodeSlice is the same as Decode but converts the result to a Slice.
+func (this *Decoder) DecodeSlice() (Slice, error) {
+	doc, err := this.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return this.toSlice(doc)
+}
+
+// DecodeNative is the same as Decode but converts the result to standard Go
+// types, the same as BSON Native.
+func (this *Decoder) DecodeNative() (map[string]interface{}, error) {
+	doc, err := this.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := doc.(Slice); ok {
+		return s.Native()
+	}
+	m, err := this.toMap(doc)
+	if err != nil {
+		return nil, err
+	}
+	return m.Native()
+}
+
+// DecodeStruct is the same as Decode but decodes the result to the struct
+// pointed to by dst, the same as the DecodeStruct func.
+func (this *Decoder) DecodeStruct(dst interface{}) error {
+	bs, err := this.DecodeBSON()
+	if err != nil {
+		return err
+	}
+	return decodeStructBSON(bs, dst,
+		&decodeState{strict: this.DisallowUnknownFields})
+}
+
+// Unmarshal passes the document through the hooks instead of reading it from
+// the stream.
+func (this *Decoder) Unmarshal(bs BSON) (Doc, error) {
+	var doc Doc = bs
+	for _, hook := range this.Hooks {
+		var err error
+		if doc, err = hook(doc); err != nil {
+			return nil, err
+		}
+	}
+	if doc == nil {
+		return nil, errors.New("hook returned nil Doc.")
+	}
+	return doc, nil
+}
+
+// toMap converts a Doc to a Map. BSON is decoded within the MaxAlloc budget
+// and NestDepth.
+func (this *Decoder) toMap(doc Doc) (Map, error) {
+	bs, ok := doc.(BSON)
+	if !ok || (this.MaxAlloc <= 0 && this.NestDepth <= 0) {
+		return docMap(doc)
+	}
+	v, err := this.decodeRaw(bs, false)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Map), nil
+}
+
+// toSlice converts a Doc to a Slice. BSON is decoded within the MaxAlloc
+// budget and NestDepth.
+func (this *Decoder) toSlice(doc Doc) (Slice, error) {
+	bs, ok := doc.(BSON)
+	if !ok || (this.MaxAlloc <= 0 && this.NestDepth <= 0) {
+		return docSlice(doc)
+	}
+	v, err := this.decodeRaw(bs, true)
+	if err != nil {
+		return nil, err
+	}
+	return v.(Slice), nil
+}
+
+// decodeRaw decodes the BSON to a Map, or Slice if slice, within the MaxAlloc
+// budget and NestDepth. The BSON itself counts against the budget.
+func (this *Decoder) decodeRaw(bs BSON, slice bool) (interface{}, error) {
+	d := rawDecoder{slice: slice, nest: this.NestDepth}
+	if this.MaxAlloc > 0 {
+		if len(bs) > this.MaxAlloc {
+			return nil, errBudget
+		}
+		d.limit, d.budget = true, this.MaxAlloc-len(bs)
+	}
+	return d.doc(bs, "", 0)
+}
+
+// docMap converts a Doc to a Map.
+func docMap(doc Doc) (Map, error) {
+	if m, ok := doc.(Map); ok {
+		return m, nil
+	}
+	bs, err := doc.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return bs.Map()
+}
+
+// docSlice converts a Doc to a Slice.
+func docSlice(doc Doc) (Slice, error) {
+	if s, ok := doc.(Slice); ok {
+		return s, nil
+	}
+	bs, err := doc.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return bs.Slice()
+}
@@ -0,0 +1,133 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecoderHooks(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(Map{"old": String("foo")}.MustEncode())
+	buf.Write(Map{"new": String("bar")}.MustEncode())
+
+	// Raw hook counts bytes, doc hook migrates "old" to "new".
+	var n int
+	dec := NewDecoder(buf)
+	dec.Hooks = []Hook{
+		func(doc Doc) (Doc, error) {
+			n += len(doc.(BSON))
+			return doc, nil
+		},
+		func(doc Doc) (Doc, error) {
+			m, err := doc.(BSON).Map()
+			if err != nil {
+				return nil, err
+			}
+			if v, ok := m["old"]; ok {
+				delete(m, "old")
+				m["new"] = v
+			}
+			return m, nil
+		},
+	}
+	for _, exp := range []Map{{"new": String("foo")}, {"new": String("bar")}} {
+		s, err := dec.DecodeSlice()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := docMap(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, exp) {
+			t.Fatal(m, exp)
+		}
+	}
+	if n != 2*len(Map{"new": String("foo")}.MustEncode()) {
+		t.Fatal(n)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatal(err)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	type nest struct {
+		B Int32 `bson:"b"`
+	}
+	type strict struct {
+		A    Int32  `bson:"a"`
+		Nest []nest `bson:"nest"`
+	}
+	buf := bytes.NewBuffer(nil)
+	buf.Write(Map{"a": Int32(1), "x": Int32(2)}.MustEncode())
+	buf.Write(Map{"a": Int32(1), "x": Int32(2)}.MustEncode())
+	buf.Write(Map{"nest": Array{Map{"b": Int32(1), "y": Int32(2)}}}.MustEncode())
+	buf.Write(Map{"a": Int32(1), "nest": Array{Map{"b": Int32(1)}}}.MustEncode())
+	dec := NewDecoder(buf)
+
+	// Unknown keys are ignored by default.
+	var dst strict
+	if err := dec.DecodeStruct(&dst); err != nil || dst.A != 1 {
+		t.Fatal(err, dst)
+	}
+	dec.DisallowUnknownFields = true
+	if err := dec.DecodeStruct(&dst); err == nil || err.Error() != "x, unknown field." {
+		t.Fatal(err)
+	}
+	if err := dec.DecodeStruct(&dst); err == nil ||
+		err.Error() != "nest.0.y, unknown field." {
+
+		t.Fatal(err)
+	}
+	if err := dec.DecodeStruct(&dst); err != nil || len(dst.Nest) != 1 {
+		t.Fatal(err, dst)
+	}
+}
+
+func TestDecoderMaxAlloc(t *testing.T) {
+	doc := Map{
+		"a": String(strings.Repeat("a", 100)),
+		"b": Map{"c": Binary(make([]byte, 100))},
+	}
+	bs := doc.MustEncode()
+
+	// Budget big enough.
+	dec := NewDecoder(bytes.NewReader(bs))
+	dec.MaxAlloc = 1024
+	m, err := dec.DecodeMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, doc) {
+		t.Fatal(m)
+	}
+
+	// Strings and binaries exceed budget.
+	dec = NewDecoder(bytes.NewReader(bs))
+	dec.MaxAlloc = len(bs) + 150
+	if _, err := dec.DecodeSlice(); err == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// Many small elements exceed budget, the keys and pairs count.
+	bs = Map{"a": make(Array, 100)}.MustEncode()
+	dec = NewDecoder(bytes.NewReader(bs))
+	dec.MaxAlloc = 2 * len(bs)
+	if _, err := dec.DecodeMap(); err == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// Doc exceeds budget.
+	dec = NewDecoder(bytes.NewReader(bs))
+	dec.MaxAlloc = len(bs) - 1
+	if _, err := dec.Decode(); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
@@ -11,17 +11,34 @@ Package bson implements reading and writing bson.
 	Map:    Does not preserve order. Most commonly used document type.
 	Slice:  Preserves order. If order is not required use Map.
 	BSON:   Raw BSON. Used to support preencoded BSON for efficiency.
-	struct: Only struct encoding is supported.
+	struct: Encoded with EncodeStruct, decoded with DecodeStruct.
 
 	Supported struct tags:
 	Field int `bson:"-"`                // Ignored.
 	Field int `bson:"myName"`           // Encoded with key "myName".
 	Field int `bson:"myName,omitempty"` // Key "myName". Ignore if empty value.
 	Field int `bson:",omitempty"`       // Ignore if zero (note the ',').
+	Field T   `bson:",omitempty=deep"`  // Ignore if all nested fields empty.
+	Field T   `bson:",omitzero"`        // Ignore if zero value or IsZero().
+	Field T   `bson:",inline"`          // Fields of struct or map flattened.
+	Field int `bson:",minsize"`         // Int32 if the value fits.
+	Field int `bson:",truncate"`        // Decode Float or Int64 if it fits.
+	Field int `bson:",required"`        // Decode error if missing.
+	Field Map `bson:",remain"`          // Keys without a field, Map or Slice.
+	Field T   `bson:",transform=name"`  // Apply RegisterTransform'd funcs.
 
 	Empty values:
 	Empty value is defined as false, 0, nil, empty slice, empty map, or empty
-	string.
+	string, or a value with a IsZero method which returns true (e.g. time.Time).
+	With omitempty=deep a struct or map is also empty if all of its fields or
+	values are empty.
+
+	Inline:
+	The fields of a inline struct are encoded as fields of the containing
+	document. A inline map gets the keys which don't belong to any other field
+	when decoding. Duplicate keys are a error when encoding. A remain field is
+	the same as a inline Map or Slice, it keeps unknown keys so that they're
+	written back when the struct is encoded.
 
 	Coercion:
 	Coercion is used when exact BSON types are not used. The following coercions
@@ -43,6 +60,10 @@ Package bson implements reading and writing bson.
 	*Binary is encoded with subtype 0x00.
 	*Binary subtypes are ignored while decoding.
 
+	Types which implement Marshaler or ValueMarshaler control their own
+	encoding, and Unmarshaler or ValueUnmarshaler their own decoding. These are
+	checked before coercion.
+
 	Reaching Into Documents:
 	There is significant boiler plate associated with unmarshaling BSON. For this
 	reason a "reach" funcs are prodied to traverse documents and pick out specific
@@ -60,6 +81,10 @@ Package bson implements reading and writing bson.
 	}
 	fmt.Print(dst)
 	// Output: baz
+
+	The path may also be a dotted string, doc.Reach(&dst, "foo.bar"). A key which
+	contains a dot is escaped with a backslash, "foo\\.bar". Array elements are
+	reached by index, "items.3.price".
 */
 package bson
 
@@ -0,0 +1,106 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// DocumentSequence is a identifier followed by documents, the payload of a
+// OP_MSG kind 1 section. For example, the documents of a insert command with
+// the identifier "documents". The encoding is:
+//   int32 size, including itself
+//   cstring identifier
+//   documents, one after the other, to the end of the size
+type DocumentSequence struct {
+	Identifier string
+	Docs       []BSON
+}
+
+// Add encodes the document and appends it.
+func (this *DocumentSequence) Add(doc Doc) error {
+	bs, err := doc.Encode()
+	if err != nil {
+		return err
+	}
+	this.Docs = append(this.Docs, bs)
+	return nil
+}
+
+// Size returns the encoded size of the sequence.
+func (this *DocumentSequence) Size() int {
+	n := 4 + len(this.Identifier) + 1
+	for _, doc := range this.Docs {
+		n += len(doc)
+	}
+	return n
+}
+
+// WriteTo writes the encoded sequence to wr.
+func (this *DocumentSequence) WriteTo(wr io.Writer) (int64, error) {
+	if strings.IndexByte(this.Identifier, 0x00) != -1 {
+		return 0, errors.New("DocumentSequence identifier contains null byte.")
+	}
+	size := this.Size()
+	if size > math.MaxInt32 {
+		return 0, fmt.Errorf("DocumentSequence size %v too large.", size)
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	if err := writeInt32(buf, int32(size)); err != nil {
+		return 0, err
+	}
+	buf.WriteString(this.Identifier)
+	buf.WriteByte(0x00)
+	for _, doc := range this.Docs {
+		buf.Write(doc)
+	}
+	return buf.WriteTo(wr)
+}
+
+// ReadDocumentSequence reads one encoded sequence from rd. Nothing after the
+// size of the sequence is read.
+func ReadDocumentSequence(rd io.Reader) (*DocumentSequence, error) {
+	size, err := readInt32(rd)
+	if err != nil {
+		return nil, err
+	}
+	if size < 5 {
+		return nil, fmt.Errorf("DocumentSequence size %v invalid.", size)
+	}
+	lr := &io.LimitedReader{R: rd, N: int64(size - 4)}
+	var id []byte
+	for {
+		var b [1]byte
+		if _, err := io.ReadFull(lr, b[:]); err != nil {
+			return nil, fmt.Errorf("DocumentSequence identifier %w.",
+				ErrTruncated)
+		}
+		if b[0] == 0x00 {
+			break
+		}
+		id = append(id, b[0])
+	}
+	seq := &DocumentSequence{Identifier: string(id)}
+	for lr.N > 0 {
+		max := int32(maxDocLen)
+		if lr.N < int64(max) {
+			max = int32(lr.N)
+		}
+		doc, err := readOne(lr, nil, max)
+		if err == io.EOF {
+			err = fmt.Errorf("Doc %w.", ErrTruncated)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("DocumentSequence doc %v, %w",
+				len(seq.Docs), err)
+		}
+		seq.Docs = append(seq.Docs, doc)
+	}
+	return seq, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDocumentSequence(t *testing.T) {
+	seq := &DocumentSequence{Identifier: "documents"}
+	for i := 0; i < 3; i++ {
+		if err := seq.Add(Map{"i": Int32(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := bytes.NewBuffer(nil)
+	n, err := seq.WriteTo(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(n) != seq.Size() || buf.Len() != seq.Size() {
+		t.Fatal(n, buf.Len(), seq.Size())
+	}
+
+	// Bytes after the sequence aren't read.
+	buf.WriteString("xyz")
+	seq1, err := ReadDocumentSequence(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(seq, seq1) {
+		t.Fatal(seq, seq1)
+	}
+	if buf.String() != "xyz" {
+		t.Fatal(buf.String())
+	}
+
+	// Empty.
+	buf.Reset()
+	if _, err := (&DocumentSequence{}).WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	seq1, err = ReadDocumentSequence(buf)
+	if err != nil || seq1.Identifier != "" || len(seq1.Docs) != 0 {
+		t.Fatal(err, seq1)
+	}
+
+	// Truncated.
+	buf.Reset()
+	seq.WriteTo(buf)
+	b := buf.Bytes()
+	_, err = ReadDocumentSequence(bytes.NewReader(b[:len(b)-1]))
+	if !errors.Is(err, ErrTruncated) {
+		t.Fatal(err)
+	}
+
+	// Size ends inside a document.
+	b[0] -= 2
+	if _, err := ReadDocumentSequence(bytes.NewReader(b)); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
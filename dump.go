@@ -0,0 +1,130 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Dump returns a annotated hex dump of the BSON, with one line for each part
+// of each element. This is useful when debugging interop problems with other
+// BSON implementations. For example:
+//   0000  10 00 00 00              doc length 16
+//   0004  02                       type String
+//   0005  61 00                    key "a"
+//   0007  04 00 00 00 66 6f 6f 00  String(foo)
+//   000f  00                       end of doc
+// Nested documents are indented. If the BSON is corrupt the rest of it is
+// dumped with the error.
+func Dump(bs BSON) string {
+	d := dumper{bs: bs}
+	if !d.doc(0, 0) {
+		return d.buf.String()
+	}
+	docLen, _ := rawDocLen(bs, 0)
+	if docLen < len(bs) {
+		d.line(docLen, len(bs)-docLen, 0, "trailing bytes")
+	}
+	return d.buf.String()
+}
+
+// dumpWidth is the number of bytes on each line of a dump.
+const dumpWidth = 8
+
+type dumper struct {
+	bs  []byte
+	buf bytes.Buffer
+}
+
+// doc dumps the document at off. Returns false if it was corrupt, in which case
+// the rest of the BSON has been dumped.
+func (this *dumper) doc(off, depth int) bool {
+	docLen, err := rawDocLen(this.bs, off)
+	if err != nil {
+		this.rest(off, depth, err)
+		return false
+	}
+	this.line(off, 4, depth, "doc length %v", docLen)
+	end := off + docLen - 1
+	for pos := off + 4; pos < end; {
+		t := this.bs[pos]
+		this.line(pos, 1, depth, "type %v", Type(t))
+		nul := bytes.IndexByte(this.bs[pos+1:end], 0x00)
+		if nul == -1 {
+			this.rest(pos+1, depth,
+				fmt.Errorf("%w, not terminated.", ErrInvalidKey))
+			return false
+		}
+		this.line(pos+1, nul+1, depth, "key %q", this.bs[pos+1:pos+1+nul])
+		pos += 1 + nul + 1
+		n, err := rawValueLen(t, this.bs[pos:end])
+		if err != nil {
+			this.rest(pos, depth, err)
+			return false
+		}
+		if !this.value(t, pos, n, depth) {
+			return false
+		}
+		pos += n
+	}
+	this.line(end, 1, depth, "end of doc")
+	return true
+}
+
+// value dumps the value of type t and length n at off.
+func (this *dumper) value(t byte, off, n, depth int) bool {
+	switch t {
+	case _EMBEDDED_DOCUMENT, _ARRAY:
+		return this.doc(off, depth+1)
+	case _JAVASCRIPT_SCOPE:
+		this.line(off, 4, depth, "code with scope length %v", n)
+		sLen, err := rawValueLen(_STRING, this.bs[off+4:off+n])
+		if err != nil {
+			this.rest(off+4, depth, err)
+			return false
+		}
+		this.line(off+4, sLen, depth, "Javascript(%s)",
+			this.bs[off+8:off+4+sLen-1])
+		return this.doc(off+4+sLen, depth+1)
+	}
+	d := rawDecoder{}
+	v, err := d.value(t, this.bs[off:off+n], "", off)
+	if err != nil {
+		this.line(off, n, depth, "error: %v", err)
+		return true
+	}
+	this.line(off, n, depth, "%v", print(v))
+	return true
+}
+
+// rest dumps the rest of the BSON from off, which couldn't be parsed because
+// of err.
+func (this *dumper) rest(off, depth int, err error) {
+	this.line(off, len(this.bs)-off, depth, "error: %v", err)
+}
+
+// line dumps n bytes at off with a annotation. Bytes which don't fit on the
+// line continue on following lines.
+func (this *dumper) line(off, n, depth int, format string,
+	a ...interface{}) {
+
+	for i := 0; i == 0 || i < n; i += dumpWidth {
+		j := i + dumpWidth
+		if j > n {
+			j = n
+		}
+		hex := make([]string, 0, dumpWidth)
+		for _, b := range this.bs[off+i : off+j] {
+			hex = append(hex, fmt.Sprintf("%02x", b))
+		}
+		l := fmt.Sprintf("%04x  %-*s", off+i, dumpWidth*3-1,
+			strings.Join(hex, " "))
+		if i == 0 {
+			l += "  " + strings.Repeat("  ", depth) + fmt.Sprintf(format, a...)
+		}
+		this.buf.WriteString(strings.TrimRight(l, " ") + "\n")
+	}
+}
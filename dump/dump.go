@@ -0,0 +1,313 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+Package dump reads and writes the files of mongodump and mongorestore, so that
+migration scripts can work on dumps without the MongoDB tools.
+
+A dump has a directory for each database, with a <collection>.bson file of the
+documents and a <collection>.metadata.json file of the options and indexes of
+each collection. Files compressed with --gzip end in .gz. For example:
+
+	colls, err := dump.List("dump")
+	if err != nil {
+		return err
+	}
+	for _, c := range colls {
+		rd, err := dump.Open(c.BSONPath)
+		if err != nil {
+			return err
+		}
+		for rd.Scan() {
+			m, err := rd.Map()
+			...
+		}
+		if err := rd.Close(); err != nil {
+			return err
+		}
+	}
+*/
+package dump
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sbunce/bson"
+)
+
+// Collection is the files of a collection in a dump.
+type Collection struct {
+	DB           string // Empty if the dump is of one database.
+	Name         string
+	BSONPath     string
+	MetadataPath string // Empty if there's no metadata file.
+}
+
+// List returns the collections in a dump directory, sorted by database and
+// name. The directory may be the output of mongodump, with a directory for
+// each database, or the directory of one database.
+func List(dir string) ([]Collection, error) {
+	var colls []Collection
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry,
+		err error) error {
+
+		if err != nil {
+			return err
+		}
+		name, ok := collectionName(d.Name())
+		if d.IsDir() || !ok {
+			return nil
+		}
+		c := Collection{Name: name, BSONPath: path}
+		rel, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if rel != "." {
+			c.DB = filepath.ToSlash(rel)
+		}
+		base := filepath.Join(filepath.Dir(path), name+".metadata.json")
+		for _, md := range []string{base, base + ".gz"} {
+			if _, err := os.Stat(md); err == nil {
+				c.MetadataPath = md
+				break
+			}
+		}
+		colls = append(colls, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(colls, func(i, j int) bool {
+		if colls[i].DB != colls[j].DB {
+			return colls[i].DB < colls[j].DB
+		}
+		return colls[i].Name < colls[j].Name
+	})
+	return colls, nil
+}
+
+// collectionName returns the collection name of a .bson or .bson.gz file.
+func collectionName(file string) (string, bool) {
+	file = strings.TrimSuffix(file, ".gz")
+	if !strings.HasSuffix(file, ".bson") {
+		return "", false
+	}
+	return strings.TrimSuffix(file, ".bson"), true
+}
+
+// Reader reads the documents of a .bson file. The methods of bson.Scanner are
+// used to read them.
+type Reader struct {
+	*bson.Scanner
+	closers []io.Closer
+}
+
+// Open opens a .bson file. A file ending in .gz is decompressed.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	rd := &Reader{closers: []io.Closer{f}}
+	var src io.Reader = bufio.NewReader(f)
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(src)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		rd.closers = append([]io.Closer{zr}, rd.closers...)
+		src = zr
+	}
+	rd.Scanner = bson.NewScanner(src)
+	return rd, nil
+}
+
+// Close closes the file.
+func (this *Reader) Close() error {
+	var first error
+	for _, c := range this.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Writer writes a .bson file.
+type Writer struct {
+	f  *os.File
+	zw *gzip.Writer
+	bw *bufio.Writer
+}
+
+// Create creates a .bson file, replacing it if it exists. A file ending in .gz
+// is compressed.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	wr := &Writer{f: f}
+	if strings.HasSuffix(path, ".gz") {
+		wr.zw = gzip.NewWriter(f)
+		wr.bw = bufio.NewWriter(wr.zw)
+	} else {
+		wr.bw = bufio.NewWriter(f)
+	}
+	return wr, nil
+}
+
+// Write writes a document.
+func (this *Writer) Write(doc bson.Doc) error {
+	bs, err := doc.Encode()
+	if err != nil {
+		return err
+	}
+	_, err = this.bw.Write(bs)
+	return err
+}
+
+// Close flushes and closes the file. The file is incomplete unless Close
+// returns nil.
+func (this *Writer) Close() error {
+	err := this.bw.Flush()
+	if this.zw != nil {
+		if zerr := this.zw.Close(); err == nil {
+			err = zerr
+		}
+	}
+	if serr := this.f.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := this.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Metadata is a .metadata.json file. Documents are Slices so the order of
+// index keys is kept.
+type Metadata struct {
+	CollectionName string
+	Type           string // "collection", "view" or "timeseries".
+	UUID           string // Hex, without dashes.
+	Options        bson.Slice
+	Indexes        []bson.Slice
+}
+
+// ReadMetadata reads a .metadata.json file. A file ending in .gz is
+// decompressed.
+func ReadMetadata(path string) (*Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var src io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		src = zr
+	}
+	b, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	var s bson.Slice
+	if err := s.UnmarshalJSON(b); err != nil {
+		return nil, fmt.Errorf("%v, %w", path, err)
+	}
+	md := &Metadata{}
+	for _, pair := range s {
+		var ok bool
+		switch pair.Key {
+		case "collectionName":
+			md.CollectionName, ok = str(pair.Val)
+		case "type":
+			md.Type, ok = str(pair.Val)
+		case "uuid":
+			md.UUID, ok = str(pair.Val)
+		case "options":
+			md.Options, ok = pair.Val.(bson.Slice)
+		case "indexes":
+			var a bson.Array
+			if a, ok = pair.Val.(bson.Array); ok {
+				md.Indexes, ok = indexes(a)
+			}
+		default:
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%v, %v invalid.", path, pair.Key)
+		}
+	}
+	return md, nil
+}
+
+func str(v interface{}) (string, bool) {
+	s, ok := v.(bson.String)
+	return string(s), ok
+}
+
+func indexes(a bson.Array) ([]bson.Slice, bool) {
+	idx := make([]bson.Slice, len(a))
+	for i, v := range a {
+		s, ok := v.(bson.Slice)
+		if !ok {
+			return nil, false
+		}
+		idx[i] = s
+	}
+	return idx, true
+}
+
+// WriteMetadata writes a .metadata.json file as Extended JSON, with the keys in
+// the same order as mongodump. Empty fields are left out. A file ending in .gz
+// is compressed.
+func WriteMetadata(path string, md *Metadata) error {
+	s := bson.Slice{{Key: "indexes", Val: bson.Array{}}}
+	for _, idx := range md.Indexes {
+		s[0].Val = append(s[0].Val.(bson.Array), idx)
+	}
+	if md.UUID != "" {
+		s = append(s, bson.Pair{Key: "uuid", Val: bson.String(md.UUID)})
+	}
+	if md.CollectionName != "" {
+		s = append(s, bson.Pair{Key: "collectionName",
+			Val: bson.String(md.CollectionName)})
+	}
+	if md.Type != "" {
+		s = append(s, bson.Pair{Key: "type", Val: bson.String(md.Type)})
+	}
+	options := md.Options
+	if options == nil {
+		options = bson.Slice{}
+	}
+	s = append(s, bson.Pair{Key: "options", Val: options})
+	b, err := s.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	wr, err := Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := wr.bw.Write(b); err != nil {
+		wr.Close()
+		return err
+	}
+	return wr.Close()
+}
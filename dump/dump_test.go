@@ -0,0 +1,110 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package dump
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sbunce/bson"
+)
+
+func TestDump(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"b.bson", "a.bson.gz"} {
+		wr, err := Create(filepath.Join(dir, "db", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			if err := wr.Write(bson.Map{"i": bson.Int32(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := wr.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	md := &Metadata{
+		CollectionName: "b",
+		Type:           "collection",
+		UUID:           "0123456789abcdef0123456789abcdef",
+		Options:        bson.Slice{{Key: "capped", Val: bson.Bool(true)}},
+		Indexes: []bson.Slice{{
+			{Key: "v", Val: bson.Int32(2)},
+			{Key: "key", Val: bson.Slice{
+				{Key: "z", Val: bson.Int32(1)},
+				{Key: "a", Val: bson.Int32(-1)},
+			}},
+			{Key: "name", Val: bson.String("z_1_a_-1")},
+		}},
+	}
+	mdPath := filepath.Join(dir, "db", "b.metadata.json")
+	if err := WriteMetadata(mdPath, md); err != nil {
+		t.Fatal(err)
+	}
+	md1, err := ReadMetadata(mdPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(md, md1) {
+		t.Fatal(md, md1)
+	}
+
+	// Compressed metadata.
+	gzPath := filepath.Join(dir, "db", "a.metadata.json.gz")
+	if err := WriteMetadata(gzPath, md); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(gzPath); err != nil || !bytes.HasPrefix(b,
+		[]byte{0x1f, 0x8b}) {
+
+		t.Fatal("Expected gzip file.", err)
+	}
+	if md1, err = ReadMetadata(gzPath); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(md, md1) {
+		t.Fatal(md, md1)
+	}
+
+	colls, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Collection{
+		{DB: "db", Name: "a", BSONPath: filepath.Join(dir, "db", "a.bson.gz"),
+			MetadataPath: gzPath},
+		{DB: "db", Name: "b", BSONPath: filepath.Join(dir, "db", "b.bson"),
+			MetadataPath: mdPath},
+	}
+	if !reflect.DeepEqual(colls, expect) {
+		t.Fatal(colls)
+	}
+	for _, c := range colls {
+		rd, err := Open(c.BSONPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var i bson.Int32
+		for ; rd.Scan(); i++ {
+			m, err := rd.Map()
+			if err != nil || m["i"] != i {
+				t.Fatal(err, m)
+			}
+		}
+		if rd.Err() != nil || i != 3 {
+			t.Fatal(rd.Err(), i)
+		}
+		if err := rd.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
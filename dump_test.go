@@ -0,0 +1,62 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	bs := Map{"a": String("foo")}.MustEncode()
+	expect := `0000  10 00 00 00              doc length 16
+0004  02                       type String
+0005  61 00                    key "a"
+0007  04 00 00 00 66 6f 6f 00  String(foo)
+000f  00                       end of doc
+`
+	if s := Dump(bs); s != expect {
+		t.Fatalf("got\n%v", s)
+	}
+
+	// Nested and wrapped.
+	bs = Slice{{"a", Slice{{"b", Binary("0123456789")}}}}.MustEncode()
+	s := Dump(bs)
+	if !strings.Contains(s, "0016  33 34 35 36 37 38 39\n") ||
+		!strings.Contains(s, "    doc length 23\n") {
+
+		t.Fatalf("got\n%v", s)
+	}
+
+	// Corrupt.
+	bs = Map{"a": String("foo")}.MustEncode()
+	bs[7] = 0x40
+	s = Dump(bs)
+	if !strings.Contains(s, "error: Element truncated.") ||
+		!strings.HasSuffix(s, "000f  00\n") {
+
+		t.Fatalf("got\n%v", s)
+	}
+}
+
+func TestBSONString(t *testing.T) {
+	bs := Slice{{"a", Int32(1)}, {"b", String("x")}}.MustEncode()
+	if s := fmt.Sprint(bs); s != "Slice[a: Int32(1) b: String(x)]" {
+		t.Fatal(s)
+	}
+
+	// Corrupt falls back to a dump.
+	bs = bs[:len(bs)-1]
+	s := fmt.Sprint(bs)
+	if !strings.HasPrefix(s, "BSON(") || !strings.Contains(s, "error: ") {
+		t.Fatal(s)
+	}
+
+	// Nested is printed on one line.
+	s = Slice{{"c", bs}}.String()
+	if s != fmt.Sprintf("Slice[c: BSON(%x)]", []byte(bs)) {
+		t.Fatal(s)
+	}
+}
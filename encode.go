@@ -6,8 +6,10 @@ package bson
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 	"strconv"
 	"strings"
@@ -16,70 +18,79 @@ import (
 
 // encodeMap encodes a BSON document. The path keeps track of where in the Map
 // we are for error reporting purposes.
-func encodeMap(path string, m Map) ([]byte, error) {
-	buf := bytes.NewBuffer(make([]byte, 0))
-
-	// This will be replaced by the size of the doc later.
-	if err := binary.Write(buf, binary.LittleEndian, uint32(0)); err != nil {
+func (this *Encoder) encodeMap(path string, m Map) ([]byte, error) {
+	buf := getBuffer()
+	this.growBuffer(buf, path, m)
+	if err := this.writeMap(buf, path, m); err != nil {
+		putBuffer(buf)
 		return nil, err
 	}
+	return releaseBuffer(buf), nil
+}
 
-	// Encode.
+// writeMap writes a Map as a BSON document.
+func (this *Encoder) writeMap(buf *bytes.Buffer, path string, m Map) error {
+	start := beginDoc(buf)
 	for name, v := range m {
-		if err := encodeVal(buf, catpath(path, name), name, v); err != nil {
-			return nil, err
+		if err := this.encodeField(buf, catpath(path, name), name, v); err != nil {
+			return err
 		}
 	}
-
-	// End of BSON null byte.
-	if err := buf.WriteByte(0x00); err != nil {
-		return nil, err
-	}
-
-	// Write size of document at start of BSON.
-	binary.LittleEndian.PutUint32(buf.Bytes(), uint32(buf.Len()))
-
-	return buf.Bytes(), nil
+	return endDoc(buf, start)
 }
 
 // encodeSlice encodes a BSON document. The path keeps track of where in the
 // Slice we are for error reporting purposes.
-func encodeSlice(path string, s Slice) ([]byte, error) {
-	buf := bytes.NewBuffer(make([]byte, 0))
-
-	// This will be replaced by the size of the doc later.
-	if err := binary.Write(buf, binary.LittleEndian, uint32(0)); err != nil {
+func (this *Encoder) encodeSlice(path string, s Slice) ([]byte, error) {
+	buf := getBuffer()
+	this.growBuffer(buf, path, s)
+	if err := this.writeSlice(buf, path, s); err != nil {
+		putBuffer(buf)
 		return nil, err
 	}
+	return releaseBuffer(buf), nil
+}
 
-	// Encode.
+// writeSlice writes a Slice as a BSON document.
+func (this *Encoder) writeSlice(buf *bytes.Buffer, path string, s Slice) error {
+	start := beginDoc(buf)
 	for _, pair := range s {
-		if err := encodeVal(buf, catpath(path, pair.Key), pair.Key, pair.Val);
-			err != nil {
+		if err := this.encodeField(buf, catpath(path, pair.Key), pair.Key,
+			pair.Val); err != nil {
 
-			return nil, err
+			return err
 		}
 	}
+	return endDoc(buf, start)
+}
 
-	// End of BSON null byte.
+// beginDoc writes a placeholder for the size of a document, and returns where
+// the document starts.
+func beginDoc(buf *bytes.Buffer) int {
+	var size [4]byte
+	start := buf.Len()
+	buf.Write(size[:])
+	return start
+}
+
+// endDoc writes the null byte at the end of the document which starts at
+// start, and the size of the document at the start.
+func endDoc(buf *bytes.Buffer, start int) error {
 	if err := buf.WriteByte(0x00); err != nil {
-		return nil, err
+		return err
 	}
-
-	// Write size of document at start of BSON.
-	binary.LittleEndian.PutUint32(buf.Bytes(), uint32(buf.Len()))
-
-	return buf.Bytes(), nil
+	binary.LittleEndian.PutUint32(buf.Bytes()[start:], uint32(buf.Len()-start))
+	return nil
 }
 
 // EncodeStruct encodes a struct to BSON.
 func EncodeStruct(src interface{}) (BSON, error) {
-	return encodeStruct("", src)
+	return defaultEncoder.begin().encodeStruct("", src)
 }
 
 // MustEncodeStruct encodes a struct to BSON. Panics upon error.
 func MustEncodeStruct(src interface{}) BSON {
-	b, err := encodeStruct("", src)
+	b, err := defaultEncoder.begin().encodeStruct("", src)
 	if err != nil {
 		panic(err)
 	}
@@ -88,63 +99,235 @@ func MustEncodeStruct(src interface{}) BSON {
 
 // encodeStruct encodes a BSON document. The path keeps track of where in the
 // struct we are for error reporting purposes.
-func encodeStruct(path string, src interface{}) ([]byte, error) {
+func (this *Encoder) encodeStruct(path string, src interface{}) ([]byte, error) {
+	buf := getBuffer()
+	if err := this.writeStruct(buf, path, src); err != nil {
+		putBuffer(buf)
+		return nil, err
+	}
+	return releaseBuffer(buf), nil
+}
+
+// writeStruct writes a struct as a BSON document.
+func (this *Encoder) writeStruct(buf *bytes.Buffer, path string,
+	src interface{}) error {
+
+	if m, ok := src.(Marshaler); ok {
+		b, err := marshalBSON(path, m)
+		if err != nil {
+			return err
+		}
+		_, err = buf.Write(b)
+		return err
+	}
 	rv := indirect(reflect.ValueOf(src))
 	if rv.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("%v, expected struct.", path)
+		return encodeErrorf(path, "expected struct.")
 	}
-	buf := bytes.NewBuffer(make([]byte, 0))
-
-	// This will be replaced by the size of the doc later.
-	if err := binary.Write(buf, binary.LittleEndian, uint32(0)); err != nil {
-		return nil, err
+	start := beginDoc(buf)
+	if err := this.encodeStructFields(buf, path, rv, map[string]bool{}); err != nil {
+		return err
 	}
+	return endDoc(buf, start)
+}
 
-	// Encode.
-	for i := 0; i < rv.NumField(); i++ {
-		sv := rv.Type().Field(i)
-		if sv.PkgPath != "" {
-			// Unexported field.
+// encodeStructFields encodes the fields of a struct. The keys are recorded so
+// that a inline field can't duplicate a key.
+func (this *Encoder) encodeStructFields(buf *bytes.Buffer, path string,
+	rv reflect.Value, keys map[string]bool) error {
+
+	for _, f := range planStruct(rv.Type()).fields {
+		name := f.key
+		field := rv.Field(f.index)
+		fv := indirect(field)
+		if f.inline {
+			if err := this.encodeInline(buf, path, name, field, keys); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.omitEmpty && (!fv.IsValid() || isEmptyValue(fv)) {
+			// Empty field, omitempty true.
+			continue
+		}
+		if f.omitDeep && isDeepEmptyValue(fv) {
+			// Empty field, including nested fields, omitempty=deep.
 			continue
 		}
-		name := sv.Name
-		fv := rv.Field(i)
-		fv = indirect(rv.Field(i))
-		if tag := sv.Tag.Get("bson"); tag != "" {
-			tok := strings.Split(tag, ",")
-			if tok[0] == "-" {
-				// Ignore field.
-				continue
+		if f.omitZero && isZeroValue(field) {
+			// Zero value, or IsZero returns true.
+			continue
+		}
+		if keys[name] {
+			return encodeErrorf(catpath(path, name), "duplicate key.")
+		}
+		keys[name] = true
+		v := field.Interface()
+		if f.minsize {
+			v = minsize(fv, v)
+		}
+		if f.transform != "" {
+			var err error
+			if v, err = applyTransform(catpath(path, name), f.transform, v, false);
+				err != nil {
+
+				return err
 			}
-			if tok[0] != "" {
-				// Renamed field.
-				name = tok[0]
+		}
+		if err := this.encodeField(buf, catpath(path, name), name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// minsize returns Int32 if fv is a int, int64 or Int64 which fits in 32 bits,
+// otherwise v.
+func minsize(fv reflect.Value, v interface{}) interface{} {
+	if fv.Kind() != reflect.Int && fv.Kind() != reflect.Int64 {
+		return v
+	}
+	if _, ok := fv.Interface().(time.Duration); ok {
+		return v
+	}
+	i := fv.Int()
+	if i < math.MinInt32 || i > math.MaxInt32 {
+		return v
+	}
+	return Int32(i)
+}
+
+// encodeInline encodes the fields of a inline struct, or the values of a
+// inline map or Slice, as fields of the containing document. A nil inline field
+// has no fields.
+func (this *Encoder) encodeInline(buf *bytes.Buffer, path, name string,
+	fv reflect.Value, keys map[string]bool) error {
+
+	switch fv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if fv.IsNil() {
+			return nil
+		}
+	}
+	leave, err := this.enter(catpath(path, name), fv)
+	if err != nil {
+		return err
+	}
+	defer leave()
+	fv = indirect(fv)
+	switch fv.Kind() {
+	case reflect.Struct:
+		return this.encodeStructFields(buf, path, fv, keys)
+	case reflect.Slice:
+		s, ok := fv.Interface().(Slice)
+		if !ok {
+			break
+		}
+		for _, pair := range s {
+			if keys[pair.Key] {
+				return encodeErrorf(catpath(path, pair.Key), "duplicate key.")
 			}
-			if len(tok) == 2 && tok[1] == "omitempty" && isEmptyValue(fv) {
-				// Empty field, omitempty true.
-				continue
+			keys[pair.Key] = true
+			if err := this.encodeField(buf, catpath(path, pair.Key), pair.Key,
+				pair.Val);
+				err != nil {
+
+				return err
 			}
 		}
-		if err := encodeVal(buf, catpath(path, name), name, fv.Interface());
-			err != nil {
+		return nil
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		iter := fv.MapRange()
+		for iter.Next() {
+			k := iter.Key().String()
+			if keys[k] {
+				return encodeErrorf(catpath(path, k), "duplicate key.")
+			}
+			keys[k] = true
+			if err := this.encodeField(buf, catpath(path, k), k,
+				iter.Value().Interface());
+				err != nil {
 
-			return nil, err
+				return err
+			}
 		}
+		return nil
 	}
+	return encodeErrorf(catpath(path, name), "cannot inline %v.", fv.Type())
+}
 
-	// End of BSON null byte.
-	if err := buf.WriteByte(0x00); err != nil {
-		return nil, err
+// defaultEncoder is used when encoding without an Encoder.
+var defaultEncoder = &Encoder{}
+
+// begin returns a copy of the Encoder for encoding one document. The copy has
+// its own record of the values being visited, so defaultEncoder can be shared.
+func (this *Encoder) begin() *Encoder {
+	enc := *this
+	enc.visiting = map[visit]bool{}
+	return &enc
+}
+
+// enter records that the value is being visited. Returns error if it already
+// is, which means the value contains itself. The returned func must be called
+// when done with the value.
+func (this *Encoder) enter(path string, rv reflect.Value) (func(), error) {
+	var v visit
+	switch rv.Kind() {
+	case reflect.Map, reflect.Ptr:
+		v = visit{ptr: rv.Pointer(), typ: rv.Type()}
+	case reflect.Slice:
+		if rv.Len() == 0 || rv.Type().Elem().Kind() == reflect.Uint8 {
+			return func() {}, nil
+		}
+		v = visit{ptr: rv.Pointer(), len: rv.Len(), typ: rv.Type()}
+	default:
+		return func() {}, nil
 	}
+	if this.visiting[v] {
+		return nil, encodeErrorf(path, "cycle detected, %v contains itself.",
+			rv.Type())
+	}
+	this.visiting[v] = true
+	return func() { delete(this.visiting, v) }, nil
+}
 
-	// Write size of document at start of BSON.
-	binary.LittleEndian.PutUint32(buf.Bytes(), uint32(buf.Len()))
+// encodeField encodes a document field. If OmitEmptyDocs is set and the field
+// is an empty document or array it's removed. Nested fields are encoded first,
+// so a document which only contained empty documents is also removed.
+func (this *Encoder) encodeField(buf *bytes.Buffer, path, name string,
+	src interface{}) error {
 
-	return buf.Bytes(), nil
+	if this.RejectNULKeys || this.SpecialKeys != KeyPass {
+		var err error
+		if name, err = this.checkKey(path, name); err != nil {
+			return err
+		}
+	}
+	start := buf.Len()
+	if err := this.encodeVal(buf, path, name, src); err != nil {
+		return err
+	}
+	if this.OmitEmptyDocs && isEmptyDocElem(buf.Bytes()[start:], name) {
+		buf.Truncate(start)
+	}
+	return nil
+}
+
+// isEmptyDocElem returns true if the encoded element is an empty document or
+// array.
+func isEmptyDocElem(b []byte, name string) bool {
+	if len(b) != 1+len(name)+1+5 {
+		return false
+	}
+	return (b[0] == _EMBEDDED_DOCUMENT || b[0] == _ARRAY) &&
+		binary.LittleEndian.Uint32(b[len(b)-5:]) == 5
 }
 
 // encodeVal encodes a struct field.
-func encodeVal(buf *bytes.Buffer, path, name string, src interface{}) error {
+func (this *Encoder) encodeVal(buf *bytes.Buffer, path, name string, src interface{}) error {
 	if src == nil {
 		return encodeNull(buf, name)
 	}
@@ -152,23 +335,48 @@ func encodeVal(buf *bytes.Buffer, path, name string, src interface{}) error {
 	if rvsrc.Kind() == reflect.Ptr && rvsrc.IsNil() {
 		return encodeNull(buf, name)
 	}
-	src = indirect(rvsrc).Interface()
+	switch srct := src.(type) {
+	case Marshaler:
+		bs, err := marshalBSON(path, srct)
+		if err != nil {
+			return err
+		}
+		return this.encodeEmbeddedDocument(buf, path, name, bs)
+	case ValueMarshaler:
+		v, err := srct.MarshalBSONValue()
+		if err != nil {
+			return prefixEncodeError(path, err)
+		}
+		return this.encodeVal(buf, path, name, v)
+	}
+	if v, ok, err := registeredEncode(path, src); ok {
+		if err != nil {
+			return err
+		}
+		return this.encodeVal(buf, path, name, v)
+	}
+	leave, err := this.enter(path, rvsrc)
+	if err != nil {
+		return err
+	}
+	defer leave()
+	rvsrc = indirect(rvsrc)
+	src = rvsrc.Interface()
 
 	// Try non-reflect first.
 	switch srct := src.(type) {
 	case Float:
-		return encodeFloat(buf, name, srct)
+		return this.encodeFloatChecked(buf, path, name, srct)
 	case String:
 		return encodeString(buf, name, srct)
 	case Map:
-		return encodeEmbeddedDocument(buf, path, name, srct)
+		return this.encodeEmbeddedDocument(buf, path, name, srct)
 	case Slice:
-		return encodeEmbeddedDocument(buf, path, name, srct)
+		return this.encodeEmbeddedDocument(buf, path, name, srct)
 	case BSON:
-		_, err := buf.Write(srct)
-		return err
+		return this.encodeEmbeddedDocument(buf, path, name, srct)
 	case Array:
-		return encodeArray(buf, path, name, srct)
+		return this.encodeArray(buf, path, name, srct)
 	case Binary:
 		return encodeBinary(buf, name, srct)
 	case Undefined:
@@ -190,7 +398,7 @@ func encodeVal(buf *bytes.Buffer, path, name string, src interface{}) error {
 	case Symbol:
 		return encodeSymbol(buf, name, srct)
 	case JavascriptScope:
-		return encodeJavascriptScope(buf, path, name, srct)
+		return this.encodeJavascriptScope(buf, path, name, srct)
 	case Int32:
 		return encodeInt32(buf, name, srct)
 	case Timestamp:
@@ -214,14 +422,39 @@ func encodeVal(buf *bytes.Buffer, path, name string, src interface{}) error {
 	case int64:
 		return encodeInt64(buf, name, Int64(srct))
 	case float64:
-		return encodeFloat(buf, name, Float(srct))
+		return this.encodeFloatChecked(buf, path, name, Float(srct))
 	case string:
 		return encodeString(buf, name, String(srct))
 	case time.Time:
-		return encodeUTCDateTime(buf, name,
-			UTCDateTime(srct.UnixNano()/1000/1000))
+		return encodeUTCDateTime(buf, name, NewUTCDateTime(srct))
+	case time.Duration:
+		switch this.Duration {
+		case DurationMillis:
+			return encodeInt64(buf, name, Int64(srct.Milliseconds()))
+		case DurationString:
+			return encodeString(buf, name, String(srct.String()))
+		}
+		return encodeInt64(buf, name, Int64(srct))
 	case []byte:
 		return encodeBinary(buf, name, srct)
+	case big.Int:
+		v, err := this.bigInt(&srct)
+		if err != nil {
+			return prefixEncodeError(path, err)
+		}
+		return this.encodeVal(buf, path, name, v)
+	case big.Float:
+		v, err := this.bigFloat(&srct)
+		if err != nil {
+			return prefixEncodeError(path, err)
+		}
+		return this.encodeVal(buf, path, name, v)
+	case json.Number:
+		v, err := this.jsonNumber(srct)
+		if err != nil {
+			return prefixEncodeError(path, err)
+		}
+		return this.encodeVal(buf, path, name, v)
 	default:
 		// Fall back to reflect.
 		switch rvsrc.Kind() {
@@ -232,27 +465,105 @@ func encodeVal(buf *bytes.Buffer, path, name string, src interface{}) error {
 		case reflect.Int, reflect.Int64:
 			return encodeInt64(buf, name, Int64(rvsrc.Int()))
 		case reflect.Float64:
-			return encodeFloat(buf, name, Float(rvsrc.Float()))
+			return this.encodeFloatChecked(buf, path, name, Float(rvsrc.Float()))
+		case reflect.Array:
+			if rvsrc.Type().Elem().Kind() == reflect.Uint8 {
+				b := make(Binary, rvsrc.Len())
+				reflect.Copy(reflect.ValueOf(b), rvsrc)
+				return encodeBinary(buf, name, b)
+			}
+			a := make(Array, rvsrc.Len())
+			for i := 0; i < rvsrc.Len(); i++ {
+				a[i] = rvsrc.Index(i).Interface()
+			}
+			return this.encodeArray(buf, path, name, a)
 		case reflect.Slice:
 			a := make(Array, rvsrc.Len())
 			for i := 0; i < rvsrc.Len(); i++ {
 				a[i] = rvsrc.Index(i).Interface()
 			}
-			return encodeArray(buf, path, name, a)
+			return this.encodeArray(buf, path, name, a)
 		case reflect.String:
 			return encodeString(buf, name, String(rvsrc.String()))
+		case reflect.Map:
+			if rvsrc.Type().Key().Kind() != reflect.String {
+				break
+			}
+			m := make(Map, rvsrc.Len())
+			iter := rvsrc.MapRange()
+			for iter.Next() {
+				m[iter.Key().String()] = iter.Value().Interface()
+			}
+			return this.encodeEmbeddedDocument(buf, path, name, m)
+		case reflect.Struct:
+			if err := buf.WriteByte(_EMBEDDED_DOCUMENT); err != nil {
+				return err
+			}
+			if err := writeCstring(buf, name); err != nil {
+				return err
+			}
+			return this.writeStruct(buf, path, src)
 		}
 	}
-	return fmt.Errorf("%v, cannot encode %T.\n", path, src)
+	return encodeErrorf(path, "cannot encode %T.", src)
 }
 
-// encodeArray encodes a BSON Array.
-func encodeArray(buf *bytes.Buffer, path, name string, val Array) error {
-	// Array is encoded as a document with incrementing numeric keys.
-	if len(val) == 0 {
-		return nil
+// jsonNumber converts a json.Number to Int32 or Int64 if it's a integer which
+// fits, otherwise Float. Always Float if JSONNumberFloat is set.
+func (this *Encoder) jsonNumber(n json.Number) (interface{}, error) {
+	if !this.JSONNumberFloat {
+		return parseJSONNumber(string(n))
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, err
 	}
+	return Float(f), nil
+}
 
+// bigInt converts a big.Int to Int64, or String if BigString is set. Returns
+// error if it doesn't fit in Int64.
+func (this *Encoder) bigInt(i *big.Int) (interface{}, error) {
+	if this.BigString {
+		return String(i.String()), nil
+	}
+	if !i.IsInt64() {
+		return nil, fmt.Errorf("big.Int %v overflows Int64.", i)
+	}
+	return Int64(i.Int64()), nil
+}
+
+// bigFloat converts a big.Float to Float, or String if BigString is set.
+// Precision beyond float64 is lost. Returns error if it doesn't fit in Float.
+func (this *Encoder) bigFloat(f *big.Float) (interface{}, error) {
+	if this.BigString {
+		return String(f.Text('g', -1)), nil
+	}
+	v, _ := f.Float64()
+	if math.IsInf(v, 0) && !f.IsInf() {
+		return nil, fmt.Errorf("big.Float %v overflows Float.", f)
+	}
+	return Float(v), nil
+}
+
+// encodeFloatChecked encodes a Float, applying the NaN policy if it's NaN or
+// infinite.
+func (this *Encoder) encodeFloatChecked(buf *bytes.Buffer, path, name string,
+	val Float) error {
+
+	f := float64(val)
+	if this.NaN == NaNPass || (!math.IsNaN(f) && !math.IsInf(f, 0)) {
+		return encodeFloat(buf, name, val)
+	}
+	if this.NaN == NaNNull {
+		return encodeNull(buf, name)
+	}
+	return encodeErrorf(path, "%v not allowed.", f)
+}
+
+// encodeArray encodes a BSON Array.
+func (this *Encoder) encodeArray(buf *bytes.Buffer, path, name string, val Array) error {
+	// Array is encoded as a document with incrementing numeric keys.
 	// type
 	if err := buf.WriteByte(_ARRAY); err != nil {
 		return err
@@ -263,13 +574,8 @@ func encodeArray(buf *bytes.Buffer, path, name string, val Array) error {
 		return err
 	}
 
-	// Create array doc.
-	tmp := bytes.NewBuffer(make([]byte, 0))
-
-	// This will be replaced by the size of the doc later.
-	if err := binary.Write(tmp, binary.LittleEndian, uint32(0)); err != nil {
-		return err
-	}
+	// Array doc.
+	start := beginDoc(buf)
 	for i := 0; i < len(val); i++ {
 		name := strconv.Itoa(i)
 		var newpath string
@@ -278,21 +584,11 @@ func encodeArray(buf *bytes.Buffer, path, name string, val Array) error {
 		} else {
 			newpath = strings.Join([]string{path, name}, ".")
 		}
-		if err := encodeVal(tmp, newpath, name, val[i]); err != nil {
+		if err := this.encodeVal(buf, newpath, name, val[i]); err != nil {
 			return err
 		}
 	}
-
-	// End of BSON null byte.
-	if err := tmp.WriteByte(0x00); err != nil {
-		return err
-	}
-
-	// Write size of document at start of BSON.
-	binary.LittleEndian.PutUint32(tmp.Bytes(), uint32(tmp.Len()))
-	buf.Write(tmp.Bytes())
-
-	return nil
+	return endDoc(buf, start)
 }
 
 // encodeBinary encodes BSON Binary.
@@ -308,9 +604,7 @@ func encodeBinary(buf *bytes.Buffer, name string, val Binary) error {
 	}
 
 	// value
-	if err := binary.Write(buf, binary.LittleEndian, uint32(len(val)));
-		err != nil {
-
+	if err := writeInt32(buf, int32(len(val))); err != nil {
 		return err
 	}
 
@@ -356,7 +650,7 @@ func encodeDBPointer(buf *bytes.Buffer, path, name string,
 	val DBPointer) error {
 
 	if len(val.ObjectId) != 12 {
-		return fmt.Errorf("%v, DBPointer must be 12 bytes.", path)
+		return encodeErrorf(path, "DBPointer must be 12 bytes.")
 	}
 
 	// type
@@ -383,7 +677,7 @@ func encodeDBPointer(buf *bytes.Buffer, path, name string,
 }
 
 // encodeEmbeddedDocument encodes embedded BSON document.
-func encodeEmbeddedDocument(buf *bytes.Buffer, path, name string,
+func (this *Encoder) encodeEmbeddedDocument(buf *bytes.Buffer, path, name string,
 	val Doc) error {
 
 	// type
@@ -398,19 +692,15 @@ func encodeEmbeddedDocument(buf *bytes.Buffer, path, name string,
 
 	// value
 	if a, ok := val.(Map); ok {
-		b, err := encodeMap(catpath(path, name), a)
-		if err != nil {
-			return err
-		}
-		if _, err := buf.Write(b); err != nil {
+		if err := this.writeMap(buf, path, a); err != nil {
 			return err
 		}
 	} else if a, ok := val.(Slice); ok {
-		b, err := encodeSlice(catpath(path, name), a)
-		if err != nil {
+		if err := this.writeSlice(buf, path, a); err != nil {
 			return err
 		}
-		if _, err := buf.Write(b); err != nil {
+	} else if a, ok := val.(BSON); ok {
+		if _, err := buf.Write(a); err != nil {
 			return err
 		}
 	} else {
@@ -433,21 +723,7 @@ func encodeFloat(buf *bytes.Buffer, name string, val Float) error {
 	}
 
 	// value
-	u := math.Float64bits(float64(val))
-	b := make([]byte, 8)
-	b[7] = byte(u >> 56)
-	b[6] = byte(u >> 48)
-	b[5] = byte(u >> 40)
-	b[4] = byte(u >> 32)
-	b[3] = byte(u >> 24)
-	b[2] = byte(u >> 16)
-	b[1] = byte(u >> 8)
-	b[0] = byte(u)
-	if _, err := buf.Write(b); err != nil {
-		return err
-	}
-
-	return nil
+	return writeInt64(buf, int64(math.Float64bits(float64(val))))
 }
 
 // encodeInt32 encodes BSON Int32.
@@ -463,7 +739,7 @@ func encodeInt32(buf *bytes.Buffer, name string, val Int32) error {
 	}
 
 	// value
-	return binary.Write(buf, binary.LittleEndian, val)
+	return writeInt32(buf, int32(val))
 }
 
 // encodeInt64 encodes BSON Int64.
@@ -477,8 +753,7 @@ func encodeInt64(buf *bytes.Buffer, name string, val Int64) error {
 	}
 
 	// value
-	binary.Write(buf, binary.LittleEndian, val)
-	return nil
+	return writeInt64(buf, int64(val))
 }
 
 // encodeJavascript encodes BSON Javascript.
@@ -498,7 +773,7 @@ func encodeJavascript(buf *bytes.Buffer, name string, val Javascript) error {
 }
 
 // encodeJavascriptScope encodes BSON JavascriptScope.
-func encodeJavascriptScope(buf *bytes.Buffer, path, name string,
+func (this *Encoder) encodeJavascriptScope(buf *bytes.Buffer, path, name string,
 	val JavascriptScope) error {
 
 	// type
@@ -511,31 +786,34 @@ func encodeJavascriptScope(buf *bytes.Buffer, path, name string,
 		return err
 	}
 
-	// Start code_w_s.
-	tmp := bytes.NewBuffer(make([]byte, 0))
-
-	// This will be replaced by the size of code_w_s.
-	if err := binary.Write(tmp, binary.LittleEndian, uint32(0)); err != nil {
-		return err
-	}
+	// Start code_w_s, the size is written at the end.
+	start := beginDoc(buf)
 
 	// Write Javascript.
-	if err := writeString(tmp, val.Javascript); err != nil {
+	if err := writeString(buf, val.Javascript); err != nil {
 		return err
 	}
 
 	// Write scope.
-	b, err := encodeMap(catpath(path, name), val.Scope)
-	if err != nil {
-		return err
+	var err error
+	switch scope := val.Scope.(type) {
+	case nil:
+		err = this.writeMap(buf, path, Map{})
+	case Map:
+		err = this.writeMap(buf, path, scope)
+	case Slice:
+		err = this.writeSlice(buf, path, scope)
+	case BSON:
+		_, err = buf.Write(scope)
+	default:
+		err = encodeErrorf(path, "cannot encode scope %T.", val.Scope)
 	}
-	if _, err := tmp.Write(b); err != nil {
+	if err != nil {
 		return err
 	}
 
-	// Write size of document at start of code_w_s.
-	binary.LittleEndian.PutUint32(tmp.Bytes(), uint32(tmp.Len()))
-	buf.Write(tmp.Bytes())
+	// Write size of code_w_s, which has no null byte at the end.
+	binary.LittleEndian.PutUint32(buf.Bytes()[start:], uint32(buf.Len()-start))
 
 	return nil
 }
@@ -576,7 +854,7 @@ func encodeNull(buf *bytes.Buffer, name string) error {
 // encodeObjectId encodes BSON ObjectId.
 func encodeObjectId(buf *bytes.Buffer, path, name string, val ObjectId) error {
 	if len(val) != 12 {
-		return fmt.Errorf("%v, ObjectId must be 12 bytes.", path)
+		return encodeErrorf(path, "ObjectId must be 12 bytes.")
 	}
 
 	// type
@@ -663,7 +941,7 @@ func encodeTimestamp(buf *bytes.Buffer, name string, val Timestamp) error {
 	}
 
 	// value
-	return binary.Write(buf, binary.LittleEndian, uint64(val))
+	return writeInt64(buf, int64(val))
 }
 
 // encodeUndefined encodes BSON undefined.
@@ -690,12 +968,16 @@ func encodeUTCDateTime(buf *bytes.Buffer, name string, val UTCDateTime) error {
 	}
 
 	// value
-	return binary.Write(buf, binary.LittleEndian, val)
+	return writeInt64(buf, int64(val))
 }
 
-// isEmpty returns true if the value is the empty value.
-// Copied verbatim from the json package in the standard library.
+// isEmptyValue returns true if the value is the empty value, the same as the
+// json package in the standard library, except that a IsZero method is used if
+// the value has one.
 func isEmptyValue(val reflect.Value) bool {
+	if zero, ok := callIsZero(val); ok {
+		return zero
+	}
 	switch val.Kind() {
 	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
 		return val.Len() == 0
@@ -714,6 +996,88 @@ func isEmptyValue(val reflect.Value) bool {
 	return false
 }
 
+// isZeroValue returns true if the value is the zero value of its type, or if
+// it has a IsZero method which returns true.
+func isZeroValue(val reflect.Value) bool {
+	if !val.IsValid() {
+		return true
+	}
+	if zero, ok := callIsZero(val); ok {
+		return zero
+	}
+	return val.IsZero()
+}
+
+// zeroer is implemented by types which know when they're zero, such as
+// time.Time.
+type zeroer interface {
+	IsZero() bool
+}
+
+var zeroerType = reflect.TypeOf((*zeroer)(nil)).Elem()
+
+// callIsZero calls the IsZero method of the value, or of a pointer to the value
+// (or a copy of it if not addressable). Returns false if there's no IsZero
+// method.
+func callIsZero(val reflect.Value) (bool, bool) {
+	if !val.IsValid() || !val.CanInterface() {
+		return false, false
+	}
+	if (val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface) &&
+		val.IsNil() {
+
+		return true, true
+	}
+	if z, ok := val.Interface().(zeroer); ok {
+		return z.IsZero(), true
+	}
+	if !reflect.PointerTo(val.Type()).Implements(zeroerType) {
+		return false, false
+	}
+	if !val.CanAddr() {
+		cp := reflect.New(val.Type()).Elem()
+		cp.Set(val)
+		val = cp
+	}
+	return val.Addr().Interface().(zeroer).IsZero(), true
+}
+
+// isDeepEmptyValue returns true if the value is empty. Unlike isEmptyValue a
+// struct is empty if all of its fields are empty, and a map is empty if all of
+// its values are empty. Pointers and interfaces are followed.
+func isDeepEmptyValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Invalid:
+		// Indirected nil pointer.
+		return true
+	case reflect.Struct:
+		switch val.Type() {
+		case reflect.TypeOf(MinKey{}), reflect.TypeOf(MaxKey{}):
+			// Fieldless but not empty.
+			return false
+		}
+		if zero, ok := callIsZero(val); ok {
+			return zero
+		}
+		for i := 0; i < val.NumField(); i++ {
+			if !isDeepEmptyValue(val.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		for _, k := range val.MapKeys() {
+			if !isDeepEmptyValue(val.MapIndex(k)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Interface, reflect.Ptr:
+		return val.IsNil() || isDeepEmptyValue(val.Elem())
+	}
+	return isEmptyValue(val)
+}
+
 // writeCstring writes BSON cstring. This is not a BSON element.
 func writeCstring(buf *bytes.Buffer, s string) error {
 	if _, err := buf.WriteString(s); err != nil {
@@ -724,7 +1088,7 @@ func writeCstring(buf *bytes.Buffer, s string) error {
 
 // writeString writes a BSON string. This is not a BSON element.
 func writeString(buf *bytes.Buffer, s string) error {
-	if err := binary.Write(buf, binary.LittleEndian, uint32(len(s)+1)); err != nil {
+	if err := writeInt32(buf, int32(len(s)+1)); err != nil {
 		return err
 	}
 	if _, err := buf.WriteString(s); err != nil {
@@ -732,3 +1096,19 @@ func writeString(buf *bytes.Buffer, s string) error {
 	}
 	return buf.WriteByte(0x00)
 }
+
+// writeInt32 writes a little endian int32. This is not a BSON element.
+func writeInt32(buf *bytes.Buffer, i int32) error {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(i))
+	_, err := buf.Write(b[:])
+	return err
+}
+
+// writeInt64 writes a little endian int64. This is not a BSON element.
+func writeInt64(buf *bytes.Buffer, i int64) error {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], uint64(i))
+	_, err := buf.Write(b[:])
+	return err
+}
@@ -0,0 +1,274 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"time"
+)
+
+// Hook is called with each top-level document. The returned document is used
+// in place of the original. Hooks must not modify the document they're passed,
+// they should return a modified copy.
+type Hook func(doc Doc) (Doc, error)
+
+// DurationPolicy is how a Encoder encodes time.Duration.
+type DurationPolicy int
+
+const (
+	DurationNanos  DurationPolicy = iota // Int64 nanoseconds.
+	DurationMillis                       // Int64 milliseconds.
+	DurationString                       // String such as "1h30m0s".
+)
+
+// NaNPolicy is how a Encoder encodes NaN and infinite floats.
+type NaNPolicy int
+
+const (
+	NaNPass  NaNPolicy = iota // Encoded as is, BSON supports them.
+	NaNNull                   // Encoded as Null.
+	NaNError                  // Encoding fails.
+)
+
+// KeyPolicy is how a Encoder encodes keys which start with '$' or contain '.',
+// which MongoDB treats as operators and paths.
+type KeyPolicy int
+
+const (
+	KeyPass   KeyPolicy = iota // Encoded as is.
+	KeyEscape                  // Escaped, '%' is "%25", '$' "%24", '.' "%2E".
+	KeyError                   // Encoding fails.
+)
+
+// Encoder encodes documents and writes them to a stream.
+type Encoder struct {
+	// Hooks are called in order on each document before it's encoded.
+	Hooks []Hook
+
+	// BatchSize is the number of documents to hold before writing them with a
+	// single vectored write (net.Buffers). The documents aren't copied in to one
+	// buffer, so BSON passed to Encode must not be modified until it's written.
+	// If 0 each document is written when it's encoded. Flush must be called to
+	// write a partial batch.
+	BatchSize int
+
+	// OmitEmptyDocs removes fields which are empty embedded documents or empty
+	// arrays. This is recursive, a document which only contains empty documents
+	// is removed too. Elements of arrays are never removed because that would
+	// change the indexes. A empty BSON value is removed, but documents nested
+	// in a BSON value are written as is.
+	OmitEmptyDocs bool
+
+	// JSONNumberFloat encodes every json.Number as Float. By default a
+	// json.Number is Int32 or Int64 if it's a integer which fits, else Float.
+	JSONNumberFloat bool
+
+	// BigString encodes big.Int and big.Float as String so that no precision
+	// is lost. By default big.Int is Int64 and big.Float is Float, a big.Int
+	// which doesn't fit in Int64 is a error.
+	BigString bool
+
+	// Duration is how time.Duration is encoded. The default is DurationNanos.
+	// Int64 and String both decode back to a time.Duration, Int64 as
+	// nanoseconds, so DurationMillis is only for interop with other drivers.
+	Duration DurationPolicy
+
+	// NaN is how NaN and infinite floats are encoded. The default is NaNPass.
+	// Many JSON encoders fail on these values, so NaNNull or NaNError is
+	// useful when documents are later converted to plain JSON.
+	NaN NaNPolicy
+
+	// RejectNULKeys makes keys containing a NUL byte a error. A key is
+	// encoded as a cstring so a NUL ends it early and corrupts the document.
+	RejectNULKeys bool
+
+	// SpecialKeys is how keys which start with '$' or contain '.' are encoded.
+	// The default is KeyPass. With KeyEscape '%' is escaped too, so that the
+	// original key can be recovered. BSON values are written as is.
+	SpecialKeys KeyPolicy
+
+	batch    net.Buffers
+	visiting map[visit]bool
+	w        io.Writer
+}
+
+// visit identifies a map, slice or pointer being encoded. The length is needed
+// because a slice and a sub-slice can share the same pointer, the type because
+// a struct and its first field do.
+type visit struct {
+	ptr uintptr
+	len int
+	typ reflect.Type
+}
+
+// NewEncoder returns a Encoder which writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode encodes a document and writes it to the stream. The src may be a Doc
+// or a struct. Structs are encoded before being passed to the hooks.
+func (this *Encoder) Encode(src interface{}) error {
+	b, err := this.Marshal(src)
+	if err != nil {
+		return err
+	}
+	if this.BatchSize <= 0 {
+		_, err = this.w.Write(b)
+		return err
+	}
+	this.batch = append(this.batch, b)
+	if len(this.batch) < this.BatchSize {
+		return nil
+	}
+	return this.Flush()
+}
+
+// Flush writes the documents held for the current batch. On a connection
+// which supports it (e.g. *net.TCPConn) this is a single writev.
+func (this *Encoder) Flush() error {
+	if len(this.batch) == 0 {
+		return nil
+	}
+	batch := this.batch
+	this.batch = nil
+	_, err := batch.WriteTo(this.w)
+	return err
+}
+
+// Buffers returns the documents held for the current batch instead of writing
+// them, and starts a new batch. This is for writing with something other than
+// the Encoder's writer.
+func (this *Encoder) Buffers() net.Buffers {
+	batch := this.batch
+	this.batch = nil
+	return batch
+}
+
+// Marshal encodes a document and returns it instead of writing it to the
+// stream. The src may be a Doc or a struct.
+func (this *Encoder) Marshal(src interface{}) (BSON, error) {
+	enc := this.begin()
+	doc, ok := src.(Doc)
+	if !ok {
+		b, err := enc.encodeStruct("", src)
+		if err != nil {
+			return nil, err
+		}
+		doc = BSON(b)
+	}
+	for _, hook := range this.Hooks {
+		var err error
+		if doc, err = hook(doc); err != nil {
+			return nil, err
+		}
+	}
+	switch doct := doc.(type) {
+	case Map:
+		return enc.encodeMap("", doct)
+	case Slice:
+		return enc.encodeSlice("", doct)
+	}
+	return doc.Encode()
+}
+
+// AssignObjectId returns a Hook which adds a "_id" with a new ObjectId to
+// documents which don't have one. For ordered documents the "_id" is added
+// first.
+func AssignObjectId() Hook {
+	return func(doc Doc) (Doc, error) {
+		s, ok, err := hookSlice(doc)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range s {
+			if pair.Key == "_id" {
+				return doc, nil
+			}
+		}
+		oid, err := NewObjectId()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			m := copyMap(doc.(Map))
+			m["_id"] = oid
+			return m, nil
+		}
+		return append(Slice{{Key: "_id", Val: oid}}, s...), nil
+	}
+}
+
+// StampTime returns a Hook which sets key to the current time. If overwrite is
+// false then documents which already have the key are left alone. This is
+// useful for "created" (overwrite false) and "updated" (overwrite true)
+// timestamps.
+func StampTime(key string, overwrite bool) Hook {
+	return func(doc Doc) (Doc, error) {
+		s, ok, err := hookSlice(doc)
+		if err != nil {
+			return nil, err
+		}
+		now := NewUTCDateTime(time.Now())
+		if !ok {
+			m := doc.(Map)
+			if _, has := m[key]; has && !overwrite {
+				return doc, nil
+			}
+			m = copyMap(m)
+			m[key] = now
+			return m, nil
+		}
+		for i, pair := range s {
+			if pair.Key != key {
+				continue
+			}
+			if !overwrite {
+				return doc, nil
+			}
+			dst := make(Slice, len(s))
+			copy(dst, s)
+			dst[i].Val = now
+			return dst, nil
+		}
+		dst := make(Slice, len(s), len(s)+1)
+		copy(dst, s)
+		return append(dst, Pair{Key: key, Val: now}), nil
+	}
+}
+
+// hookSlice returns the document as a Slice so that hooks can handle each Doc
+// type the same way. Returns false if the document is a Map, in which case the
+// Slice is only usable for reading.
+func hookSlice(doc Doc) (Slice, bool, error) {
+	switch doct := doc.(type) {
+	case Map:
+		s := make(Slice, 0, len(doct))
+		for k, v := range doct {
+			s = append(s, Pair{Key: k, Val: v})
+		}
+		return s, false, nil
+	case Slice:
+		return doct, true, nil
+	case BSON:
+		s, err := doct.Slice()
+		if err != nil {
+			return nil, false, err
+		}
+		return s, true, nil
+	}
+	return nil, false, fmt.Errorf("unsupported Doc type %T.", doc)
+}
+
+// copyMap returns a shallow copy of the Map.
+func copyMap(m Map) Map {
+	dst := make(Map, len(m)+1)
+	for k, v := range m {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,390 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncoderHooks(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	enc.Hooks = []Hook{AssignObjectId(), StampTime("created", false),
+		StampTime("updated", true)}
+
+	// Map without _id.
+	src := Map{"foo": String("bar")}
+	if err := enc.Encode(src); err != nil {
+		t.Fatal(err)
+	}
+	if len(src) != 1 {
+		t.Fatal("Hook modified source document.", src)
+	}
+
+	// Slice with _id and created.
+	oid := ObjectId{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x01}
+	if err := enc.Encode(Slice{
+		{"created", UTCDateTime(123)},
+		{"_id", oid},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := ReadMap(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["_id"].(ObjectId); !ok {
+		t.Fatal("Expected _id to be assigned.", m)
+	}
+	if _, ok := m["created"].(UTCDateTime); !ok {
+		t.Fatal("Expected created to be stamped.", m)
+	}
+	if _, ok := m["updated"].(UTCDateTime); !ok {
+		t.Fatal("Expected updated to be stamped.", m)
+	}
+
+	s, err := ReadSlice(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 3 || s[0].Val != UTCDateTime(123) ||
+		bytes.Compare(s[1].Val.(ObjectId), oid) != 0 || s[2].Key != "updated" {
+
+		t.Fatal(s)
+	}
+}
+
+func TestEncoderStruct(t *testing.T) {
+	enc := NewEncoder(nil)
+	enc.Hooks = []Hook{AssignObjectId()}
+	bs, err := enc.Marshal(tags{Rename: "foo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s) != 2 || s[0].Key != "_id" || s[1].Val != String("foo") {
+		t.Fatal(s)
+	}
+}
+
+func TestEncoderBatch(t *testing.T) {
+	w := bytes.NewBuffer(nil)
+	enc := NewEncoder(w)
+	enc.BatchSize = 2
+	for i := 0; i < 3; i++ {
+		if err := enc.Encode(Map{"i": Int32(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.Len() != 2*len(Map{"i": Int32(0)}.MustEncode()) {
+		t.Fatal("Expected first batch written.", w.Len())
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		m, err := ReadMap(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["i"] != Int32(i) {
+			t.Fatal(m)
+		}
+	}
+
+	// Buffers takes the batch without writing it.
+	bs := Map{"foo": String("bar")}.MustEncode()
+	if err := enc.Encode(bs); err != nil {
+		t.Fatal(err)
+	}
+	bufs := enc.Buffers()
+	if len(bufs) != 1 || &bufs[0][0] != &bs[0] {
+		t.Fatal("Expected BSON to be batched without copying.", bufs)
+	}
+	if err := enc.Flush(); err != nil || w.Len() != 0 {
+		t.Fatal(err, w.Len())
+	}
+}
+
+func TestEncoderOmitEmptyDocs(t *testing.T) {
+	enc := NewEncoder(nil)
+	enc.OmitEmptyDocs = true
+	bs, err := enc.Marshal(Slice{
+		{"a", Map{}},
+		{"b", Slice{{"c", Array{}}, {"d", Map{"e": Slice{}}}}},
+		{"f", Array{Map{"g": Map{}}}},
+		{"h", Int32(1)},
+		{"i", Map{}.MustEncode()},
+		{"j", Map{"k": Map{}}.MustEncode()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Slice{{"f", Array{Slice{}}}, {"h", Int32(1)},
+		{"j", Slice{{"k", Slice{}}}}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+
+	// Empty docs are kept by default.
+	enc.OmitEmptyDocs = false
+	if bs, err = enc.Marshal(Map{"a": Map{}}); err != nil {
+		t.Fatal(err)
+	}
+	if m, err := bs.Map(); err != nil || !reflect.DeepEqual(m, Map{"a": Map{}}) {
+		t.Fatal(err, m)
+	}
+}
+
+func TestEncoderJSONNumber(t *testing.T) {
+	src := Slice{
+		{"a", json.Number("1")},
+		{"b", json.Number("4294967296")},
+		{"c", json.Number("1.5")},
+		{"d", json.Number("1e3")},
+	}
+	enc := NewEncoder(nil)
+	bs, err := enc.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Slice{{"a", Int32(1)}, {"b", Int64(4294967296)}, {"c", Float(1.5)},
+		{"d", Float(1000)}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+
+	// Force Float.
+	enc.JSONNumberFloat = true
+	if bs, err = enc.Marshal(src[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := bs.Slice(); err != nil || !reflect.DeepEqual(s, Slice{{"a", Float(1)}}) {
+		t.Fatal(err, s)
+	}
+
+	// Invalid number.
+	if _, err := enc.Marshal(Slice{{"a", json.Number("x")}}); err == nil {
+		t.Fatal("Expected error for invalid json.Number.")
+	}
+}
+
+func TestEncoderBig(t *testing.T) {
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	src := Slice{
+		{"a", big.NewInt(-5)},
+		{"b", *big.NewFloat(1.5)},
+	}
+	enc := NewEncoder(nil)
+	bs, err := enc.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Slice{{"a", Int64(-5)}, {"b", Float(1.5)}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+	if _, err := enc.Marshal(Slice{{"a", huge}}); err == nil {
+		t.Fatal("Expected error for big.Int overflow.")
+	}
+
+	// String.
+	enc.BigString = true
+	if bs, err = enc.Marshal(Slice{{"a", huge}, {"b", big.NewFloat(1.5)}}); err != nil {
+		t.Fatal(err)
+	}
+	s, err = bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp = Slice{{"a", String("123456789012345678901234567890")}, {"b", String("1.5")}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+}
+
+func TestEncoderDuration(t *testing.T) {
+	type durations struct {
+		D time.Duration
+	}
+	src := durations{D: 90 * time.Minute}
+	enc := NewEncoder(nil)
+	for _, test := range []struct {
+		policy DurationPolicy
+		exp    interface{}
+	}{
+		{DurationNanos, Int64(90 * time.Minute)},
+		{DurationMillis, Int64(90 * 60 * 1000)},
+		{DurationString, String("1h30m0s")},
+	} {
+		enc.Duration = test.policy
+		bs, err := enc.Marshal(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := bs.Map()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["D"] != test.exp {
+			t.Fatal(test.policy, m["D"], test.exp)
+		}
+		if test.policy == DurationMillis {
+			continue
+		}
+		var dst durations
+		if err := DecodeStruct(bs, &dst); err != nil {
+			t.Fatal(err)
+		}
+		if dst != src {
+			t.Fatal(test.policy, dst, src)
+		}
+	}
+}
+
+func TestEncoderNaN(t *testing.T) {
+	src := Slice{{"a", Float(math.NaN())}, {"b", math.Inf(-1)}, {"c", Float(1)}}
+	enc := NewEncoder(nil)
+	bs, err := enc.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := s[0].Val.(Float); !math.IsNaN(float64(f)) {
+		t.Fatal(f)
+	}
+	if f := s[1].Val.(Float); !math.IsInf(float64(f), -1) {
+		t.Fatal(f)
+	}
+
+	// Null.
+	enc.NaN = NaNNull
+	if bs, err = enc.Marshal(src); err != nil {
+		t.Fatal(err)
+	}
+	s, err = bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Slice{{"a", Null{}}, {"b", Null{}}, {"c", Float(1)}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+
+	// Error.
+	enc.NaN = NaNError
+	if _, err := enc.Marshal(src); err == nil {
+		t.Fatal("Expected error for NaN.")
+	}
+	if _, err := enc.Marshal(src[2:]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncoderKeys(t *testing.T) {
+	src := Slice{
+		{"$set", Slice{{"a.b", Int32(1)}, {"%", Int32(2)}}},
+		{"c", Int32(3)},
+	}
+	enc := NewEncoder(nil)
+	bs, err := enc.Marshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, src) {
+		t.Fatal(s)
+	}
+
+	// Escape.
+	enc.SpecialKeys = KeyEscape
+	if bs, err = enc.Marshal(src); err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{"%24set": Map{"a%2Eb": Int32(1), "%25": Int32(2)}, "c": Int32(3)}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+
+	// Error.
+	enc.SpecialKeys = KeyError
+	if _, err := enc.Marshal(src); err == nil {
+		t.Fatal("Expected error for special key.")
+	}
+	if _, err := enc.Marshal(src[1:]); err != nil {
+		t.Fatal(err)
+	}
+
+	// NUL.
+	nul := Map{"a\x00b": Int32(1)}
+	if _, err := enc.Marshal(nul); err != nil {
+		t.Fatal(err)
+	}
+	enc.RejectNULKeys = true
+	if _, err := enc.Marshal(nul); err == nil {
+		t.Fatal("Expected error for NUL key.")
+	}
+}
+
+func TestEncoderCycle(t *testing.T) {
+	m := Map{"a": Int32(1)}
+	m["b"] = Map{"c": m}
+	if _, err := m.Encode(); err == nil {
+		t.Fatal("Expected error for cyclic Map.")
+	}
+
+	s := Slice{{"a", nil}}
+	s[0].Val = Array{s}
+	if _, err := s.Encode(); err == nil {
+		t.Fatal("Expected error for cyclic Slice.")
+	}
+
+	type node struct {
+		Next *node
+	}
+	n := &node{}
+	n.Next = n
+	if _, err := EncodeStruct(n); err == nil {
+		t.Fatal("Expected error for cyclic struct.")
+	}
+
+	// The same value twice isn't a cycle.
+	shared := Map{"x": Int32(1)}
+	if _, err := (Map{"a": shared, "b": Array{shared, shared}}).Encode(); err != nil {
+		t.Fatal(err)
+	}
+}
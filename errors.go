@@ -0,0 +1,134 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Errors which are wrapped by the errors returned when encoding or decoding,
+// so that the kind of failure can be checked with errors.Is. For example:
+//   if errors.Is(err, bson.ErrTruncated) {
+//       // Wait for more data.
+//   }
+var (
+	ErrDocTooLarge     = errors.New("Doc exceeded maximum size")
+	ErrUnsupportedType = errors.New("Unsupported type")
+	ErrTruncated       = errors.New("truncated")
+	ErrInvalidKey      = errors.New("invalid key")
+	ErrNotFound        = errors.New("not found")
+	ErrCorrupt         = errors.New("corrupt")
+)
+
+// DecodeError is returned when a document can't be decoded. For example:
+//   var de *bson.DecodeError
+//   if errors.As(err, &de) {
+//       log.Printf("bad %v at offset %v: %v", de.Path, de.Offset, de.Err)
+//   }
+type DecodeError struct {
+	// Path of the element, or of the document if the element's key couldn't
+	// be read.
+	Path string
+
+	// Offset of the element's type byte in the BSON being decoded, or -1 if
+	// not known, such as when decoding a Map to a struct. For a nested document
+	// left encoded (e.g. by MapNoNest) and decoded later, the offset is in that
+	// document.
+	Offset int
+
+	// Type of the element, or 0 if not known.
+	Type Type
+
+	Err error
+}
+
+func (this *DecodeError) Error() string {
+	switch {
+	case this.Path != "" && this.Offset >= 0:
+		return fmt.Sprintf("%v (offset %v), %v", this.Path, this.Offset, this.Err)
+	case this.Path != "":
+		return fmt.Sprintf("%v, %v", this.Path, this.Err)
+	case this.Offset >= 0:
+		return fmt.Sprintf("offset %v, %v", this.Offset, this.Err)
+	}
+	return this.Err.Error()
+}
+
+func (this *DecodeError) Unwrap() error {
+	return this.Err
+}
+
+// EncodeError is returned when a value can't be encoded.
+type EncodeError struct {
+	Path string // Path of the value.
+	Err  error
+}
+
+func (this *EncodeError) Error() string {
+	if this.Path == "" {
+		return this.Err.Error()
+	}
+	return fmt.Sprintf("%v, %v", this.Path, this.Err)
+}
+
+func (this *EncodeError) Unwrap() error {
+	return this.Err
+}
+
+// decodeError returns a DecodeError for the element of type t at offset off.
+// The name is the element's key, or "" if it couldn't be read. A err which is
+// already a DecodeError, from a nested document, is returned as is. The end of
+// the stream before the end of the document is ErrTruncated.
+func decodeError(path, name string, off int, t byte, err error) error {
+	if _, ok := err.(*DecodeError); ok {
+		return err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = fmt.Errorf("Doc %w, %w.", ErrTruncated, err)
+	}
+	if name != "" {
+		path = catpath(path, name)
+	}
+	return &DecodeError{Path: path, Offset: off, Type: Type(t), Err: err}
+}
+
+// decodeErrorf returns a DecodeError for a value at a path, where the offset
+// isn't known.
+func decodeErrorf(path, format string, a ...interface{}) error {
+	return &DecodeError{Path: path, Offset: -1, Err: fmt.Errorf(format, a...)}
+}
+
+// prefixDecodeError prefixes the path of a DecodeError with path. Other errors
+// are wrapped in a DecodeError.
+func prefixDecodeError(path string, err error) error {
+	if de, ok := err.(*DecodeError); ok {
+		dst := *de
+		if de.Path != "" {
+			dst.Path = catpath(path, de.Path)
+		} else {
+			dst.Path = path
+		}
+		return &dst
+	}
+	return &DecodeError{Path: path, Offset: -1, Err: err}
+}
+
+// encodeErrorf returns a EncodeError for a value at a path.
+func encodeErrorf(path, format string, a ...interface{}) error {
+	return &EncodeError{Path: path, Err: fmt.Errorf(format, a...)}
+}
+
+// prefixEncodeError prefixes the path of a EncodeError with path. Other errors
+// are wrapped in a EncodeError.
+func prefixEncodeError(path string, err error) error {
+	if ee, ok := err.(*EncodeError); ok {
+		if ee.Path == "" {
+			return &EncodeError{Path: path, Err: ee.Err}
+		}
+		return &EncodeError{Path: catpath(path, ee.Path), Err: ee.Err}
+	}
+	return &EncodeError{Path: path, Err: err}
+}
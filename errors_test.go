@@ -0,0 +1,228 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeError(t *testing.T) {
+	bs := Slice{
+		{"a", Int32(1)},
+		{"b", Slice{{"c", Int32(2)}, {"d", Int32(3)}}},
+	}.MustEncode()
+
+	// Offset of "d" is doc length, "a" element, "b" type and name, nested doc
+	// length, "c" element.
+	off := 4 + 7 + 3 + 4 + 7
+	if bs[off] != _32BIT_INTEGER || bs[off+1] != 'd' {
+		t.Fatal("bad offset")
+	}
+	bad := append(BSON(nil), bs...)
+	bad[off] = 0x13
+	for _, decode := range []func(BSON) error{
+		func(bs BSON) error { _, err := bs.Map(); return err },
+		func(bs BSON) error { _, err := bs.Slice(); return err },
+		func(bs BSON) error { _, err := bs.MapView(); return err },
+		func(bs BSON) error { _, err := NewArena().Slice(bs); return err },
+	} {
+		var de *DecodeError
+		if err := decode(bad); !errors.As(err, &de) {
+			t.Fatal(err)
+		}
+		if de.Path != "b.d" || de.Offset != off || de.Type != 0x13 ||
+			!errors.Is(de, ErrUnsupportedType) {
+
+			t.Fatal(de)
+		}
+	}
+
+	// Truncated.
+	bad = append(BSON(nil), bs...)
+	bad = bad[:off+4]
+	var de *DecodeError
+	if _, err := ReadMap(bytes.NewReader(bad)); !errors.As(err, &de) {
+		t.Fatal(err)
+	}
+	if de.Path != "b.d" || de.Offset != off || de.Type != TypeInt32 ||
+		!errors.Is(de, ErrTruncated) || !errors.Is(de, io.ErrUnexpectedEOF) {
+
+		t.Fatal(de)
+	}
+
+	// Struct.
+	var dst struct {
+		A string
+	}
+	err := DecodeStruct(Map{"A": Int32(1)}.MustEncode(), &dst)
+	if !errors.As(err, &de) {
+		t.Fatal(err)
+	}
+	if de.Path != "A" || de.Offset != -1 {
+		t.Fatal(de)
+	}
+}
+
+func TestEncodeError(t *testing.T) {
+	var ee *EncodeError
+	_, err := Map{"a": Map{"b": ObjectId("short")}}.Encode()
+	if !errors.As(err, &ee) {
+		t.Fatal(err)
+	}
+	if ee.Path != "a.b" || ee.Error() != "a.b, ObjectId must be 12 bytes." {
+		t.Fatal(ee)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	// ErrDocTooLarge.
+	big := []byte{0xFF, 0xFF, 0xFF, 0x7F}
+	if _, err := ReadOne(bytes.NewReader(big)); !errors.Is(err, ErrDocTooLarge) {
+		t.Fatal(err)
+	}
+
+	// ErrTruncated.
+	bs := Map{"a": String("foo")}.MustEncode()
+	if _, err := ReadOne(bytes.NewReader(bs[:len(bs)-1])); !errors.Is(err,
+		ErrTruncated) {
+
+		t.Fatal(err)
+	}
+	if _, err := bs[:len(bs)-1].MapView(); !errors.Is(err, ErrTruncated) {
+		t.Fatal(err)
+	}
+
+	// ErrInvalidKey.
+	enc := NewEncoder(nil)
+	enc.RejectNULKeys = true
+	if _, err := enc.Marshal(Map{"a\x00": Int32(1)}); !errors.Is(err,
+		ErrInvalidKey) {
+
+		t.Fatal(err)
+	}
+
+	// ErrNotFound.
+	if _, err := bs.Splice(Int32(1), "b", "c"); !errors.Is(err, ErrNotFound) {
+		t.Fatal(err)
+	}
+}
+
+func TestDocLengthMismatch(t *testing.T) {
+	bs := Map{"a": Map{"b": Int32(1)}}.MustEncode()
+
+	// Trailing data after the end of the nested document.
+	bad := append(BSON(nil), bs[:len(bs)-2]...)
+	bad = append(bad, 0x00, 0xFF, 0x00, 0x00)
+	binary.LittleEndian.PutUint32(bad, uint32(len(bad)))
+	binary.LittleEndian.PutUint32(bad[7:], uint32(len(bad)-7-1))
+	if _, err := bad.Map(); err == nil {
+		t.Fatal("Expected error.")
+	}
+	if _, err := bad.MapView(); err == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// String not null terminated.
+	bs = Map{"a": String("foo")}.MustEncode()
+	bad = append(BSON(nil), bs...)
+	bad[len(bad)-2] = 'x'
+	if _, err := bad.Map(); err == nil {
+		t.Fatal("Expected error.")
+	}
+	if _, err := bad.MapView(); err == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// JavascriptScope length.
+	bs = Map{"a": JavascriptScope{Javascript: "x", Scope: Map{}}}.MustEncode()
+	bad = append(BSON(nil), bs...)
+	bad[7]++
+	if _, err := bad.Map(); err == nil {
+		t.Fatal("Expected error.")
+	}
+	if _, err := bad.MapView(); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
+
+func TestLengthFields(t *testing.T) {
+	tests := []struct {
+		val interface{}
+		len int32
+	}{
+		{Binary("foo"), -1},
+		{Binary("foo"), math.MaxInt32},
+		{String("foo"), 0},
+		{String("foo"), math.MaxInt32},
+		{Map{"b": Int32(1)}, 2},
+		{Map{"b": Int32(1)}, -5},
+		{Map{"b": Int32(1)}, 100},
+		{JavascriptScope{Javascript: "x", Scope: Map{}}, math.MaxInt32},
+	}
+	for _, test := range tests {
+		bad := Map{"a": test.val}.MustEncode()
+		binary.LittleEndian.PutUint32(bad[7:], uint32(test.len))
+		if _, err := bad.Map(); err == nil {
+			t.Fatalf("Expected error, %#v length %v.", test.val, test.len)
+		}
+		if _, err := bad.MapView(); err == nil {
+			t.Fatalf("Expected error, %#v length %v.", test.val, test.len)
+		}
+	}
+
+	// Document length too small.
+	for _, n := range []int32{-1, 0, 4} {
+		bad := make(BSON, 5)
+		binary.LittleEndian.PutUint32(bad, uint32(n))
+		if _, err := ReadMap(bytes.NewReader(bad)); err == nil {
+			t.Fatalf("Expected error, length %v.", n)
+		}
+	}
+}
+
+func TestSalvage(t *testing.T) {
+	bs := Slice{
+		{"a", Int32(1)},
+		{"b", String("foo")},
+		{"c", Slice{{"d", Int32(2)}, {"e", Int32(3)}}},
+		{"f", Int32(4)},
+	}.MustEncode()
+
+	// Corrupt the type of "b" and "e".
+	bad := append(BSON(nil), bs...)
+	b := bytes.Index(bad, []byte("b\x00")) - 1
+	bad[b] = 0x42
+	e := bytes.Index(bad, []byte("e\x00")) - 1
+	bad[e] = 0x42
+	if _, err := bad.Slice(); err == nil {
+		t.Fatal("Expected error.")
+	}
+	s, errs := bad.SalvageSlice()
+	exp := Slice{{"a", Int32(1)}, {"c", Slice{{"d", Int32(2)}}}, {"f", Int32(4)}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s)
+	}
+	if len(errs) != 2 {
+		t.Fatal(errs)
+	}
+	var de *DecodeError
+	if !errors.As(errs[0], &de) || de.Path != "b" || de.Offset != b {
+		t.Fatal(errs[0])
+	}
+	if !errors.As(errs[1], &de) || de.Path != "c.e" || de.Offset != e {
+		t.Fatal(errs[1])
+	}
+
+	// Truncated.
+	m, errs := bs[:len(bs)-8].SalvageMap()
+	if len(errs) == 0 || m["a"] != Int32(1) || m["b"] != String("foo") {
+		t.Fatal(m, errs)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Flatten returns the values in the Map keyed by dotted path. Array elements
+// are keyed by index (e.g. "a.b.0.c"). Keys are escaped with EscapeKey, so a
+// key which contains a dot doesn't make the path ambiguous. Nested Maps,
+// Slices and Arrays are flattened. Empty documents and Arrays are kept as
+// values so that Unflatten can rebuild them.
+func Flatten(m Map) map[string]interface{} {
+	dst := make(map[string]interface{})
+	flatten(dst, "", m)
+	return dst
+}
+
+// flatten adds the value to dst if it's a leaf, otherwise it recurses.
+func flatten(dst map[string]interface{}, path string, v interface{}) {
+	switch vt := v.(type) {
+	case Map:
+		if len(vt) != 0 {
+			for k, e := range vt {
+				flatten(dst, catpath(path, EscapeKey(k)), e)
+			}
+			return
+		}
+	case Slice:
+		if len(vt) != 0 {
+			for _, pair := range vt {
+				flatten(dst, catpath(path, EscapeKey(pair.Key)),
+					pair.Val)
+			}
+			return
+		}
+	case Array:
+		if len(vt) != 0 {
+			for i, e := range vt {
+				flatten(dst, catpath(path, strconv.Itoa(i)), e)
+			}
+			return
+		}
+	}
+	dst[path] = v
+}
+
+// flatNode is a document being built by Unflatten.
+type flatNode map[string]interface{}
+
+// Unflatten is the inverse of Flatten. Nested documents are created as Maps.
+// A nested document whose keys are exactly "0" to "n-1" becomes an Array.
+// Returns error if a path passes through a value (e.g. "a" and "a.b").
+func Unflatten(flat map[string]interface{}) (Map, error) {
+	root := flatNode{}
+	for path, v := range flat {
+		dot := splitPath([]string{path})
+		cur := root
+		for _, name := range dot[:len(dot)-1] {
+			next, ok := cur[name]
+			if !ok {
+				next = flatNode{}
+				cur[name] = next
+			}
+			node, ok := next.(flatNode)
+			if !ok {
+				return nil, fmt.Errorf("%v, path collision.", path)
+			}
+			cur = node
+		}
+		name := dot[len(dot)-1]
+		if _, ok := cur[name]; ok {
+			return nil, fmt.Errorf("%v, path collision.", path)
+		}
+		cur[name] = v
+	}
+	return root.Map(), nil
+}
+
+// Map converts the node and its children to a Map.
+func (this flatNode) Map() Map {
+	m := make(Map, len(this))
+	for k, v := range this {
+		if node, ok := v.(flatNode); ok {
+			m[k] = node.val()
+		} else {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// val converts the node to an Array if it has index keys, otherwise to a Map.
+func (this flatNode) val() interface{} {
+	for i := 0; i < len(this); i++ {
+		if _, ok := this[strconv.Itoa(i)]; !ok {
+			return this.Map()
+		}
+	}
+	m := this.Map()
+	a := make(Array, len(m))
+	for i := range a {
+		a[i] = m[strconv.Itoa(i)]
+	}
+	return a
+}
@@ -0,0 +1,87 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	src := Map{
+		"a": Map{
+			"b": Array{Map{"c": Int32(1)}, String("foo")},
+			"d": Slice{{"e", Bool(true)}},
+		},
+		"empty": Map{},
+		"none":  Array{},
+	}
+	exp := map[string]interface{}{
+		"a.b.0.c": Int32(1),
+		"a.b.1":   String("foo"),
+		"a.d.e":   Bool(true),
+		"empty":   Map{},
+		"none":    Array{},
+	}
+	flat := Flatten(src)
+	if !reflect.DeepEqual(flat, exp) {
+		t.Fatal(flat, exp)
+	}
+
+	// Slice becomes Map when unflattened.
+	src["a"].(Map)["d"] = Map{"e": Bool(true)}
+	m, err := Unflatten(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, src) {
+		t.Fatal(m, src)
+	}
+}
+
+func TestFlattenEscape(t *testing.T) {
+	src := Map{
+		"example.com": Map{"port": Int32(80)},
+		`a\b`:         Int32(1),
+		"$x":          Int32(2),
+	}
+	exp := map[string]interface{}{
+		`example\.com.port`: Int32(80),
+		`a\\b`:              Int32(1),
+		"$x":                Int32(2),
+	}
+	flat := Flatten(src)
+	if !reflect.DeepEqual(flat, exp) {
+		t.Fatal(flat, exp)
+	}
+	m, err := Unflatten(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, src) {
+		t.Fatal(m, src)
+	}
+	var port int
+	if ok, err := m.Reach(&port, EscapeKey("example.com"), "port"); !ok ||
+		err != nil || port != 80 {
+
+		t.Fatal(ok, err, port)
+	}
+	if err := m.Set(Int32(443), EscapeKey("example.com")+".port"); err != nil {
+		t.Fatal(err)
+	}
+	if m["example.com"].(Map)["port"] != Int32(443) {
+		t.Fatal(m)
+	}
+}
+
+func TestUnflattenCollision(t *testing.T) {
+	_, err := Unflatten(map[string]interface{}{
+		"a":   Int32(1),
+		"a.b": Int32(2),
+	})
+	if err == nil {
+		t.Fatal("Expected path collision error.")
+	}
+}
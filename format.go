@@ -0,0 +1,123 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatOptions are options for Format.
+type FormatOptions struct {
+	// Indent for each level of nesting. Defaults to two spaces.
+	Indent string
+
+	// Sort the keys of Slices. Keys of Maps are always sorted.
+	SortSlices bool
+}
+
+// Format pretty prints a document with one element per line, indented, with
+// BSON types. Keys of Maps are sorted so the output is deterministic, which
+// makes it suitable for diffs and logs. For example:
+//   Map{
+//     a: Int32(1)
+//     b: Array[
+//       String(foo)
+//     ]
+//   }
+// BSON is decoded to a Slice first. If it can't be decoded the error is
+// printed instead.
+func Format(doc Doc, opts FormatOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	f := formatter{opts: opts}
+	f.value(doc, 0)
+	return f.buf.String()
+}
+
+type formatter struct {
+	opts FormatOptions
+	buf  bytes.Buffer
+}
+
+// value prints a value at the nesting depth.
+func (this *formatter) value(v interface{}, depth int) {
+	switch vt := v.(type) {
+	case Map:
+		keys := make([]string, 0, len(vt))
+		for k := range vt {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		this.open("Map{", len(keys) == 0)
+		for _, k := range keys {
+			this.elem(k, vt[k], depth+1)
+		}
+		this.close("}", len(keys) == 0, depth)
+	case Slice:
+		if this.opts.SortSlices {
+			vt = append(Slice(nil), vt...)
+			vt.SortKeys()
+		}
+		this.open("Slice{", len(vt) == 0)
+		for _, pair := range vt {
+			this.elem(pair.Key, pair.Val, depth+1)
+		}
+		this.close("}", len(vt) == 0, depth)
+	case BSON:
+		s, err := vt.Slice()
+		if err != nil {
+			fmt.Fprintf(&this.buf, "BSON(%v)", err)
+			return
+		}
+		this.value(s, depth)
+	case Array:
+		this.open("Array[", len(vt) == 0)
+		for _, v := range vt {
+			this.indent(depth + 1)
+			this.value(v, depth+1)
+			this.buf.WriteString("\n")
+		}
+		this.close("]", len(vt) == 0, depth)
+	case JavascriptScope:
+		fmt.Fprintf(&this.buf, "JavascriptScope(Javascript(%v) Scope(",
+			vt.Javascript)
+		this.value(vt.Scope, depth)
+		this.buf.WriteString("))")
+	default:
+		this.buf.WriteString(print(v))
+	}
+}
+
+// elem prints a element of a document.
+func (this *formatter) elem(key string, v interface{}, depth int) {
+	this.indent(depth)
+	this.buf.WriteString(key)
+	this.buf.WriteString(": ")
+	this.value(v, depth)
+	this.buf.WriteString("\n")
+}
+
+// open starts a document or array.
+func (this *formatter) open(s string, empty bool) {
+	this.buf.WriteString(s)
+	if !empty {
+		this.buf.WriteString("\n")
+	}
+}
+
+// close ends a document or array.
+func (this *formatter) close(s string, empty bool, depth int) {
+	if !empty {
+		this.indent(depth)
+	}
+	this.buf.WriteString(s)
+}
+
+func (this *formatter) indent(depth int) {
+	this.buf.WriteString(strings.Repeat(this.opts.Indent, depth))
+}
@@ -0,0 +1,38 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	doc := Map{
+		"b": Array{String("foo"), Map{}},
+		"a": Int32(1),
+		"c": Slice{{"z", Bool(true)}, {"y", Null{}}},
+	}
+	expect := `Map{
+  a: Int32(1)
+  b: Array[
+    String(foo)
+    Map{}
+  ]
+  c: Slice{
+    z: Bool(true)
+    y: Null()
+  }
+}`
+	if s := Format(doc, FormatOptions{}); s != expect {
+		t.Fatalf("got\n%v", s)
+	}
+
+	// Sorted Slice, from BSON.
+	bs := Slice{{"z", Bool(true)}, {"y", Null{}}}.MustEncode()
+	expect = "Slice{\n\ty: Null()\n\tz: Bool(true)\n}"
+	s := Format(bs, FormatOptions{Indent: "\t", SortSlices: true})
+	if s != expect {
+		t.Fatalf("got\n%v", s)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// A framed stream has a header before each document so that corruption can be
+// detected, and reading can continue from the next frame which isn't corrupt.
+// This is for shipping documents over storage or networks which may damage
+// them. The frame is:
+//   magic       "BSF\x01"
+//   length      uint32, of the document
+//   doc CRC     uint32, CRC32C of the document
+//   header CRC  uint32, CRC32C of the magic, length and doc CRC
+//   document
+// All integers are little endian.
+
+const (
+	frameMagic      = "BSF\x01"
+	frameHeaderSize = 16
+)
+
+// castagnoli is the CRC32C table.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// FrameWriter writes a framed stream.
+type FrameWriter struct {
+	wr io.Writer
+}
+
+// NewFrameWriter returns a FrameWriter which writes to wr.
+func NewFrameWriter(wr io.Writer) *FrameWriter {
+	return &FrameWriter{wr: wr}
+}
+
+// Write writes one document in a frame.
+func (this *FrameWriter) Write(doc Doc) error {
+	bs, err := doc.Encode()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 0, frameHeaderSize+len(bs))
+	b = append(b, frameMagic...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(bs)))
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(bs, castagnoli))
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, castagnoli))
+	b = append(b, bs...)
+	_, err = this.wr.Write(b)
+	return err
+}
+
+// FrameReader reads a framed stream.
+type FrameReader struct {
+	rd     *bufio.Reader
+	src    unreadReader // Source of rd.
+	off    int64        // Offset of the next byte of rd in the stream.
+	resync bool         // Skip to the next valid frame before reading.
+}
+
+// NewFrameReader returns a FrameReader which reads from rd.
+func NewFrameReader(rd io.Reader) *FrameReader {
+	this := &FrameReader{src: unreadReader{rd: rd}}
+	this.rd = bufio.NewReader(&this.src)
+	return this
+}
+
+// unreadReader reads bytes which were unread before reading from rd.
+type unreadReader struct {
+	unread []byte
+	rd     io.Reader
+}
+
+func (this *unreadReader) Read(p []byte) (int, error) {
+	if len(this.unread) > 0 {
+		n := copy(p, this.unread)
+		this.unread = this.unread[n:]
+		return n, nil
+	}
+	return this.rd.Read(p)
+}
+
+// unread puts bytes which were read from the start of a frame back, all but
+// the first, so reading continues from the byte after the start of the frame.
+// A document length which is wrong (e.g. bytes were lost from the document)
+// may have consumed the frames after it.
+func (this *FrameReader) unread(frame []byte) {
+	buffered, _ := this.rd.Peek(this.rd.Buffered())
+	b := make([]byte, 0, len(frame)-1+len(buffered)+len(this.src.unread))
+	b = append(b, frame[1:]...)
+	b = append(b, buffered...)
+	b = append(b, this.src.unread...)
+	this.src.unread = b
+	this.rd.Reset(&this.src)
+	this.off -= int64(len(frame) - 1)
+}
+
+// hasFrame returns true if b contains a valid frame header.
+func hasFrame(b []byte) bool {
+	for {
+		i := bytes.Index(b, []byte(frameMagic))
+		if i == -1 || len(b)-i < frameHeaderSize {
+			return false
+		}
+		if validFrameHeader(b[i : i+frameHeaderSize]) {
+			return true
+		}
+		b = b[i+1:]
+	}
+}
+
+// Read reads the next document. Returns io.EOF at the end of the stream. If a
+// frame is corrupt the error wraps ErrCorrupt, and the next Read continues
+// from the next frame which isn't corrupt. For example:
+//   for {
+//       bs, err := rd.Read()
+//       if errors.Is(err, bson.ErrCorrupt) {
+//           log.Print(err)
+//           continue
+//       } else if err != nil {
+//           return err
+//       }
+//       ...
+//   }
+func (this *FrameReader) Read() (BSON, error) {
+	if this.resync {
+		if err := this.skip(); err != nil {
+			return nil, err
+		}
+		this.resync = false
+	}
+	off := this.off
+	hdr, err := this.rd.Peek(frameHeaderSize)
+	if len(hdr) == 0 && err == io.EOF {
+		return nil, io.EOF
+	}
+	if err == io.EOF {
+		return nil, fmt.Errorf("Frame at offset %v %w.", off, ErrTruncated)
+	} else if err != nil {
+		return nil, err
+	}
+	if !validFrameHeader(hdr) {
+		this.resync = true
+		this.discard(1)
+		return nil, fmt.Errorf("Frame at offset %v header %w.", off,
+			ErrCorrupt)
+	}
+	docLen := binary.LittleEndian.Uint32(hdr[4:])
+	docCRC := binary.LittleEndian.Uint32(hdr[8:])
+	if docLen < 5 || docLen > maxDocLen {
+		this.resync = true
+		this.discard(1)
+		return nil, fmt.Errorf("Frame at offset %v length %v %w.", off,
+			docLen, ErrCorrupt)
+	}
+	frame := make([]byte, frameHeaderSize+docLen)
+	copy(frame, hdr)
+	this.discard(frameHeaderSize)
+	bs := BSON(frame[frameHeaderSize:])
+	n, err := io.ReadFull(this.rd, bs)
+	this.off += int64(n)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// Truncated, unless a frame after this one was consumed.
+		frame = frame[:frameHeaderSize+n]
+		if !hasFrame(frame[1:]) {
+			return nil, fmt.Errorf("Frame at offset %v %w.", off,
+				ErrTruncated)
+		}
+		this.unread(frame)
+		this.resync = true
+		return nil, fmt.Errorf("Frame at offset %v length %v %w.", off,
+			docLen, ErrCorrupt)
+	} else if err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(bs, castagnoli) != docCRC ||
+		binary.LittleEndian.Uint32(bs) != docLen {
+
+		this.unread(frame)
+		this.resync = true
+		return nil, fmt.Errorf("Frame at offset %v document %w.", off,
+			ErrCorrupt)
+	}
+	return bs, nil
+}
+
+// validFrameHeader returns true if the header has the magic and its CRC is
+// correct.
+func validFrameHeader(hdr []byte) bool {
+	return string(hdr[:4]) == frameMagic &&
+		crc32.Checksum(hdr[:12], castagnoli) ==
+			binary.LittleEndian.Uint32(hdr[12:])
+}
+
+// skip discards bytes until the next valid frame header, or the end of the
+// stream.
+func (this *FrameReader) skip() error {
+	for {
+		hdr, err := this.rd.Peek(frameHeaderSize)
+		if err == io.EOF {
+			// Too short for a frame.
+			this.discard(len(hdr))
+			return io.EOF
+		} else if err != nil {
+			return err
+		}
+		if validFrameHeader(hdr) {
+			return nil
+		}
+		this.discard(1)
+	}
+}
+
+func (this *FrameReader) discard(n int) {
+	n, _ = this.rd.Discard(n)
+	this.off += int64(n)
+}
@@ -0,0 +1,109 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestFrame(t *testing.T) {
+	var frames [][]byte
+	for i := 0; i < 5; i++ {
+		buf := bytes.NewBuffer(nil)
+		if err := NewFrameWriter(buf).Write(Map{"i": Int32(i)}); err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, buf.Bytes())
+	}
+	frames[1][frameHeaderSize+8]++ // Document.
+	frames[3][5]++                 // Header length.
+	stream := bytes.Join(frames, []byte("garbage"))
+	stream = append(stream, frames[0][:10]...)
+
+	rd := NewFrameReader(bytes.NewReader(stream))
+	var got []Int32
+	corrupt := 0
+	for {
+		bs, err := rd.Read()
+		if errors.Is(err, ErrCorrupt) {
+			corrupt++
+			continue
+		} else if errors.Is(err, ErrTruncated) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		m, err := bs.Map()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m["i"].(Int32))
+	}
+	if len(got) != 3 || got[0] != 0 || got[1] != 2 || got[2] != 4 {
+		t.Fatal(got)
+	}
+	if corrupt != 3 {
+		t.Fatal(corrupt)
+	}
+
+	// Garbage at the end.
+	frame := frames[0][:len(frames[0]):len(frames[0])]
+	rd = NewFrameReader(bytes.NewReader(append(frame, "junk"...)))
+	if _, err := rd.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rd.Read(); !errors.Is(err, ErrTruncated) {
+		t.Fatal(err)
+	}
+	rd = NewFrameReader(bytes.NewReader(append(frame, "junk junk junk junk"...)))
+	rd.Read()
+	if _, err := rd.Read(); !errors.Is(err, ErrCorrupt) {
+		t.Fatal(err)
+	}
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatal(err)
+	}
+}
+
+func TestFrameDeletedBytes(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	wr := NewFrameWriter(buf)
+	for i := 0; i < 3; i++ {
+		doc := Map{"i": Int32(i), "pad": String("0123456789abcdef")}
+		if err := wr.Write(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	frameLen := buf.Len() / 3
+
+	// Bytes deleted from the document of the first and the second frame.
+	for _, lost := range []int{0, frameLen} {
+		at := lost + frameHeaderSize + 10
+		b := append([]byte(nil), buf.Bytes()...)
+		b = append(b[:at], b[at+10:]...)
+		rd := NewFrameReader(bytes.NewReader(b))
+		var got []Int32
+		for {
+			bs, err := rd.Read()
+			if errors.Is(err, ErrCorrupt) {
+				continue
+			} else if err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			m, err := bs.Map()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, m["i"].(Int32))
+		}
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 docs, got %v.", got)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fuzzSeed makes the generated documents the same on every run.
+const fuzzSeed = 1
+
+// fuzzDocs is the number of random documents to check.
+const fuzzDocs = 500
+
+// fuzzDepth is the max nesting depth of generated documents.
+const fuzzDepth = 3
+
+// TestFuzzDifferential generates random documents and checks that the
+// different ways of encoding and decoding them agree with each other.
+func TestFuzzDifferential(t *testing.T) {
+	rnd := rand.New(rand.NewSource(fuzzSeed))
+	for i := 0; i < fuzzDocs; i++ {
+		s := RandomSlice(rnd, RandomOptions{MaxDepth: fuzzDepth})
+		m := randomMap(s).(Map)
+
+		// Slice round trip.
+		sbs, err := s.Encode()
+		if err != nil {
+			t.Fatal(i, err, s)
+		}
+		s1, err := sbs.Slice()
+		if err != nil {
+			t.Fatal(i, err, s)
+		}
+		if !reflect.DeepEqual(s, s1) {
+			t.Fatal(i, s, s1)
+		}
+
+		// Map round trip.
+		mbs, err := m.Encode()
+		if err != nil {
+			t.Fatal(i, err, m)
+		}
+		m1, err := mbs.Map()
+		if err != nil {
+			t.Fatal(i, err, m)
+		}
+		if !reflect.DeepEqual(m, m1) {
+			t.Fatal(i, m, m1)
+		}
+
+		// Slice encoded, Map decoded.
+		m2, err := sbs.Map()
+		if err != nil {
+			t.Fatal(i, err, s)
+		}
+		if !reflect.DeepEqual(m, m2) {
+			t.Fatal(i, m, m2)
+		}
+
+		// Both encodings are the same size.
+		if len(sbs) != len(mbs) {
+			t.Fatal(i, len(sbs), len(mbs))
+		}
+
+		// Extended JSON round trip.
+		j, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(i, err, s)
+		}
+		var s3 Slice
+		if err := json.Unmarshal(j, &s3); err != nil {
+			t.Fatal(i, err, string(j))
+		}
+		if !reflect.DeepEqual(s, s3) {
+			t.Fatal(i, s, s3)
+		}
+		var m3 Map
+		if err := json.Unmarshal(j, &m3); err != nil {
+			t.Fatal(i, err, string(j))
+		}
+		if !reflect.DeepEqual(m, m3) {
+			t.Fatal(i, m, m3)
+		}
+
+		// Normalize (Native) agrees for the Slice, Map, BSON and Extended
+		// JSON forms of the document.
+		n, err := s.Native()
+		if err != nil {
+			t.Fatal(i, err, s)
+		}
+		for _, doc := range []Doc{m, sbs, mbs, s3, m3} {
+			n1, err := docNative(doc)
+			if err != nil {
+				t.Fatal(i, err, doc)
+			}
+			if !reflect.DeepEqual(n, n1) {
+				t.Fatal(i, n, n1)
+			}
+		}
+
+		// Denormalize is consistent, what it returns normalizes to the same
+		// and round trips through BSON.
+		d, err := fromNative(n)
+		if err != nil {
+			t.Fatal(i, err, n)
+		}
+		dbs, err := d.(Map).Encode()
+		if err != nil {
+			t.Fatal(i, err, d)
+		}
+		n2, err := dbs.Native()
+		if err != nil {
+			t.Fatal(i, err, d)
+		}
+		if !reflect.DeepEqual(n, n2) {
+			t.Fatal(i, n, n2)
+		}
+	}
+}
+
+// docNative normalizes a Doc with Native.
+func docNative(doc Doc) (map[string]interface{}, error) {
+	switch dt := doc.(type) {
+	case Map:
+		return dt.Native()
+	case Slice:
+		return dt.Native()
+	case BSON:
+		return dt.Native()
+	}
+	return nil, fmt.Errorf("%T not supported.", doc)
+}
+
+// fromNative is the inverse of Native, it denormalizes standard Go types to
+// BSON types. Types which Native merges (e.g. Symbol and String) come back as
+// one of them.
+func fromNative(v interface{}) (interface{}, error) {
+	switch vt := v.(type) {
+	case nil:
+		return Null{}, nil
+	case float64:
+		return Float(vt), nil
+	case string:
+		return String(vt), nil
+	case []byte:
+		return Binary(vt), nil
+	case bool:
+		return Bool(vt), nil
+	case time.Time:
+		return NewUTCDateTime(vt), nil
+	case int32:
+		return Int32(vt), nil
+	case int64:
+		return Int64(vt), nil
+	case []interface{}:
+		a := make(Array, len(vt))
+		for i, e := range vt {
+			var err error
+			if a[i], err = fromNative(e); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case map[string]interface{}:
+		return fromNativeMap(vt)
+	}
+	return nil, fmt.Errorf("%T not a native type.", v)
+}
+
+// fromNativeMap denormalizes a map, which is either a document or the
+// Extended JSON form of a type which has no standard Go type.
+func fromNativeMap(m map[string]interface{}) (interface{}, error) {
+	if re, ok := m["$regularExpression"].(map[string]interface{}); ok &&
+		len(m) == 1 {
+
+		return Regexp{Pattern: re["pattern"].(string),
+			Options: re["options"].(string)}, nil
+	}
+	if ptr, ok := m["$dbPointer"].(map[string]interface{}); ok && len(m) == 1 {
+		oid, err := ObjectIdFromHex(ptr["$id"].(string))
+		if err != nil {
+			return nil, err
+		}
+		return DBPointer{Name: ptr["$ref"].(string), ObjectId: oid}, nil
+	}
+	if code, ok := m["$code"].(string); ok && len(m) == 2 {
+		scope, err := fromNative(m["$scope"])
+		if err != nil {
+			return nil, err
+		}
+		return JavascriptScope{Javascript: code, Scope: scope.(Map)}, nil
+	}
+	if ts, ok := m["$timestamp"].(map[string]interface{}); ok && len(m) == 1 {
+		return NewTimestamp(ts["t"].(uint32), ts["i"].(uint32)), nil
+	}
+	if _, ok := m["$minKey"]; ok && len(m) == 1 {
+		return MinKey{}, nil
+	}
+	if _, ok := m["$maxKey"]; ok && len(m) == 1 {
+		return MaxKey{}, nil
+	}
+	dst := make(Map, len(m))
+	for k, e := range m {
+		v, err := fromNative(e)
+		if err != nil {
+			return nil, err
+		}
+		dst[k] = v
+	}
+	return dst, nil
+}
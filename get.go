@@ -0,0 +1,92 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"time"
+)
+
+// The Get funcs return the value of a key in the Map. Values are coerced the
+// same as with Reach. Returns false if the key is not present or the value
+// cannot be coerced. To tell these apart, or to reach in to nested documents,
+// use Reach.
+
+// GetArray returns the Array value of the key.
+func (this Map) GetArray(key string) (Array, bool) {
+	var dst Array
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetBinary returns the Binary value of the key as []byte.
+func (this Map) GetBinary(key string) ([]byte, bool) {
+	var dst []byte
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetBool returns the Bool value of the key.
+func (this Map) GetBool(key string) (bool, bool) {
+	var dst bool
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetDoc returns the embedded document value of the key as a Map. A Slice or
+// BSON value is converted to a Map.
+func (this Map) GetDoc(key string) (Map, bool) {
+	v, ok := this[key]
+	if !ok {
+		return nil, false
+	}
+	switch vt := v.(type) {
+	case Map:
+		return vt, true
+	case Slice, BSON:
+		m, err := docMap(vt.(Doc))
+		return m, err == nil
+	}
+	return nil, false
+}
+
+// GetFloat returns the Float value of the key.
+func (this Map) GetFloat(key string) (float64, bool) {
+	var dst float64
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetInt32 returns the Int32 value of the key.
+func (this Map) GetInt32(key string) (int32, bool) {
+	var dst int32
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetInt64 returns the Int64, Int32, UTCDateTime or Timestamp value of the key.
+func (this Map) GetInt64(key string) (int64, bool) {
+	var dst int64
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetObjectId returns the ObjectId value of the key.
+func (this Map) GetObjectId(key string) (ObjectId, bool) {
+	dst, ok := this[key].(ObjectId)
+	return dst, ok
+}
+
+// GetString returns the String, Javascript or Symbol value of the key.
+func (this Map) GetString(key string) (string, bool) {
+	var dst string
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
+
+// GetTime returns the UTCDateTime or Timestamp value of the key.
+func (this Map) GetTime(key string) (time.Time, bool) {
+	var dst time.Time
+	ok, err := this.Reach(&dst, key)
+	return dst, ok && err == nil
+}
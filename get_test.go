@@ -0,0 +1,55 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapGet(t *testing.T) {
+	m := Map{
+		"string": String("foo"),
+		"int32":  Int32(1),
+		"int64":  Int64(2),
+		"bool":   Bool(true),
+		"float":  Float(1.5),
+		"doc":    Slice{{"a", Int32(3)}},
+		"raw":    Map{"b": Int32(4)}.MustEncode(),
+		"array":  Array{Int32(5)},
+	}
+	if v, ok := m.GetString("string"); !ok || v != "foo" {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetString("int32"); ok {
+		t.Fatal("Expected coercion failure.", v)
+	}
+	if v, ok := m.GetString("missing"); ok {
+		t.Fatal("Expected missing.", v)
+	}
+	if v, ok := m.GetInt64("int32"); !ok || v != 1 {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetInt64("int64"); !ok || v != 2 {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetInt32("int64"); ok {
+		t.Fatal("Expected coercion failure.", v)
+	}
+	if v, ok := m.GetBool("bool"); !ok || !v {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetFloat("float"); !ok || v != 1.5 {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetDoc("doc"); !ok || !reflect.DeepEqual(v, Map{"a": Int32(3)}) {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetDoc("raw"); !ok || !reflect.DeepEqual(v, Map{"b": Int32(4)}) {
+		t.Fatal(v, ok)
+	}
+	if v, ok := m.GetArray("array"); !ok || !reflect.DeepEqual(v, Array{Int32(5)}) {
+		t.Fatal(v, ok)
+	}
+}
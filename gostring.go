@@ -0,0 +1,187 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// The GoString methods print values as Go literals, so that %#v output can be
+// pasted in to tests. For example:
+//   fmt.Printf("%#v", Map{"a": Int64(1)}) // Map{"a": Int64(1)}
+// Map keys are sorted. Literals aren't qualified with the package name.
+
+// goString returns the Go literal for a value in a document.
+func goString(v interface{}) string {
+	if v == nil {
+		return "nil"
+	}
+	if gs, ok := v.(fmt.GoStringer); ok {
+		return gs.GoString()
+	}
+	return fmt.Sprintf("%#v", v)
+}
+
+// GoString returns the Map as a Go literal with the keys sorted.
+func (this Map) GoString() string {
+	keys := make([]string, 0, len(this))
+	for k := range this {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	wr := bytes.NewBuffer(nil)
+	wr.WriteString("Map{")
+	for i, k := range keys {
+		if i != 0 {
+			wr.WriteString(", ")
+		}
+		fmt.Fprintf(wr, "%q: %v", k, goString(this[k]))
+	}
+	wr.WriteString("}")
+	return wr.String()
+}
+
+// GoString returns the Slice as a Go literal.
+func (this Slice) GoString() string {
+	wr := bytes.NewBuffer(nil)
+	wr.WriteString("Slice{")
+	for i, pair := range this {
+		if i != 0 {
+			wr.WriteString(", ")
+		}
+		fmt.Fprintf(wr, "{%q, %v}", pair.Key, goString(pair.Val))
+	}
+	wr.WriteString("}")
+	return wr.String()
+}
+
+// GoString returns the Pair as a Go literal.
+func (this Pair) GoString() string {
+	return fmt.Sprintf("Pair{%q, %v}", this.Key, goString(this.Val))
+}
+
+// GoString returns the BSON as a Go literal.
+func (this BSON) GoString() string {
+	return "BSON(" + strconv.Quote(string(this)) + ")"
+}
+
+// GoString returns the Array as a Go literal.
+func (this Array) GoString() string {
+	wr := bytes.NewBuffer(nil)
+	wr.WriteString("Array{")
+	for i, v := range this {
+		if i != 0 {
+			wr.WriteString(", ")
+		}
+		wr.WriteString(goString(v))
+	}
+	wr.WriteString("}")
+	return wr.String()
+}
+
+// GoString returns the Float as a Go literal, NaN and Inf use math.
+func (this Float) GoString() string {
+	f := float64(this)
+	switch {
+	case math.IsNaN(f):
+		return "Float(math.NaN())"
+	case math.IsInf(f, 1):
+		return "Float(math.Inf(1))"
+	case math.IsInf(f, -1):
+		return "Float(math.Inf(-1))"
+	}
+	return "Float(" + strconv.FormatFloat(f, 'g', -1, 64) + ")"
+}
+
+// GoString returns the String as a Go literal.
+func (this String) GoString() string {
+	return "String(" + strconv.Quote(string(this)) + ")"
+}
+
+// GoString returns the Binary as a Go literal.
+func (this Binary) GoString() string {
+	return "Binary(" + strconv.Quote(string(this)) + ")"
+}
+
+// GoString returns the Undefined as a Go literal.
+func (this Undefined) GoString() string {
+	return "Undefined{}"
+}
+
+// GoString returns the ObjectId as a Go literal.
+func (this ObjectId) GoString() string {
+	return "ObjectId(" + strconv.Quote(string(this)) + ")"
+}
+
+// GoString returns the Bool as a Go literal.
+func (this Bool) GoString() string {
+	return fmt.Sprintf("Bool(%v)", bool(this))
+}
+
+// GoString returns the UTCDateTime as a Go literal.
+func (this UTCDateTime) GoString() string {
+	return fmt.Sprintf("UTCDateTime(%v)", int64(this))
+}
+
+// GoString returns the Null as a Go literal.
+func (this Null) GoString() string {
+	return "Null{}"
+}
+
+// GoString returns the Regexp as a Go literal.
+func (this Regexp) GoString() string {
+	return fmt.Sprintf("Regexp{Pattern: %q, Options: %q}", this.Pattern,
+		this.Options)
+}
+
+// GoString returns the DBPointer as a Go literal.
+func (this DBPointer) GoString() string {
+	return fmt.Sprintf("DBPointer{Name: %q, ObjectId: %v}", this.Name,
+		this.ObjectId.GoString())
+}
+
+// GoString returns the Javascript as a Go literal.
+func (this Javascript) GoString() string {
+	return "Javascript(" + strconv.Quote(string(this)) + ")"
+}
+
+// GoString returns the Symbol as a Go literal.
+func (this Symbol) GoString() string {
+	return "Symbol(" + strconv.Quote(string(this)) + ")"
+}
+
+// GoString returns the JavascriptScope as a Go literal.
+func (this JavascriptScope) GoString() string {
+	return fmt.Sprintf("JavascriptScope{Javascript: %q, Scope: %v}",
+		this.Javascript, goString(this.Scope))
+}
+
+// GoString returns the Int32 as a Go literal.
+func (this Int32) GoString() string {
+	return fmt.Sprintf("Int32(%v)", int32(this))
+}
+
+// GoString returns the Timestamp as a Go literal.
+func (this Timestamp) GoString() string {
+	return fmt.Sprintf("Timestamp(%v)", int64(this))
+}
+
+// GoString returns the Int64 as a Go literal.
+func (this Int64) GoString() string {
+	return fmt.Sprintf("Int64(%v)", int64(this))
+}
+
+// GoString returns the MinKey as a Go literal.
+func (this MinKey) GoString() string {
+	return "MinKey{}"
+}
+
+// GoString returns the MaxKey as a Go literal.
+func (this MaxKey) GoString() string {
+	return "MaxKey{}"
+}
@@ -0,0 +1,48 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"go/parser"
+	"math"
+	"testing"
+)
+
+func TestGoString(t *testing.T) {
+	tests := []struct {
+		val    interface{}
+		expect string
+	}{
+		{Map{"b": Int64(1), "a": nil}, `Map{"a": nil, "b": Int64(1)}`},
+		{Slice{{"a", Array{String("x"), Bool(true)}}},
+			`Slice{{"a", Array{String("x"), Bool(true)}}}`},
+		{Pair{"a", Null{}}, `Pair{"a", Null{}}`},
+		{Float(1.5), `Float(1.5)`},
+		{Float(math.Inf(-1)), `Float(math.Inf(-1))`},
+		{Binary{0x00, 'a'}, `Binary("\x00a")`},
+		{Regexp{"^a", "i"}, `Regexp{Pattern: "^a", Options: "i"}`},
+		{JavascriptScope{Javascript: "x", Scope: Slice{}},
+			`JavascriptScope{Javascript: "x", Scope: Slice{}}`},
+		{Map{"a": []int{1}}, `Map{"a": []int{1}}`},
+	}
+	for _, test := range tests {
+		s := fmt.Sprintf("%#v", test.val)
+		if s != test.expect {
+			t.Fatalf("Expected %v, got %v.", test.expect, s)
+		}
+	}
+
+	// Every type is a valid expression.
+	doc := Map{
+		"a": Undefined{}, "b": ObjectId("abcdefghijkl"), "c": UTCDateTime(1),
+		"d": DBPointer{Name: "x", ObjectId: ObjectId("mnopqrstuvwx")},
+		"e": Javascript("x"), "f": Symbol("y"), "g": Int32(1),
+		"h": Timestamp(2), "i": MinKey{}, "j": MaxKey{},
+		"k": Map{"x": Float(math.NaN())}.MustEncode(),
+	}
+	if _, err := parser.ParseExpr(doc.GoString()); err != nil {
+		t.Fatal(doc.GoString(), err)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// BuildIndex reads documents, one after the other, from rd and returns the
+// offset of each. Documents are skipped rather than decoded. The offsets can
+// be kept and given to NewIndexedReader for random access.
+func BuildIndex(rd io.Reader) ([]int64, error) {
+	br := bufio.NewReader(rd)
+	var offsets []int64
+	var off int64
+	for {
+		var b [4]byte
+		n, err := io.ReadFull(br, b[:])
+		if n == 0 && err == io.EOF {
+			return offsets, nil
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("Doc at offset %v %w.", off, ErrTruncated)
+		} else if err != nil {
+			return nil, err
+		}
+		docLen := int32(binary.LittleEndian.Uint32(b[:]))
+		if docLen < 5 {
+			return nil, fmt.Errorf("Doc at offset %v length %v invalid.", off,
+				docLen)
+		}
+		if docLen > maxDocLen {
+			return nil, fmt.Errorf("Doc at offset %v, %w.", off, ErrDocTooLarge)
+		}
+		if _, err := br.Discard(int(docLen) - 4); err == io.EOF {
+			return nil, fmt.Errorf("Doc at offset %v %w.", off, ErrTruncated)
+		} else if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, off)
+		off += int64(docLen)
+	}
+}
+
+// IndexedReader reads the Nth document of a file, using the offsets from
+// BuildIndex. For example, to binary search a file sorted by "ts":
+//   offsets, err := bson.BuildIndex(f)
+//   ...
+//   ir := bson.NewIndexedReader(f, offsets)
+//   i := sort.Search(ir.Len(), func(i int) bool {
+//       var ts bson.UTCDateTime
+//       bs, err := ir.Doc(i)
+//       ...
+//       bs.Reach(&ts, "ts")
+//       return ts >= want
+//   })
+// It's safe for concurrent use if the io.ReaderAt is, as *os.File is.
+type IndexedReader struct {
+	ra      io.ReaderAt
+	offsets []int64
+}
+
+// NewIndexedReader returns a IndexedReader which reads from ra.
+func NewIndexedReader(ra io.ReaderAt, offsets []int64) *IndexedReader {
+	return &IndexedReader{ra: ra, offsets: offsets}
+}
+
+// Len returns the number of documents.
+func (this *IndexedReader) Len() int {
+	return len(this.offsets)
+}
+
+// Doc reads document n.
+func (this *IndexedReader) Doc(n int) (BSON, error) {
+	if n < 0 || n >= len(this.offsets) {
+		return nil, fmt.Errorf("Doc %v %w.", n, ErrNotFound)
+	}
+	bs, err := ReadAt(this.ra, this.offsets[n])
+	if err != nil {
+		return nil, fmt.Errorf("Doc %v, %w", n, err)
+	}
+	return bs, nil
+}
+
+// ReadAt reads the document at offset off.
+func ReadAt(ra io.ReaderAt, off int64) (BSON, error) {
+	bs, err := readOne(io.NewSectionReader(ra, off, maxDocLen), nil,
+		maxDocLen)
+	if err == io.EOF {
+		err = fmt.Errorf("Doc at offset %v %w.", off, ErrNotFound)
+	}
+	return bs, err
+}
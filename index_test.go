@@ -0,0 +1,76 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestIndexedReader(t *testing.T) {
+	var b []byte
+	for i := 0; i < 100; i++ {
+		doc := Slice{{"i", Int32(i * 2)}, {"pad", String(make([]byte, i))}}
+		b = append(b, doc.MustEncode()...)
+	}
+	offsets, err := BuildIndex(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ir := NewIndexedReader(bytes.NewReader(b), offsets)
+	if ir.Len() != 100 {
+		t.Fatalf("Expected 100 docs, got %v.", ir.Len())
+	}
+	for _, want := range []Int32{0, 42, 198} {
+		var got Int32
+		i := sort.Search(ir.Len(), func(i int) bool {
+			bs, err := ir.Doc(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs.MustReach(&got, "i")
+			return got >= want
+		})
+		bs, err := ir.Doc(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bs.MustReach(&got, "i")
+		if got != want {
+			t.Fatalf("Expected %v, got %v.", want, got)
+		}
+	}
+	if _, err := ir.Doc(100); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected not found, got %v.", err)
+	}
+	if _, err := ReadAt(bytes.NewReader(b), int64(len(b))); !errors.Is(err,
+		ErrNotFound) {
+
+		t.Fatalf("Expected not found, got %v.", err)
+	}
+}
+
+func TestBuildIndexErrors(t *testing.T) {
+	bs := Map{"a": Int32(1)}.MustEncode()
+	for _, b := range [][]byte{
+		bs[:2],
+		bs[:len(bs)-1],
+		append(append([]byte(nil), bs...), bs[:6]...),
+	} {
+		if _, err := BuildIndex(bytes.NewReader(b)); !errors.Is(err,
+			ErrTruncated) {
+
+			t.Fatalf("Expected truncated, got %v, %x.", err, b)
+		}
+	}
+	if _, err := BuildIndex(bytes.NewReader([]byte{1, 0, 0, 0})); err == nil {
+		t.Fatal("Expected invalid length error.")
+	}
+	offsets, err := BuildIndex(bytes.NewReader(nil))
+	if err != nil || len(offsets) != 0 {
+		t.Fatalf("Expected no offsets, got %v, %v.", offsets, err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"io"
+	"sort"
+)
+
+// Inferrer summarizes the paths in a stream of documents, to find out what's in
+// a dump before writing code which decodes it.
+type Inferrer struct {
+	docs  int
+	paths map[string]*pathStats
+}
+
+// PathSummary is what was seen at a path. Paths are dotted, the same as
+// Flatten, except that the elements of a Array are all at the path of the Array
+// with "[]" appended (e.g. "items[].price").
+type PathSummary struct {
+	Path string
+
+	// Types is the number of values of each type.
+	Types map[Type]int
+
+	// Count is the number of documents which have the path, Ratio is Count
+	// divided by the number of documents.
+	Count int
+	Ratio float64
+
+	// MinLen and MaxLen are the shortest and longest String, Binary, Array or
+	// embedded document (number of keys). Both are -1 if there were none.
+	MinLen int
+	MaxLen int
+}
+
+// pathStats is a PathSummary being built.
+type pathStats struct {
+	PathSummary
+	parent  string // Path of the document or Array the value is in.
+	key     string // Key in the parent, "[]" for a Array element.
+	lastDoc int    // Last document counted.
+}
+
+// NewInferrer returns a empty Inferrer.
+func NewInferrer() *Inferrer {
+	return &Inferrer{paths: map[string]*pathStats{}}
+}
+
+// Infer reads documents until EOF and returns the summary.
+func Infer(dec *Decoder) ([]PathSummary, error) {
+	inf := NewInferrer()
+	for {
+		doc, err := dec.Decode()
+		if err == io.EOF {
+			return inf.Summary(), nil
+		} else if err != nil {
+			return nil, err
+		}
+		if err := inf.Add(doc); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Add adds a document to the summary.
+func (this *Inferrer) Add(doc Doc) error {
+	this.docs++
+	return this.walk("", "", "", doc)
+}
+
+// Docs returns the number of documents added.
+func (this *Inferrer) Docs() int {
+	return this.docs
+}
+
+// Summary returns the paths sorted.
+func (this *Inferrer) Summary() []PathSummary {
+	summary := make([]PathSummary, 0, len(this.paths))
+	for _, st := range this.paths {
+		ps := st.PathSummary
+		ps.Types = make(map[Type]int, len(st.Types))
+		for t, n := range st.Types {
+			ps.Types[t] = n
+		}
+		ps.Ratio = float64(ps.Count) / float64(this.docs)
+		summary = append(summary, ps)
+	}
+	sort.Slice(summary, func(i, j int) bool {
+		return summary[i].Path < summary[j].Path
+	})
+	return summary
+}
+
+// walk adds the value at the path, then the values nested in it.
+func (this *Inferrer) walk(path, parent, key string, v interface{}) error {
+	if bs, ok := v.(BSON); ok {
+		s, err := bs.SliceNoNest()
+		if err != nil {
+			return err
+		}
+		v = s
+	}
+	if path != "" {
+		this.see(path, parent, key, v)
+	}
+	switch vt := v.(type) {
+	case Map:
+		for k, e := range vt {
+			if err := this.walk(catpath(path, k), path, k, e); err != nil {
+				return err
+			}
+		}
+	case Slice:
+		for _, pair := range vt {
+			if err := this.walk(catpath(path, pair.Key), path, pair.Key,
+				pair.Val); err != nil {
+				return err
+			}
+		}
+	case Array:
+		for _, e := range vt {
+			if err := this.walk(path+"[]", path, "[]", e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// see adds one value to the stats for the path.
+func (this *Inferrer) see(path, parent, key string, v interface{}) {
+	st, ok := this.paths[path]
+	if !ok {
+		st = &pathStats{PathSummary: PathSummary{
+			Path:   path,
+			Types:  map[Type]int{},
+			MinLen: -1,
+			MaxLen: -1,
+		}, parent: parent, key: key}
+		this.paths[path] = st
+	}
+	if t, ok := typeOf(v); ok {
+		st.Types[t]++
+	}
+	if st.lastDoc != this.docs {
+		st.lastDoc = this.docs
+		st.Count++
+	}
+	n := -1
+	switch vt := v.(type) {
+	case String:
+		n = len(vt)
+	case Binary:
+		n = len(vt)
+	case Array:
+		n = len(vt)
+	case Map:
+		n = len(vt)
+	case Slice:
+		n = len(vt)
+	}
+	if n == -1 {
+		return
+	}
+	if st.MinLen == -1 || n < st.MinLen {
+		st.MinLen = n
+	}
+	if n > st.MaxLen {
+		st.MaxLen = n
+	}
+}
+
+// Schema returns a Schema which allows the types seen at each path. A key is
+// required if it was in every document, or every embedded document at the path
+// of its parent. Lengths and ranges aren't limited.
+func (this *Inferrer) Schema() *Schema {
+	var paths []string
+	for path := range this.paths {
+		paths = append(paths, path)
+	}
+	// A parent path sorts before its children.
+	sort.Strings(paths)
+	root := &Schema{}
+	schemas := map[string]*Schema{"": root}
+	for _, path := range paths {
+		st := this.paths[path]
+		s := &Schema{}
+		vals := 0
+		for t, n := range st.Types {
+			s.Types = append(s.Types, t)
+			vals += n
+		}
+		sort.Slice(s.Types, func(i, j int) bool {
+			return s.Types[i] < s.Types[j]
+		})
+		schemas[path] = s
+		p := schemas[st.parent]
+		if st.key == "[]" {
+			p.Items = s
+			continue
+		}
+		if p.Fields == nil {
+			p.Fields = map[string]*Schema{}
+		}
+		p.Fields[st.key] = s
+		docs := this.docs
+		if st.parent != "" {
+			docs = this.paths[st.parent].Types[TypeEmbeddedDocument]
+		}
+		if vals == docs {
+			p.Required = append(p.Required, st.key)
+		}
+	}
+	return root
+}
@@ -0,0 +1,86 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestInfer(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	docs := []Doc{
+		Map{"name": String("alice"), "items": Array{Map{"price": Int32(1)}}},
+		Slice{{"name", String("bob")}, {"items", Array{}}},
+		Map{"name": Null{}, "age": Int64(3)},
+		Map{"name": String("carol"), "items": Array{
+			Map{"price": Float(2)},
+			Map{"price": Int32(3)},
+		}},
+	}
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := Infer(NewDecoder(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []PathSummary{
+		{Path: "age", Types: map[Type]int{TypeInt64: 1}, Count: 1, Ratio: 0.25,
+			MinLen: -1, MaxLen: -1},
+		{Path: "items", Types: map[Type]int{TypeArray: 3}, Count: 3, Ratio: 0.75,
+			MinLen: 0, MaxLen: 2},
+		{Path: "items[]", Types: map[Type]int{TypeEmbeddedDocument: 3}, Count: 2,
+			Ratio: 0.5, MinLen: 1, MaxLen: 1},
+		{Path: "items[].price", Types: map[Type]int{TypeInt32: 2, TypeFloat: 1},
+			Count: 2, Ratio: 0.5, MinLen: -1, MaxLen: -1},
+		{Path: "name", Types: map[Type]int{TypeString: 3, TypeNull: 1}, Count: 4,
+			Ratio: 1, MinLen: 3, MaxLen: 5},
+	}
+	if !reflect.DeepEqual(summary, exp) {
+		t.Fatalf("got:\n%v\nexpected:\n%v", summary, exp)
+	}
+}
+
+func TestInferrerSchema(t *testing.T) {
+	inf := NewInferrer()
+	docs := []Doc{
+		Map{"name": String("a"), "tags": Array{String("x")},
+			"address": Map{"city": String("b"), "zip": Int32(1)}},
+		Map{"name": Null{}, "address": Map{"city": String("c")}},
+	}
+	for _, doc := range docs {
+		if err := inf.Add(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := &Schema{
+		Required: []string{"address", "name"},
+		Fields: map[string]*Schema{
+			"address": {
+				Types:    []Type{TypeEmbeddedDocument},
+				Required: []string{"city"},
+				Fields: map[string]*Schema{
+					"city": {Types: []Type{TypeString}},
+					"zip":  {Types: []Type{TypeInt32}},
+				},
+			},
+			"name": {Types: []Type{TypeString, TypeNull}},
+			"tags": {
+				Types: []Type{TypeArray},
+				Items: &Schema{Types: []Type{TypeString}},
+			},
+		},
+	}
+	if s := inf.Schema(); !reflect.DeepEqual(s, exp) {
+		t.Fatal(s)
+	}
+}
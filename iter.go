@@ -0,0 +1,65 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+// Iter iterates over the elements of a document, decoding one at a time.
+// Embedded documents are left encoded as BSON, the same as SliceDepth(1). For
+// example:
+//   it := bs.Iter()
+//   for it.Next() {
+//       fmt.Println(it.Key(), it.Value())
+//   }
+//   if err := it.Err(); err != nil {
+//       ...
+//   }
+type Iter struct {
+	bs   []byte
+	pos  int
+	end  int
+	pair Pair
+	err  error
+	d    rawDecoder
+}
+
+// Iter returns a Iter over the elements of the BSON.
+func (this BSON) Iter() *Iter {
+	it := &Iter{bs: this, d: rawDecoder{slice: true, nest: 1, depth: 1}}
+	docLen, err := rawDocLen(this, 0)
+	if err != nil {
+		it.err = decodeError("", "", 0, 0, err)
+		return it
+	}
+	it.pos, it.end = 4, docLen-1
+	return it
+}
+
+// Next decodes the next element. Returns false when there are no more
+// elements, or on error.
+func (this *Iter) Next() bool {
+	if this.err != nil || this.pos >= this.end {
+		return false
+	}
+	pair, next, err := this.d.elem(this.bs, this.pos, this.end, "", 0)
+	if err != nil {
+		this.err = err
+		return false
+	}
+	this.pair, this.pos = pair, next
+	return true
+}
+
+// Key returns the key of the element decoded by Next.
+func (this *Iter) Key() string {
+	return this.pair.Key
+}
+
+// Value returns the value of the element decoded by Next.
+func (this *Iter) Value() interface{} {
+	return this.pair.Val
+}
+
+// Err returns the error which stopped Next, or nil.
+func (this *Iter) Err() error {
+	return this.err
+}
@@ -0,0 +1,49 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIter(t *testing.T) {
+	bs := allTypes.MustEncode()
+	exp, err := bs.SliceDepth(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s Slice
+	it := bs.Iter()
+	for it.Next() {
+		s = append(s, Pair{it.Key(), it.Value()})
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+
+	// Empty.
+	if it := (Slice{}).MustEncode().Iter(); it.Next() || it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+
+	// Invalid document.
+	if it := BSON([]byte{5, 0, 0, 0}).Iter(); it.Next() || it.Err() == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// Invalid element, the elements before it are returned.
+	bs = Slice{{"a", Int32(1)}, {"b", String("x")}}.MustEncode()
+	bs[len(bs)-7] = 0xFF
+	it = bs.Iter()
+	if !it.Next() || it.Key() != "a" || it.Value() != Int32(1) {
+		t.Fatal(it.Err())
+	}
+	if it.Next() || it.Err() == nil {
+		t.Fatal("Expected error.")
+	}
+}
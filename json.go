@@ -0,0 +1,802 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The BSON types are marshalled to MongoDB Extended JSON so that a document
+// can go through encoding/json and back without losing types. Int32, String
+// and Bool are plain JSON. Float is a plain JSON number which always has a '.'
+// or exponent so it's not mistaken for an integer.
+//
+//   Float           1.0 or {"$numberDouble": "NaN"}
+//   Int32           1
+//   Int64           {"$numberLong": "1"}
+//   Binary          {"$binary": {"base64": "...", "subType": "00"}}
+//   Undefined       {"$undefined": true}
+//   ObjectId        {"$oid": "507f1f77bcf86cd799439011"}, see below
+//   UTCDateTime     {"$date": {"$numberLong": "1"}}
+//   Null            null
+//   Regexp          {"$regularExpression": {"pattern": "...", "options": "..."}}
+//   DBPointer       {"$dbPointer": {"$ref": "...", "$id": {"$oid": "..."}}}
+//   Javascript      {"$code": "..."}
+//   Symbol          {"$symbol": "..."}
+//   JavascriptScope {"$code": "...", "$scope": {...}}
+//   Timestamp       {"$timestamp": {"t": 1, "i": 1}}
+//   MinKey          {"$minKey": 1}
+//   MaxKey          {"$maxKey": 1}
+//
+// A ObjectId on its own, such as a struct field, marshals to a hex string so
+// that it can be used as a plain id. It's only "$oid" in a Map, Slice, BSON,
+// Array, DBPointer or JavascriptScope scope.
+//
+// When unmarshalling an integer is Int32 if it fits, otherwise Int64. A
+// "$date" may also be a RFC 3339 string or a number of milliseconds. A object
+// which isn't one of the above is a document.
+
+// MarshalJSON marshals the Float as a JSON number.
+func (this Float) MarshalJSON() ([]byte, error) {
+	f := float64(this)
+	switch {
+	case math.IsNaN(f):
+		return []byte(`{"$numberDouble":"NaN"}`), nil
+	case math.IsInf(f, 1):
+		return []byte(`{"$numberDouble":"Infinity"}`), nil
+	case math.IsInf(f, -1):
+		return []byte(`{"$numberDouble":"-Infinity"}`), nil
+	}
+	b := strconv.AppendFloat(nil, f, 'g', -1, 64)
+	if !bytes.ContainsAny(b, ".e") {
+		b = append(b, ".0"...)
+	}
+	return b, nil
+}
+
+// UnmarshalJSON unmarshals a JSON number or "$numberDouble".
+func (this *Float) UnmarshalJSON(b []byte) error {
+	v, err := parseJSON(b, false)
+	if err != nil {
+		return err
+	}
+	switch vt := v.(type) {
+	case Float:
+		*this = vt
+	case Int32:
+		*this = Float(vt)
+	case Int64:
+		*this = Float(vt)
+	default:
+		return jsonTypeError(v, this)
+	}
+	return nil
+}
+
+// UnmarshalJSON unmarshals a JSON number or "$numberInt".
+func (this *Int32) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Int64 as "$numberLong".
+func (this Int64) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$numberLong", strconv.FormatInt(int64(this), 10))
+}
+
+// UnmarshalJSON unmarshals a JSON number or "$numberLong".
+func (this *Int64) UnmarshalJSON(b []byte) error {
+	v, err := parseJSON(b, false)
+	if err != nil {
+		return err
+	}
+	switch vt := v.(type) {
+	case Int32:
+		*this = Int64(vt)
+	case Int64:
+		*this = vt
+	default:
+		return jsonTypeError(v, this)
+	}
+	return nil
+}
+
+// MarshalJSON marshals the Binary as "$binary".
+func (this Binary) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$binary", Slice{
+		{"base64", base64.StdEncoding.EncodeToString(this)},
+		{"subType", "00"},
+	})
+}
+
+// UnmarshalJSON unmarshals "$binary". The subtype is ignored.
+func (this *Binary) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals Undefined as "$undefined".
+func (this Undefined) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$undefined":true}`), nil
+}
+
+// UnmarshalJSON unmarshals "$undefined".
+func (this *Undefined) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON returns the ObjectId as a hex JSON string. An empty ObjectId is
+// null.
+func (this ObjectId) MarshalJSON() ([]byte, error) {
+	if len(this) == 0 {
+		return []byte("null"), nil
+	}
+	b, err := this.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(b))
+}
+
+// extendedObjectId is a ObjectId in a document, which is marshalled as "$oid".
+type extendedObjectId ObjectId
+
+func (this extendedObjectId) MarshalJSON() ([]byte, error) {
+	if len(this) == 0 {
+		return []byte("null"), nil
+	}
+	b, err := ObjectId(this).MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSONWrapper("$oid", string(b))
+}
+
+// jsonValue returns the value to marshal for a value in a document.
+func jsonValue(v interface{}) interface{} {
+	if oid, ok := v.(ObjectId); ok {
+		return extendedObjectId(oid)
+	}
+	return v
+}
+
+// UnmarshalJSON unmarshals "$oid", or a hex string. Null is an empty
+// ObjectId.
+func (this *ObjectId) UnmarshalJSON(b []byte) error {
+	v, err := parseJSON(b, false)
+	if err != nil {
+		return err
+	}
+	switch vt := v.(type) {
+	case Null:
+		*this = nil
+	case ObjectId:
+		*this = vt
+	case String:
+		return this.UnmarshalText([]byte(vt))
+	default:
+		return jsonTypeError(v, this)
+	}
+	return nil
+}
+
+// MarshalJSON marshals the UTCDateTime as "$date".
+func (this UTCDateTime) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$date", Int64(this))
+}
+
+// UnmarshalJSON unmarshals "$date".
+func (this *UTCDateTime) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals Null as null.
+func (this Null) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON unmarshals null.
+func (this *Null) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Regexp as "$regularExpression".
+func (this Regexp) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$regularExpression", Slice{
+		{"pattern", this.Pattern},
+		{"options", this.Options},
+	})
+}
+
+// UnmarshalJSON unmarshals "$regularExpression".
+func (this *Regexp) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the DBPointer as "$dbPointer".
+func (this DBPointer) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$dbPointer", Slice{
+		{"$ref", this.Name},
+		{"$id", extendedObjectId(this.ObjectId)},
+	})
+}
+
+// UnmarshalJSON unmarshals "$dbPointer".
+func (this *DBPointer) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Javascript as "$code".
+func (this Javascript) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$code", string(this))
+}
+
+// UnmarshalJSON unmarshals "$code".
+func (this *Javascript) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Symbol as "$symbol".
+func (this Symbol) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$symbol", string(this))
+}
+
+// UnmarshalJSON unmarshals "$symbol".
+func (this *Symbol) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the JavascriptScope as "$code" and "$scope".
+func (this JavascriptScope) MarshalJSON() ([]byte, error) {
+	var scope Doc = this.Scope
+	if scope == nil {
+		scope = Map{}
+	}
+	return json.Marshal(Slice{{"$code", this.Javascript}, {"$scope", scope}})
+}
+
+// UnmarshalJSON unmarshals "$code" and "$scope". The scope is a Map.
+func (this *JavascriptScope) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Timestamp as "$timestamp".
+func (this Timestamp) MarshalJSON() ([]byte, error) {
+	return marshalJSONWrapper("$timestamp", Slice{
+		{"t", this.T()},
+		{"i", this.I()},
+	})
+}
+
+// UnmarshalJSON unmarshals "$timestamp".
+func (this *Timestamp) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals MinKey as "$minKey".
+func (this MinKey) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$minKey":1}`), nil
+}
+
+// UnmarshalJSON unmarshals "$minKey".
+func (this *MinKey) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals MaxKey as "$maxKey".
+func (this MaxKey) MarshalJSON() ([]byte, error) {
+	return []byte(`{"$maxKey":1}`), nil
+}
+
+// UnmarshalJSON unmarshals "$maxKey".
+func (this *MaxKey) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Array as a JSON array.
+func (this Array) MarshalJSON() ([]byte, error) {
+	if this == nil {
+		return []byte("null"), nil
+	}
+	a := make([]interface{}, len(this))
+	for i, v := range this {
+		a[i] = jsonValue(v)
+	}
+	return json.Marshal(a)
+}
+
+// UnmarshalJSON unmarshals a JSON array. Documents are Maps.
+func (this *Array) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Map as a JSON object with the keys sorted.
+func (this Map) MarshalJSON() ([]byte, error) {
+	if this == nil {
+		return []byte("null"), nil
+	}
+	return sortedPairs(this).MarshalJSON()
+}
+
+// UnmarshalJSON unmarshals a JSON object. Nested documents are Maps.
+func (this *Map) UnmarshalJSON(b []byte) error {
+	return unmarshalJSONAs(b, this)
+}
+
+// MarshalJSON marshals the Slice as a JSON object with the keys in order.
+func (this Slice) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte('{')
+	for i, pair := range this {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(pair.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(jsonValue(pair.Val))
+		if err != nil {
+			return nil, fmt.Errorf("%v, %v", pair.Key, err)
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON unmarshals a JSON object. The order of keys is kept. Nested
+// documents are Slices.
+func (this *Slice) UnmarshalJSON(b []byte) error {
+	v, err := parseJSON(b, true)
+	if err != nil {
+		return err
+	}
+	s, ok := v.(Slice)
+	if !ok {
+		return jsonTypeError(v, this)
+	}
+	*this = s
+	return nil
+}
+
+// MarshalJSON marshals the BSON as a JSON object with the keys in order.
+func (this BSON) MarshalJSON() ([]byte, error) {
+	s, err := this.Slice()
+	if err != nil {
+		return nil, err
+	}
+	return s.MarshalJSON()
+}
+
+// JSONOptions are options for formatting Extended JSON.
+type JSONOptions struct {
+	Indent     string // Indent for each level of nesting. Empty is compact.
+	SortKeys   bool   // Sort keys of documents instead of keeping the order.
+	EscapeHTML bool   // Escape <, > and & in strings, as encoding/json does.
+}
+
+// FormatJSON transcodes the BSON document to Extended JSON formatted with the
+// options.
+func (this BSON) FormatJSON(opts JSONOptions) (string, error) {
+	j, err := this.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.UseNumber()
+	v, err := readJSONTree(dec)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := writeJSONTree(buf, v, opts, ""); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// IndentJSON transcodes the BSON document to Extended JSON indented with two
+// spaces, for reading by humans.
+func (this BSON) IndentJSON() (string, error) {
+	return this.FormatJSON(JSONOptions{Indent: "  "})
+}
+
+// jsonObject is a JSON object with the order of keys kept. Values are
+// jsonObject, []interface{}, json.Number, string, bool or nil.
+type jsonObject []jsonMember
+
+type jsonMember struct {
+	Key string
+	Val interface{}
+}
+
+// readJSONTree reads the next JSON value.
+func readJSONTree(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('['):
+		a := []interface{}{}
+		for dec.More() {
+			v, err := readJSONTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		_, err := dec.Token()
+		return a, err
+	case json.Delim('{'):
+		o := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			v, err := readJSONTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			o = append(o, jsonMember{Key: key.(string), Val: v})
+		}
+		_, err := dec.Token()
+		return o, err
+	}
+	return tok, nil
+}
+
+// writeJSONTree writes a value read by readJSONTree.
+func writeJSONTree(buf *bytes.Buffer, v interface{}, opts JSONOptions,
+	indent string) error {
+
+	inner := indent + opts.Indent
+	newline := func(indent string) {
+		if opts.Indent != "" {
+			buf.WriteByte('\n')
+			buf.WriteString(indent)
+		}
+	}
+	switch vt := v.(type) {
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, e := range vt {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(inner)
+			if err := writeJSONTree(buf, e, opts, inner); err != nil {
+				return err
+			}
+		}
+		if len(vt) > 0 {
+			newline(indent)
+		}
+		buf.WriteByte(']')
+	case jsonObject:
+		if opts.SortKeys {
+			sorted := make(jsonObject, len(vt))
+			copy(sorted, vt)
+			sort.SliceStable(sorted, func(i, j int) bool {
+				return sorted[i].Key < sorted[j].Key
+			})
+			vt = sorted
+		}
+		buf.WriteByte('{')
+		for i, m := range vt {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			newline(inner)
+			if err := writeJSONString(buf, m.Key, opts.EscapeHTML); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if opts.Indent != "" {
+				buf.WriteByte(' ')
+			}
+			if err := writeJSONTree(buf, m.Val, opts, inner); err != nil {
+				return err
+			}
+		}
+		if len(vt) > 0 {
+			newline(indent)
+		}
+		buf.WriteByte('}')
+	case string:
+		return writeJSONString(buf, vt, opts.EscapeHTML)
+	case json.Number:
+		buf.WriteString(string(vt))
+	case bool:
+		buf.WriteString(strconv.FormatBool(vt))
+	case nil:
+		buf.WriteString("null")
+	default:
+		return fmt.Errorf("unexpected JSON token %T.", v)
+	}
+	return nil
+}
+
+// writeJSONString writes a quoted JSON string.
+func writeJSONString(buf *bytes.Buffer, s string, escapeHTML bool) error {
+	tmp := bytes.NewBuffer(nil)
+	enc := json.NewEncoder(tmp)
+	enc.SetEscapeHTML(escapeHTML)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+	// Encode adds a newline.
+	buf.Write(bytes.TrimSuffix(tmp.Bytes(), []byte{'\n'}))
+	return nil
+}
+
+// marshalJSONWrapper marshals a Extended JSON object with one key.
+func marshalJSONWrapper(key string, val interface{}) ([]byte, error) {
+	return json.Marshal(Slice{{key, val}})
+}
+
+// unmarshalJSONAs parses the JSON and sets dst if it's the same type.
+func unmarshalJSONAs[T any](b []byte, dst *T) error {
+	v, err := parseJSON(b, false)
+	if err != nil {
+		return err
+	}
+	vt, ok := v.(T)
+	if !ok {
+		return jsonTypeError(v, dst)
+	}
+	*dst = vt
+	return nil
+}
+
+func jsonTypeError(v, dst interface{}) error {
+	return fmt.Errorf("cannot unmarshal JSON %T in to %T.", v, dst)
+}
+
+// parseJSON parses Extended JSON to BSON types. If slice is true documents are
+// Slices, otherwise Maps.
+func parseJSON(b []byte, slice bool) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	v, err := parseJSONVal(dec, slice)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("JSON has trailing data.")
+	}
+	return v, nil
+}
+
+// parseJSONVal parses the next JSON value.
+func parseJSONVal(dec *json.Decoder, slice bool) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tokt := tok.(type) {
+	case json.Delim:
+		if tokt == '[' {
+			a := Array{}
+			for dec.More() {
+				v, err := parseJSONVal(dec, slice)
+				if err != nil {
+					return nil, err
+				}
+				a = append(a, v)
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, err
+			}
+			return a, nil
+		}
+		s := Slice{}
+		for dec.More() {
+			ktok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := ktok.(string)
+			v, err := parseJSONVal(dec, slice)
+			if err != nil {
+				return nil, fmt.Errorf("%v, %v", key, err)
+			}
+			s = append(s, Pair{Key: key, Val: v})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		if v, ok, err := parseExtJSON(s); ok || err != nil {
+			return v, err
+		}
+		if slice {
+			return s, nil
+		}
+		m := make(Map, len(s))
+		for _, pair := range s {
+			m[pair.Key] = pair.Val
+		}
+		return m, nil
+	case string:
+		return String(tokt), nil
+	case bool:
+		return Bool(tokt), nil
+	case json.Number:
+		return parseJSONNumber(string(tokt))
+	}
+	return Null{}, nil
+}
+
+// parseJSONNumber returns a Int32 or Int64 if the number is a integer which
+// fits, otherwise a Float.
+func parseJSONNumber(s string) (interface{}, error) {
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			if i >= math.MinInt32 && i <= math.MaxInt32 {
+				return Int32(i), nil
+			}
+			return Int64(i), nil
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return Float(f), nil
+}
+
+// parseExtJSON converts a object which is a Extended JSON type. Returns false
+// if the object is a document.
+func parseExtJSON(s Slice) (interface{}, bool, error) {
+	if len(s) == 0 || !strings.HasPrefix(s[0].Key, "$") {
+		return nil, false, nil
+	}
+	if len(s) == 2 {
+		f := jsonFields(s)
+		code, ok0 := f["$code"].(String)
+		scope, ok1 := f["$scope"].(Doc)
+		if !ok0 || !ok1 {
+			return nil, false, nil
+		}
+		return JavascriptScope{Javascript: string(code), Scope: scope}, true, nil
+	}
+	if len(s) != 1 {
+		return nil, false, nil
+	}
+	key, val := s[0].Key, s[0].Val
+	var v interface{}
+	var err error
+	switch key {
+	case "$numberInt":
+		str, _ := val.(String)
+		var i int64
+		i, err = strconv.ParseInt(string(str), 10, 32)
+		v = Int32(i)
+	case "$numberLong":
+		str, _ := val.(String)
+		var i int64
+		i, err = strconv.ParseInt(string(str), 10, 64)
+		v = Int64(i)
+	case "$numberDouble":
+		str, _ := val.(String)
+		var f float64
+		f, err = strconv.ParseFloat(string(str), 64)
+		v = Float(f)
+	case "$binary":
+		str, _ := jsonFields(val)["base64"].(String)
+		var b []byte
+		b, err = base64.StdEncoding.DecodeString(string(str))
+		v = Binary(b)
+	case "$undefined":
+		v = Undefined{}
+	case "$oid":
+		str, _ := val.(String)
+		v, err = ObjectIdFromHex(string(str))
+	case "$date":
+		switch vt := val.(type) {
+		case Int32:
+			v = UTCDateTime(vt)
+		case Int64:
+			v = UTCDateTime(vt)
+		case String:
+			var t time.Time
+			t, err = time.Parse(time.RFC3339Nano, string(vt))
+			v = NewUTCDateTime(t)
+		default:
+			err = errors.New("invalid date")
+		}
+	case "$regularExpression":
+		f := jsonFields(val)
+		pattern, ok0 := f["pattern"].(String)
+		options, ok1 := f["options"].(String)
+		if !ok0 || !ok1 {
+			err = errors.New("invalid pattern or options")
+		}
+		v = Regexp{Pattern: string(pattern), Options: string(options)}
+	case "$dbPointer":
+		f := jsonFields(val)
+		name, ok0 := f["$ref"].(String)
+		oid, ok1 := f["$id"].(ObjectId)
+		if !ok0 || !ok1 {
+			err = errors.New("invalid $ref or $id")
+		}
+		v = DBPointer{Name: string(name), ObjectId: oid}
+	case "$code":
+		str, ok := val.(String)
+		if !ok {
+			err = errors.New("code must be a string")
+		}
+		v = Javascript(str)
+	case "$symbol":
+		str, ok := val.(String)
+		if !ok {
+			err = errors.New("symbol must be a string")
+		}
+		v = Symbol(str)
+	case "$timestamp":
+		f := jsonFields(val)
+		t, ok0 := jsonUint32(f["t"])
+		i, ok1 := jsonUint32(f["i"])
+		if !ok0 || !ok1 {
+			err = errors.New("invalid t or i")
+		}
+		v = NewTimestamp(t, i)
+	case "$minKey":
+		v = MinKey{}
+	case "$maxKey":
+		v = MaxKey{}
+	default:
+		// Document with a key starting with $, such as a query operator.
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Extended JSON %v invalid, %v.", key, err)
+	}
+	return v, true, nil
+}
+
+// jsonFields returns the fields of a parsed JSON object, or nil if it's not
+// a object.
+func jsonFields(v interface{}) Map {
+	switch vt := v.(type) {
+	case Map:
+		return vt
+	case Slice:
+		m := make(Map, len(vt))
+		for _, pair := range vt {
+			m[pair.Key] = pair.Val
+		}
+		return m
+	}
+	return nil
+}
+
+// jsonUint32 returns a parsed JSON integer as a uint32.
+func jsonUint32(v interface{}) (uint32, bool) {
+	var i int64
+	switch vt := v.(type) {
+	case Int32:
+		i = int64(vt)
+	case Int64:
+		i = int64(vt)
+	default:
+		return 0, false
+	}
+	if i < 0 || i > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32(i), true
+}
@@ -0,0 +1,155 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+var jsonTests = []struct {
+	val interface{}
+	exp string
+}{
+	{Float(1), `1.0`},
+	{Float(1.5e300), `1.5e+300`},
+	{Float(math.Inf(-1)), `{"$numberDouble":"-Infinity"}`},
+	{Int32(1), `1`},
+	{Int64(1), `{"$numberLong":"1"}`},
+	{Binary{0x00, 0x01}, `{"$binary":{"base64":"AAE=","subType":"00"}}`},
+	{Undefined{}, `{"$undefined":true}`},
+	{Map{"a": ObjectId{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7, 0x99,
+		0x43, 0x90, 0x11}}, `{"a":{"$oid":"507f1f77bcf86cd799439011"}}`},
+	{Array{ObjectId{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7, 0x99,
+		0x43, 0x90, 0x11}}, `[{"$oid":"507f1f77bcf86cd799439011"}]`},
+	{UTCDateTime(123), `{"$date":{"$numberLong":"123"}}`},
+	{Null{}, `null`},
+	{Regexp{"a", "i"}, `{"$regularExpression":{"pattern":"a","options":"i"}}`},
+	{DBPointer{"foo", ObjectId{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7,
+		0x99, 0x43, 0x90, 0x11}},
+		`{"$dbPointer":{"$ref":"foo","$id":{"$oid":"507f1f77bcf86cd799439011"}}}`},
+	{Javascript("f()"), `{"$code":"f()"}`},
+	{Symbol("foo"), `{"$symbol":"foo"}`},
+	{JavascriptScope{"f()", Map{"a": Int32(1)}}, `{"$code":"f()","$scope":{"a":1}}`},
+	{NewTimestamp(1, 2), `{"$timestamp":{"t":1,"i":2}}`},
+	{MinKey{}, `{"$minKey":1}`},
+	{MaxKey{}, `{"$maxKey":1}`},
+	{Slice{{"b", Int32(1)}, {"a", Array{String("x")}}}, `{"b":1,"a":["x"]}`},
+}
+
+func TestJSON(t *testing.T) {
+	for _, test := range jsonTests {
+		b, err := json.Marshal(test.val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.exp {
+			t.Fatal(string(b), test.exp)
+		}
+
+		// Unmarshal in to the same type.
+		dst := reflect.New(reflect.TypeOf(test.val))
+		if err := json.Unmarshal(b, dst.Interface()); err != nil {
+			t.Fatal(err, test.exp)
+		}
+		if !reflect.DeepEqual(dst.Elem().Interface(), test.val) {
+			t.Fatal(dst.Elem().Interface(), test.val)
+		}
+	}
+}
+
+func TestJSONUnmarshal(t *testing.T) {
+	var m Map
+	if err := json.Unmarshal([]byte(`{
+		"int": 2147483648,
+		"float": 1e2,
+		"date": {"$date": "1970-01-01T00:00:01Z"},
+		"dateNum": {"$date": 5},
+		"numberInt": {"$numberInt": "7"},
+		"query": {"$gt": 1},
+		"nest": {"a": [{"b": null}]}
+	}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{
+		"int":       Int64(2147483648),
+		"float":     Float(100),
+		"date":      UTCDateTime(1000),
+		"dateNum":   UTCDateTime(5),
+		"numberInt": Int32(7),
+		"query":     Map{"$gt": Int32(1)},
+		"nest":      Map{"a": Array{Map{"b": Null{}}}},
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+
+	for _, s := range []string{
+		`{"a": {"$oid": "xyz"}}`,
+		`{"a": {"$numberLong": "1.5"}}`,
+		`{"a": {"$timestamp": {"t": -1, "i": 0}}}`,
+		`{"a": {"$date": true}}`,
+		`{"a": 1} {}`,
+		`[1]`,
+	} {
+		if err := json.Unmarshal([]byte(s), &m); err == nil {
+			t.Fatal("Expected error.", s)
+		}
+	}
+	var i Int32
+	if err := json.Unmarshal([]byte(`{"$numberLong": "1"}`), &i); err == nil {
+		t.Fatal("Expected error unmarshalling Int64 in to Int32.")
+	}
+}
+
+func TestBSONJSON(t *testing.T) {
+	bs := Slice{{"z", Int32(1)}, {"a", Map{"b": Int64(2)}}}.MustEncode()
+	j, err := bs.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j != `{"z":1,"a":{"b":{"$numberLong":"2"}}}` {
+		t.Fatal(j)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	bs := Slice{
+		{"z", String("<a>")},
+		{"a", Slice{{"y", Array{Int32(1), Map{}}}, {"x", Array{}}}},
+	}.MustEncode()
+	j, err := bs.FormatJSON(JSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j != `{"z":"<a>","a":{"y":[1,{}],"x":[]}}` {
+		t.Fatal(j)
+	}
+	j, err = bs.FormatJSON(JSONOptions{SortKeys: true, EscapeHTML: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j != `{"a":{"x":[],"y":[1,{}]},"z":"\u003ca\u003e"}` {
+		t.Fatal(j)
+	}
+	j, err = bs.IndentJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := `{
+  "z": "<a>",
+  "a": {
+    "y": [
+      1,
+      {}
+    ],
+    "x": []
+  }
+}`
+	if j != exp {
+		t.Fatal(j)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+// jsonSchemaTypes are the JSON Schema types of the BSON types, as they're
+// marshalled to JSON by this package. Types which are marshalled as Extended
+// JSON wrappers (e.g. {"$oid": "..."}) are objects.
+var jsonSchemaTypes = map[Type]string{
+	TypeFloat:            "number",
+	TypeString:           "string",
+	TypeEmbeddedDocument: "object",
+	TypeArray:            "array",
+	TypeBool:             "boolean",
+	TypeNull:             "null",
+	TypeInt32:            "integer",
+}
+
+// mongoSchemaTypes are the names MongoDB uses for the BSON types in bsonType.
+var mongoSchemaTypes = map[Type]string{
+	TypeFloat:            "double",
+	TypeString:           "string",
+	TypeEmbeddedDocument: "object",
+	TypeArray:            "array",
+	TypeBinary:           "binData",
+	TypeUndefined:        "undefined",
+	TypeObjectId:         "objectId",
+	TypeBool:             "bool",
+	TypeUTCDateTime:      "date",
+	TypeNull:             "null",
+	TypeRegexp:           "regex",
+	TypeDBPointer:        "dbPointer",
+	TypeJavascript:       "javascript",
+	TypeSymbol:           "symbol",
+	TypeJavascriptScope:  "javascriptWithScope",
+	TypeInt32:            "int",
+	TypeTimestamp:        "timestamp",
+	TypeInt64:            "long",
+	TypeMinKey:           "minKey",
+	TypeMaxKey:           "maxKey",
+}
+
+// JSONSchema converts the Schema to a draft-07 JSON Schema for documents
+// marshalled to JSON by this package. The result can be marshalled with
+// encoding/json. Binary lengths can't be expressed, so they aren't limited.
+func (this *Schema) JSONSchema() map[string]interface{} {
+	m := this.jsonSchema(false).(map[string]interface{})
+	m["$schema"] = "http://json-schema.org/draft-07/schema#"
+	return m
+}
+
+// MongoJSONSchema converts the Schema to a MongoDB $jsonSchema, which uses
+// bsonType rather than type. For example, to use it as a collection validator:
+//   bson.Map{"$jsonSchema": schema.MongoJSONSchema()}
+func (this *Schema) MongoJSONSchema() Map {
+	return this.jsonSchema(true).(Map)
+}
+
+// lenKeywords are the JSON Schema keywords for MinLen and MaxLen by type.
+var lenKeywords = []struct {
+	typ      Type
+	min, max string
+}{
+	{TypeString, "minLength", "maxLength"},
+	{TypeArray, "minItems", "maxItems"},
+	{TypeEmbeddedDocument, "minProperties", "maxProperties"},
+}
+
+// jsonSchema returns the JSON Schema as a map[string]interface{}, or as a Map
+// of BSON types for MongoDB.
+func (this *Schema) jsonSchema(mongo bool) interface{} {
+	m := map[string]interface{}{}
+
+	// Types.
+	var names []string
+	seen := map[string]bool{}
+	for _, t := range this.Types {
+		name, ok := jsonSchemaTypes[t]
+		if mongo {
+			name, ok = mongoSchemaTypes[t]
+		} else if !ok {
+			name, ok = "object", true
+		}
+		if ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	typeKey := "type"
+	if mongo {
+		typeKey = "bsonType"
+	}
+	if len(names) == 1 {
+		m[typeKey] = names[0]
+	} else if len(names) > 1 {
+		m[typeKey] = names
+	}
+
+	// Ranges.
+	if this.Min != nil {
+		m["minimum"] = *this.Min
+	}
+	if this.Max != nil {
+		m["maximum"] = *this.Max
+	}
+	for _, kw := range lenKeywords {
+		if len(this.Types) != 0 && !hasType(this.Types, kw.typ) {
+			continue
+		}
+		if this.MinLen != nil {
+			m[kw.min] = *this.MinLen
+		}
+		if this.MaxLen != nil {
+			m[kw.max] = *this.MaxLen
+		}
+	}
+
+	// Nested.
+	if len(this.Required) != 0 {
+		m["required"] = append([]string(nil), this.Required...)
+	}
+	if len(this.Fields) != 0 {
+		props := map[string]interface{}{}
+		for k, s := range this.Fields {
+			props[k] = s.jsonSchema(mongo)
+		}
+		m["properties"] = props
+	}
+	if this.Closed {
+		m["additionalProperties"] = false
+	}
+	if this.Items != nil {
+		m["items"] = this.Items.jsonSchema(mongo)
+	}
+	if mongo {
+		return mongoMap(m)
+	}
+	return m
+}
+
+// mongoMap converts a JSON Schema to a Map of BSON types. Nested schemas are
+// already Maps.
+func mongoMap(m map[string]interface{}) Map {
+	dst := make(Map, len(m))
+	for k, v := range m {
+		switch vt := v.(type) {
+		case string:
+			dst[k] = String(vt)
+		case []string:
+			a := make(Array, len(vt))
+			for i, s := range vt {
+				a[i] = String(s)
+			}
+			dst[k] = a
+		case float64:
+			dst[k] = Float(vt)
+		case int:
+			dst[k] = Int64(vt)
+		case bool:
+			dst[k] = Bool(vt)
+		case map[string]interface{}:
+			dst[k] = Map(vt)
+		default:
+			dst[k] = v
+		}
+	}
+	return dst
+}
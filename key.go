@@ -0,0 +1,79 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"strings"
+)
+
+// checkKey applies the RejectNULKeys and SpecialKeys policies to a key.
+// Returns the key to encode.
+func (this *Encoder) checkKey(path, key string) (string, error) {
+	if this.RejectNULKeys && strings.IndexByte(key, 0x00) != -1 {
+		return "", encodeErrorf(path, "%w, contains NUL.", ErrInvalidKey)
+	}
+	switch this.SpecialKeys {
+	case KeyEscape:
+		return escapeSpecialKey(key), nil
+	case KeyError:
+		if isSpecialKey(key) {
+			return "", encodeErrorf(path,
+				"%w, starts with '$' or contains '.'.", ErrInvalidKey)
+		}
+	}
+	return key, nil
+}
+
+// isSpecialKey returns true if the key starts with '$' or contains '.'.
+func isSpecialKey(key string) bool {
+	return strings.HasPrefix(key, "$") || strings.IndexByte(key, '.') != -1
+}
+
+// EscapeKey escapes a key so it can be used as one component of a dotted path,
+// such as for Reach, Set or Unflatten. A '.' or '\' is escaped with a
+// backslash. For example:
+//   doc.Reach(&dst, "hosts."+bson.EscapeKey("example.com")+".port")
+// This is not the same as the escaping done by Encoder SpecialKeys, which is
+// so that keys can be stored in MongoDB.
+func EscapeKey(key string) string {
+	if strings.IndexAny(key, ".\\") == -1 {
+		return key
+	}
+	dst := make([]byte, 0, len(key)+2)
+	for i := 0; i < len(key); i++ {
+		if key[i] == '.' || key[i] == '\\' {
+			dst = append(dst, '\\')
+		}
+		dst = append(dst, key[i])
+	}
+	return string(dst)
+}
+
+// UnescapeKey is the inverse of EscapeKey. A backslash escapes the character
+// after it.
+func UnescapeKey(key string) string {
+	if strings.IndexByte(key, '\\') == -1 {
+		return key
+	}
+	dst := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		dst = append(dst, key[i])
+	}
+	return string(dst)
+}
+
+// keyEscaper escapes '%', '$' and '.' in keys.
+var keyEscaper = strings.NewReplacer("%", "%25", "$", "%24", ".", "%2E")
+
+// escapeSpecialKey escapes a key which starts with '$', or contains '.' or '%',
+// for SpecialKeys KeyEscape. Other keys are returned as is.
+func escapeSpecialKey(key string) string {
+	if !isSpecialKey(key) && strings.IndexByte(key, '%') == -1 {
+		return key
+	}
+	return keyEscaper.Replace(key)
+}
@@ -0,0 +1,149 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+)
+
+// LazyDoc is a document which is decoded one element at a time, when the
+// element is accessed. Decoded values are cached. This is for documents where
+// most elements are never looked at, such as large documents of which a
+// handler reads a few keys. For example:
+//   doc, err := bson.NewLazyDoc(bs)
+//   if err != nil {
+//       return err
+//   }
+//   var name string
+//   ok, err := doc.Reach(&name, "user.name")
+// Only "user" is decoded. As with Map, if a key is repeated the last value is
+// used. A LazyDoc isn't safe for concurrent use, and the BSON must not be
+// modified while it's in use.
+type LazyDoc struct {
+	bs    BSON
+	elems []rawElement
+	index map[string]int // Key to last element with it.
+	cache map[string]interface{}
+}
+
+// NewLazyDoc returns a LazyDoc of the BSON. The elements are found, which
+// returns error if the document is malformed, but not decoded.
+func NewLazyDoc(bs BSON) (*LazyDoc, error) {
+	elems, err := rawElements(bs, 0)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[string]int, len(elems))
+	for i, e := range elems {
+		index[e.Name] = i
+	}
+	return &LazyDoc{bs: bs, elems: elems, index: index}, nil
+}
+
+// BSON returns the document the LazyDoc is of.
+func (this *LazyDoc) BSON() BSON {
+	return this.bs
+}
+
+// Len returns the number of keys.
+func (this *LazyDoc) Len() int {
+	return len(this.index)
+}
+
+// Keys returns the keys in document order.
+func (this *LazyDoc) Keys() []string {
+	keys := make([]string, 0, len(this.index))
+	for i, e := range this.elems {
+		if this.index[e.Name] == i {
+			keys = append(keys, e.Name)
+		}
+	}
+	return keys
+}
+
+// Has returns true if the key exists.
+func (this *LazyDoc) Has(key string) bool {
+	_, ok := this.index[key]
+	return ok
+}
+
+// Type returns the type of the value of a key without decoding it.
+func (this *LazyDoc) Type(key string) (Type, bool) {
+	i, ok := this.index[key]
+	if !ok {
+		return 0, false
+	}
+	return Type(this.elems[i].Type), true
+}
+
+// Get decodes the value of a key, or returns it from the cache. Embedded
+// documents are Maps. Returns false if the key doesn't exist. Values from the
+// cache are shared, modifying a Map or Array modifies the cached value.
+func (this *LazyDoc) Get(key string) (interface{}, bool, error) {
+	if v, ok := this.cache[key]; ok {
+		return v, true, nil
+	}
+	i, ok := this.index[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := this.elems[i]
+	d := rawDecoder{}
+	v, err := d.value(e.Type, this.bs[e.Value:e.End], e.Name, e.Value)
+	if err != nil {
+		return nil, false, decodeError("", e.Name, e.Start, e.Type, err)
+	}
+	if this.cache == nil {
+		this.cache = make(map[string]interface{})
+	}
+	this.cache[key] = v
+	return v, true, nil
+}
+
+// Reach is the same as map Reach, but only the value of the first key in the
+// path is decoded.
+func (this *LazyDoc) Reach(dst interface{}, dot ...string) (bool, error) {
+	if dst == nil {
+		return false, errors.New("dst must not be nil.")
+	}
+	path := splitPath(dot)
+	if len(path) == 0 {
+		m, err := this.Map()
+		if err != nil {
+			return false, err
+		}
+		return assign(dst, m)
+	}
+	v, ok, err := this.Get(path[0])
+	if err != nil || !ok {
+		return false, err
+	}
+	if v, ok = reach(v, path[1:]...); !ok {
+		return false, nil
+	}
+	return assign(dst, v)
+}
+
+// Same as map MustReach.
+func (this *LazyDoc) MustReach(dst interface{}, dot ...string) {
+	mustReach(this, dst, dot...)
+}
+
+// Same as map ReachOr.
+func (this *LazyDoc) ReachOr(dst, def interface{}, dot ...string) error {
+	return reachOr(this, dst, def, dot...)
+}
+
+// Map decodes every key which isn't cached and returns the document as a Map.
+func (this *LazyDoc) Map() (Map, error) {
+	m := make(Map, len(this.index))
+	for key := range this.index {
+		v, _, err := this.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
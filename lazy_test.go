@@ -0,0 +1,88 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLazyDoc(t *testing.T) {
+	bs := Slice{
+		{"a", Int32(1)},
+		{"user", Map{"name": String("bob")}},
+		{"tags", Array{String("x"), String("y")}},
+		{"a", Int32(2)},
+	}.MustEncode()
+	doc, err := NewLazyDoc(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Len() != 3 {
+		t.Fatalf("Expected 3 keys, got %v.", doc.Len())
+	}
+	if keys := doc.Keys(); !reflect.DeepEqual(keys,
+		[]string{"user", "tags", "a"}) {
+
+		t.Fatalf("Unexpected keys %v.", keys)
+	}
+	if typ, ok := doc.Type("tags"); !ok || typ != TypeArray {
+		t.Fatalf("Expected array, got %v, %v.", typ, ok)
+	}
+	if len(doc.cache) != 0 {
+		t.Fatal("Expected nothing decoded.")
+	}
+
+	var name string
+	if ok, err := doc.Reach(&name, "user.name"); !ok || err != nil {
+		t.Fatalf("Expected found, got %v, %v.", ok, err)
+	}
+	if name != "bob" {
+		t.Fatalf("Expected bob, got %v.", name)
+	}
+	if len(doc.cache) != 1 {
+		t.Fatalf("Expected only user decoded, got %v.", doc.cache)
+	}
+	if v, ok, err := doc.Get("a"); !ok || err != nil || v != Int32(2) {
+		t.Fatalf("Expected last a, got %v, %v, %v.", v, ok, err)
+	}
+	if _, ok, err := doc.Get("missing"); ok || err != nil {
+		t.Fatalf("Expected not found, got %v, %v.", ok, err)
+	}
+	tag, ok, err := ReachAs[string](doc, "tags.1")
+	if !ok || err != nil || tag != "y" {
+		t.Fatalf("Expected y, got %v, %v, %v.", tag, ok, err)
+	}
+
+	m, err := doc.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("Expected %v, got %v.", want, m)
+	}
+}
+
+func TestLazyDocErrors(t *testing.T) {
+	if _, err := NewLazyDoc(BSON{5, 0, 0, 0}); !errors.Is(err, ErrTruncated) {
+		t.Fatalf("Expected truncated, got %v.", err)
+	}
+	// Embedded document with a unsupported type.
+	bs := Map{"a": Map{"b": Int32(1)}}.MustEncode()
+	bs[11] = 0x7F
+	doc, err := NewLazyDoc(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = doc.Get("a")
+	var de *DecodeError
+	if !errors.As(err, &de) || de.Path != "a" {
+		t.Fatalf("Expected decode error at a, got %v.", err)
+	}
+}
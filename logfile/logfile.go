@@ -0,0 +1,311 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+Package logfile is a append-only file of documents, the storage layer for
+simple durable queues and event logs.
+
+Each record is a document followed by the CRC32C of it, little endian. A
+sidecar index file, the log path with ".idx" appended, has the offset of each
+record as a little endian uint64, so any record can be read without scanning.
+A crash while appending can leave a torn record at the end of the log. Open
+finds it by checking the records after the last one in the index, and
+truncates the log and index to the last complete record. For example:
+
+	l, err := logfile.Open("events.log", logfile.Options{Sync: true})
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	n, err := l.Append(bson.Map{"event": bson.String("login")})
+	...
+	bs, err := l.Read(n)
+*/
+package logfile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sbunce/bson"
+)
+
+const (
+	// maxDocLen is the largest document in a record.
+	maxDocLen = 64 * 1024 * 1024
+
+	crcSize   = 4
+	entrySize = 8
+)
+
+// castagnoli is the CRC32C table.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Options are options for Open.
+type Options struct {
+	// Sync the log and index to disk after each Append, so that a record is
+	// durable once Append returns. Otherwise records are durable after Sync or
+	// Close, and a crash may lose the ones before.
+	Sync bool
+}
+
+// Log is a append-only log file. It's safe for concurrent use.
+type Log struct {
+	opts Options
+
+	mu      sync.RWMutex
+	f       *os.File
+	idx     *os.File
+	offsets []int64 // Offset of each record.
+	size    int64   // Length of the complete records.
+	torn    int64
+}
+
+// Open opens the log at path, creating it and its index if they don't exist.
+// A torn record at the end of the log is truncated, see Torn. If the index is
+// missing or behind the log it's rebuilt from the records.
+func Open(path string, opts Options) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := os.OpenFile(path+".idx", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	l := &Log{opts: opts, f: f, idx: idx}
+	if err := l.recover(); err != nil {
+		f.Close()
+		idx.Close()
+		return nil, fmt.Errorf("Log %v, %w", path, err)
+	}
+	return l, nil
+}
+
+// recover reads the index, checks the records after the last one in it, and
+// truncates the log and index to the last complete record.
+func (this *Log) recover() error {
+	fi, err := this.f.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := fi.Size()
+	b, err := io.ReadAll(this.idx)
+	if err != nil {
+		return err
+	}
+	for i := 0; i+entrySize <= len(b); i += entrySize {
+		this.offsets = append(this.offsets,
+			int64(binary.LittleEndian.Uint64(b[i:])))
+	}
+
+	// Drop index entries which aren't complete records, such as entries
+	// written before the records they point to were synced.
+	for len(this.offsets) > 0 {
+		last := this.offsets[len(this.offsets)-1]
+		if n, err := this.check(last, fileSize); err != nil {
+			return err
+		} else if n > 0 {
+			this.size = last + n
+			break
+		}
+		this.offsets = this.offsets[:len(this.offsets)-1]
+	}
+	indexed := len(this.offsets)
+	for this.size < fileSize {
+		n, err := this.check(this.size, fileSize)
+		if err != nil {
+			return err
+		} else if n == 0 {
+			break
+		}
+		this.offsets = append(this.offsets, this.size)
+		this.size += n
+	}
+
+	this.torn = fileSize - this.size
+	if this.torn > 0 {
+		if err := this.f.Truncate(this.size); err != nil {
+			return err
+		}
+	}
+	if err := this.idx.Truncate(int64(indexed * entrySize)); err != nil {
+		return err
+	}
+	if _, err := this.idx.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	if indexed < len(this.offsets) {
+		if err := this.writeIndex(this.offsets[indexed:]); err != nil {
+			return err
+		}
+	}
+	if _, err := this.f.Seek(this.size, io.SeekStart); err != nil {
+		return err
+	}
+	if this.torn > 0 || indexed < len(this.offsets) {
+		return this.sync()
+	}
+	return nil
+}
+
+// check returns the length of the record at off if it's complete and its CRC
+// is correct, or 0 if not.
+func (this *Log) check(off, fileSize int64) (int64, error) {
+	var hdr [4]byte
+	if off < 0 || off+int64(len(hdr)) > fileSize {
+		return 0, nil
+	}
+	if _, err := this.f.ReadAt(hdr[:], off); err != nil {
+		return 0, err
+	}
+	docLen := int64(int32(binary.LittleEndian.Uint32(hdr[:])))
+	if docLen < 5 || docLen > maxDocLen || off+docLen+crcSize > fileSize {
+		return 0, nil
+	}
+	b := make([]byte, docLen+crcSize)
+	if _, err := this.f.ReadAt(b, off); err != nil {
+		return 0, err
+	}
+	doc, crc := b[:docLen], b[docLen:]
+	if doc[docLen-1] != 0x00 ||
+		crc32.Checksum(doc, castagnoli) != binary.LittleEndian.Uint32(crc) {
+
+		return 0, nil
+	}
+	return docLen + crcSize, nil
+}
+
+// Torn returns the number of bytes of a torn record which Open truncated, or
+// 0 if the log ended with a complete record.
+func (this *Log) Torn() int64 {
+	return this.torn
+}
+
+// Len returns the number of records.
+func (this *Log) Len() int64 {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	return int64(len(this.offsets))
+}
+
+// Append appends a document and returns its record number, starting at 0. If
+// it fails the log is left as it was.
+func (this *Log) Append(doc bson.Doc) (int64, error) {
+	bs, err := doc.Encode()
+	if err != nil {
+		return 0, err
+	}
+	if len(bs) > maxDocLen {
+		return 0, bson.ErrDocTooLarge
+	}
+	b := make([]byte, 0, len(bs)+crcSize)
+	b = append(b, bs...)
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(bs, castagnoli))
+
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.f == nil {
+		return 0, os.ErrClosed
+	}
+	off := this.size
+	if _, err := this.f.Write(b); err != nil {
+		this.rollback(off, int64(len(this.offsets)))
+		return 0, err
+	}
+	if err := this.writeIndex([]int64{off}); err != nil {
+		this.rollback(off, int64(len(this.offsets)))
+		return 0, err
+	}
+	if this.opts.Sync {
+		if err := this.sync(); err != nil {
+			this.rollback(off, int64(len(this.offsets)))
+			return 0, err
+		}
+	}
+	this.offsets = append(this.offsets, off)
+	this.size += int64(len(b))
+	return int64(len(this.offsets) - 1), nil
+}
+
+// rollback truncates the log to size and the index to n entries after a failed
+// Append. A error is ignored, Open will truncate what's left.
+func (this *Log) rollback(size, n int64) {
+	this.f.Truncate(size)
+	this.f.Seek(size, io.SeekStart)
+	this.idx.Truncate(n * entrySize)
+	this.idx.Seek(n*entrySize, io.SeekStart)
+}
+
+func (this *Log) writeIndex(offsets []int64) error {
+	b := make([]byte, 0, len(offsets)*entrySize)
+	for _, off := range offsets {
+		b = binary.LittleEndian.AppendUint64(b, uint64(off))
+	}
+	_, err := this.idx.Write(b)
+	return err
+}
+
+// Read returns the document of record n.
+func (this *Log) Read(n int64) (bson.BSON, error) {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+	if this.f == nil {
+		return nil, os.ErrClosed
+	}
+	if n < 0 || n >= int64(len(this.offsets)) {
+		return nil, fmt.Errorf("Record %v %w.", n, bson.ErrNotFound)
+	}
+	end := this.size
+	if n+1 < int64(len(this.offsets)) {
+		end = this.offsets[n+1]
+	}
+	b := make([]byte, end-this.offsets[n])
+	if _, err := this.f.ReadAt(b, this.offsets[n]); err != nil {
+		return nil, err
+	}
+	return bson.BSON(b[:len(b)-crcSize]), nil
+}
+
+// Sync syncs the log and index to disk.
+func (this *Log) Sync() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.f == nil {
+		return os.ErrClosed
+	}
+	return this.sync()
+}
+
+// sync syncs the log before the index, so the index doesn't point to records
+// which aren't on disk.
+func (this *Log) sync() error {
+	if err := this.f.Sync(); err != nil {
+		return err
+	}
+	return this.idx.Sync()
+}
+
+// Close syncs and closes the log and index.
+func (this *Log) Close() error {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.f == nil {
+		return errors.New("Log already closed.")
+	}
+	err := this.sync()
+	if cerr := this.f.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := this.idx.Close(); err == nil {
+		err = cerr
+	}
+	this.f, this.idx = nil, nil
+	return err
+}
@@ -0,0 +1,153 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package logfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbunce/bson"
+)
+
+func appendN(t *testing.T, l *Log, from, n int) {
+	for i := from; i < from+n; i++ {
+		got, err := l.Append(bson.Map{"i": bson.Int32(i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != int64(i) {
+			t.Fatalf("Expected record %v, got %v.", i, got)
+		}
+	}
+}
+
+func checkRecords(t *testing.T, l *Log, n int) {
+	if l.Len() != int64(n) {
+		t.Fatalf("Expected %v records, got %v.", n, l.Len())
+	}
+	for i := 0; i < n; i++ {
+		bs, err := l.Read(int64(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := bs.Map()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m["i"] != bson.Int32(i) {
+			t.Fatalf("record %v, got %v", i, m)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	l, err := Open(path, Options{Sync: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, l, 0, 10)
+	checkRecords(t, l, 10)
+	if _, err := l.Read(10); !errors.Is(err, bson.ErrNotFound) {
+		t.Fatalf("Expected not found, got %v.", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Torn() != 0 {
+		t.Fatalf("Expected no torn record, got %v bytes.", l.Torn())
+	}
+	checkRecords(t, l, 10)
+	appendN(t, l, 10, 5)
+	checkRecords(t, l, 15)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Append(bson.Map{}); err != os.ErrClosed {
+		t.Fatalf("Expected closed, got %v.", err)
+	}
+}
+
+func TestLogRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	l, err := Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, l, 0, 5)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Torn record, and a index entry for it.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs := bson.Map{"i": bson.Int32(5)}.MustEncode()
+	f.Write(bs[:len(bs)-3])
+	f.Close()
+	idx, err := os.OpenFile(path+".idx", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx.Write([]byte{byte(fi.Size()), byte(fi.Size() >> 8), 0, 0, 0, 0, 0, 0})
+	idx.Close()
+
+	l, err = Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Torn() != int64(len(bs)-3) {
+		t.Fatalf("Expected %v torn bytes, got %v.", len(bs)-3, l.Torn())
+	}
+	checkRecords(t, l, 5)
+	appendN(t, l, 5, 1)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Missing index is rebuilt.
+	if err := os.Remove(path + ".idx"); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRecords(t, l, 6)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt last record.
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[len(b)-1]++
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Open(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if l.Torn() == 0 {
+		t.Fatal("Expected torn record.")
+	}
+	checkRecords(t, l, 5)
+}
@@ -4,6 +4,7 @@
 package bson
 
 import (
+	"bytes"
 	"fmt"
 	"reflect"
 	"testing"
@@ -88,3 +89,273 @@ func TestMapNoNest(t *testing.T) {
 		t.Fatal(dst)
 	}
 }
+
+func TestMapDepth(t *testing.T) {
+	payload := Map{"body": Map{"x": Int32(1)}}
+	scope := Map{"y": Int32(2)}
+	src := Map{
+		"to": String("svc"),
+		"msg": Map{
+			"id":      Int32(7),
+			"payload": payload,
+			"js":      JavascriptScope{Javascript: "y", Scope: scope},
+		},
+		"list": Array{Map{"a": payload}},
+	}
+	bs := src.MustEncode()
+	exp := Map{
+		"to": String("svc"),
+		"msg": Map{
+			"id":      Int32(7),
+			"payload": payload.MustEncode(),
+			"js": JavascriptScope{Javascript: "y",
+				Scope: scope.MustEncode()},
+		},
+		"list": Array{Map{"a": payload.MustEncode()}},
+	}
+	dst, err := bs.MapDepth(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, exp) {
+		t.Fatal(dst)
+	}
+	if dst, err = bs.MapDepth(0); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatal(dst)
+	}
+
+	dec := NewDecoder(bytes.NewReader(bs))
+	dec.NestDepth = 1
+	s, err := dec.DecodeSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg BSON
+	if ok, err := s.Reach(&msg, "msg"); !ok || err != nil {
+		t.Fatal(s, err)
+	}
+}
+
+func TestMapDecodeInto(t *testing.T) {
+	dst := Map{}
+	nested := Map{"stale": Bool(true)}
+	dst["nest"] = nested
+	dst["gone"] = String("x")
+	src := Map{
+		"foo":  String("bar"),
+		"nest": Map{"a": Int32(1)},
+	}
+	bs := src.MustEncode()
+	if err := bs.DecodeInto(dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatal(dst)
+	}
+
+	// The nested Map was reused.
+	nested["b"] = Int32(2)
+	if _, ok := dst["nest"].(Map)["b"]; !ok {
+		t.Fatal("nested Map not reused")
+	}
+
+	// Invalid BSON.
+	if err := BSON([]byte{1, 2}).DecodeInto(dst); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
+
+func TestMapSortedSlice(t *testing.T) {
+	src := Map{
+		"c": Int32(1),
+		"a": Map{"z": Int32(2), "y": Int32(3)},
+		"b": Array{Map{"x": Int32(4), "w": Int32(5)}},
+	}
+	exp := Slice{
+		{"a", Slice{{"y", Int32(3)}, {"z", Int32(2)}}},
+		{"b", Array{Slice{{"w", Int32(5)}, {"x", Int32(4)}}}},
+		{"c", Int32(1)},
+	}
+	if dst := src.SortedSlice(); !reflect.DeepEqual(dst, exp) {
+		t.Fatal(dst)
+	}
+}
+
+func TestMapSet(t *testing.T) {
+	m := Map{"slice": Slice{{"a", Int32(1)}}, "val": Int32(2)}
+	if err := m.Set(String("foo"), "nest", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(Int32(3), "slice", "b", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set(Int32(4), "slice", "a"); err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{
+		"nest":  Map{"bar": String("foo")},
+		"slice": Slice{{"a", Int32(4)}, {"b", Map{"c": Int32(3)}}},
+		"val":   Int32(2),
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+	if err := m.Set(Int32(5), "val", "foo"); err == nil {
+		t.Fatal("Expected error setting in Int32.")
+	}
+}
+
+func TestMapHasDelete(t *testing.T) {
+	m := Map{
+		"null":  Null{},
+		"slice": Slice{{"a", Int32(1)}, {"b", Int32(2)}},
+	}
+	if !m.Has("null") || !m.Has("slice", "b") || m.Has("slice", "c") {
+		t.Fatal(m)
+	}
+	if !m.Delete("slice", "a") || m.Delete("slice", "a") || !m.Delete("null") {
+		t.Fatal(m)
+	}
+	exp := Map{"slice": Slice{{"b", Int32(2)}}}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+}
+
+func TestMapReachDotted(t *testing.T) {
+	m := Map{
+		"foo": Slice{{"bar", Map{"baz": Int32(1)}}},
+		"a.b": Map{"c\\d": String("x")},
+	}
+	var i int
+	if ok, err := m.Reach(&i, "foo.bar.baz"); !ok || err != nil || i != 1 {
+		t.Fatal(ok, err, i)
+	}
+	i = 0
+	if ok, err := m.Reach(&i, "foo.bar", "baz"); !ok || err != nil || i != 1 {
+		t.Fatal(ok, err, i)
+	}
+	var s string
+	if ok, err := m.Reach(&s, `a\.b.c\\d`); !ok || err != nil || s != "x" {
+		t.Fatal(ok, err, s)
+	}
+	if m.Has("a.b") || !m.Has(`a\.b`) {
+		t.Fatal(m)
+	}
+}
+
+func TestMapReachArray(t *testing.T) {
+	m := Map{
+		"items": Array{
+			Map{"price": Int32(1)},
+			Slice{{"price", Int32(2)}},
+			Array{Int32(3)},
+		},
+	}
+	var i int
+	if ok, err := m.Reach(&i, "items", "1", "price"); !ok || err != nil || i != 2 {
+		t.Fatal(ok, err, i)
+	}
+	if ok, err := m.Reach(&i, "items.2.0"); !ok || err != nil || i != 3 {
+		t.Fatal(ok, err, i)
+	}
+	for _, path := range []string{"items.3", "items.-1", "items.x", "items.0.price.x"} {
+		if m.Has(path) {
+			t.Fatal(path)
+		}
+	}
+}
+
+func TestTypeAt(t *testing.T) {
+	m := Map{
+		"null": Null{},
+		"nest": Map{"a": Array{Int32(1)}, "b": int64(2)},
+		"r":    Regexp{Pattern: "p"},
+	}
+	tests := []struct {
+		dot []string
+		exp Type
+		ok  bool
+	}{
+		{[]string{"null"}, TypeNull, true},
+		{[]string{"nest"}, TypeEmbeddedDocument, true},
+		{[]string{"nest", "a"}, TypeArray, true},
+		{[]string{"nest.a.0"}, TypeInt32, true},
+		{[]string{"nest", "b"}, TypeInt64, true},
+		{[]string{"r", "Pattern"}, TypeString, true},
+		{[]string{"missing"}, 0, false},
+	}
+	docs := []interface {
+		Exists(...string) bool
+		TypeAt(...string) (Type, bool)
+	}{m, m.MustEncode()}
+	for _, doc := range docs {
+		for _, test := range tests {
+			typ, ok := doc.TypeAt(test.dot...)
+			if typ != test.exp || ok != test.ok {
+				t.Fatal(test.dot, typ, ok)
+			}
+			if doc.Exists(test.dot...) != test.ok {
+				t.Fatal(test.dot)
+			}
+		}
+	}
+	if TypeInt32.String() != "Int32" || Type(0x42).String() != "Type(0x42)" {
+		t.Fatal(TypeInt32, Type(0x42))
+	}
+}
+
+func TestMapNativeMaps(t *testing.T) {
+	type key string
+	src := Map{
+		"a": map[string]string{"x": "y"},
+		"b": map[string]interface{}{"c": map[key]int64{"d": 1}},
+		"e": map[string]int(nil),
+	}
+	bs, err := src.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{
+		"a": Map{"x": String("y")},
+		"b": Map{"c": Map{"d": Int64(1)}},
+		"e": Map{},
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+	if _, err := (Map{"a": map[int]string{1: "x"}}).Encode(); err == nil {
+		t.Fatal("Expected error for map without string keys.")
+	}
+}
+
+func TestMapFixedArrays(t *testing.T) {
+	src := Map{
+		"a": [4]byte{1, 2, 3, 4},
+		"b": [3]float64{1, 2, 3},
+		"c": [0]int32{},
+	}
+	bs, err := src.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{
+		"a": Binary{1, 2, 3, 4},
+		"b": Array{Float(1), Float(2), Float(3)},
+		"c": Array{},
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+}
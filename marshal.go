@@ -0,0 +1,80 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+)
+
+// Marshaler is implemented by types which encode themselves to a document.
+// MarshalBSON must return a complete BSON document.
+type Marshaler interface {
+	MarshalBSON() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types which decode themselves from a document.
+// The BSON must be copied if it's kept after UnmarshalBSON returns. For a Null
+// or Undefined value UnmarshalBSON isn't called and the value is left as is.
+type Unmarshaler interface {
+	UnmarshalBSON([]byte) error
+}
+
+// ValueMarshaler is implemented by types which encode themselves to a single
+// value rather than a document. MarshalBSONValue may return any value which can
+// be encoded, usually a BSON type.
+type ValueMarshaler interface {
+	MarshalBSONValue() (interface{}, error)
+}
+
+// ValueUnmarshaler is implemented by types which decode themselves from a
+// single value. The value is a BSON type, embedded documents are BSON.
+type ValueUnmarshaler interface {
+	UnmarshalBSONValue(interface{}) error
+}
+
+// marshalBSON calls MarshalBSON and checks the result is a document.
+func marshalBSON(path string, m Marshaler) (BSON, error) {
+	b, err := m.MarshalBSON()
+	if err != nil {
+		return nil, prefixEncodeError(path, err)
+	}
+	if n, err := rawDocLen(b, 0); err != nil || n != len(b) {
+		return nil, encodeErrorf(path, "MarshalBSON returned invalid document.")
+	}
+	return b, nil
+}
+
+// unmarshal calls UnmarshalBSON or UnmarshalBSONValue if dst implements them.
+// Returns false if it doesn't.
+func unmarshal(path string, src interface{}, dst reflect.Value) (bool, error) {
+	if !dst.CanAddr() {
+		return false, nil
+	}
+	switch u := dst.Addr().Interface().(type) {
+	case Unmarshaler:
+		switch src.(type) {
+		case Null, Undefined:
+			// Left as is, the same as other types.
+			return true, nil
+		}
+		doc, ok := src.(Doc)
+		if !ok {
+			return true, decodeErrorf(path, "cannot unmarshal %T.", src)
+		}
+		bs, err := doc.Encode()
+		if err == nil {
+			err = u.UnmarshalBSON(bs)
+		}
+		if err != nil {
+			return true, prefixDecodeError(path, err)
+		}
+		return true, nil
+	case ValueUnmarshaler:
+		if err := u.UnmarshalBSONValue(src); err != nil {
+			return true, prefixDecodeError(path, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// point marshals itself as a document with x and y swapped.
+type point struct {
+	X, Y int32
+}
+
+func (this point) MarshalBSON() ([]byte, error) {
+	return Slice{{"y", Int32(this.Y)}, {"x", Int32(this.X)}}.Encode()
+}
+
+func (this *point) UnmarshalBSON(b []byte) error {
+	m, err := BSON(b).Map()
+	if err != nil {
+		return err
+	}
+	x, ok0 := m["x"].(Int32)
+	y, ok1 := m["y"].(Int32)
+	if !ok0 || !ok1 {
+		return errors.New("point needs x and y.")
+	}
+	this.X, this.Y = int32(x), int32(y)
+	return nil
+}
+
+// upper marshals itself as a upper case String.
+type upper string
+
+func (this upper) MarshalBSONValue() (interface{}, error) {
+	return String(strings.ToUpper(string(this))), nil
+}
+
+func (this *upper) UnmarshalBSONValue(v interface{}) error {
+	s, ok := v.(String)
+	if !ok {
+		return errors.New("upper must be String.")
+	}
+	*this = upper(strings.ToLower(string(s)))
+	return nil
+}
+
+func TestMarshaler(t *testing.T) {
+	type shape struct {
+		Origin point
+		Points []point
+		Ptr    *point
+		Name   upper
+	}
+	src := shape{Origin: point{1, 2}, Points: []point{{3, 4}}, Ptr: &point{5, 6},
+		Name: "foo"}
+	bs, err := EncodeStruct(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Slice{
+		{"Origin", Slice{{"y", Int32(2)}, {"x", Int32(1)}}},
+		{"Points", Array{Slice{{"y", Int32(4)}, {"x", Int32(3)}}}},
+		{"Ptr", Slice{{"y", Int32(6)}, {"x", Int32(5)}}},
+		{"Name", String("FOO")},
+	}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+	var dst shape
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatal(dst, src)
+	}
+
+	// Top level.
+	if bs, err = EncodeStruct(point{7, 8}); err != nil {
+		t.Fatal(err)
+	}
+	var p point
+	if err := DecodeStruct(bs, &p); err != nil || p != (point{7, 8}) {
+		t.Fatal(err, p)
+	}
+
+	// Errors from the methods.
+	bs = Map{"Origin": Map{"x": Int32(1)}}.MustEncode()
+	if err := DecodeStruct(bs, &dst); err == nil {
+		t.Fatal("Expected UnmarshalBSON error.")
+	}
+	bs = Map{"Name": Int32(1)}.MustEncode()
+	if err := DecodeStruct(bs, &dst); err == nil {
+		t.Fatal("Expected UnmarshalBSONValue error.")
+	}
+}
+
+// Null and Undefined leave a Unmarshaler as is, the same as other types,
+// rather than passing it something which isn't a document.
+func TestUnmarshalerNull(t *testing.T) {
+	var dst struct {
+		A point
+		B point
+	}
+	dst.A, dst.B = point{1, 2}, point{3, 4}
+	bs := Map{"A": Null{}, "B": Undefined{}}.MustEncode()
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.A != (point{1, 2}) || dst.B != (point{3, 4}) {
+		t.Fatal(dst)
+	}
+
+	// Other values which aren't documents are a error.
+	bs = Map{"A": Int32(1)}.MustEncode()
+	if err := DecodeStruct(bs, &dst); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
+
+// badMarshaler returns a invalid document.
+type badMarshaler struct{}
+
+func (this badMarshaler) MarshalBSON() ([]byte, error) {
+	return []byte{0x01}, nil
+}
+
+func TestMarshalerInvalid(t *testing.T) {
+	if _, err := (Map{"a": badMarshaler{}}).Encode(); err == nil {
+		t.Fatal("Expected invalid document error.")
+	}
+}
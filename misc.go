@@ -4,20 +4,10 @@
 package bson
 
 import (
-	"bytes"
-	"crypto/md5"
-	"encoding/binary"
-	"os"
 	"reflect"
 	"strings"
-	"sync/atomic"
-	"time"
 )
 
-// lastCount is used to get a incrementing value for a ObjectId. This must only
-// be incremented atomically.
-var lastCount int32
-
 // catpath concatenates a name on to a document path. This is used to keep track
 // of where we are in a document for the purpose of generating descriptive
 // errors.
@@ -28,6 +18,75 @@ func catpath(path, name string) string {
 	return strings.Join([]string{path, name}, ".")
 }
 
+// structTag returns the document key and tag options for a struct field.
+// Returns false if the field is unexported or ignored.
+func structTag(sv reflect.StructField) (string, []string, bool) {
+	if sv.PkgPath != "" {
+		// Unexported field.
+		return "", nil, false
+	}
+	tag := sv.Tag.Get("bson")
+	if tag == "" {
+		return sv.Name, nil, true
+	}
+	tok := strings.Split(tag, ",")
+	if tok[0] == "-" {
+		// Ignore field.
+		return "", nil, false
+	}
+	name := sv.Name
+	if tok[0] != "" {
+		// Renamed field.
+		name = tok[0]
+	}
+	return name, tok[1:], true
+}
+
+// hasOpt returns true if the struct tag options contain opt.
+func hasOpt(opts []string, opt string) bool {
+	for _, o := range opts {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// optValue returns the value of a struct tag option of the form opt=value.
+// Returns false if there's no such option.
+func optValue(opts []string, opt string) (string, bool) {
+	for _, o := range opts {
+		if strings.HasPrefix(o, opt+"=") {
+			return o[len(opt)+1:], true
+		}
+	}
+	return "", false
+}
+
+// splitPath splits each path component on dots which aren't escaped with a
+// backslash, and removes the escapes. See EscapeKey.
+func splitPath(dot []string) []string {
+	var dst []string
+	for _, s := range dot {
+		if strings.IndexAny(s, ".\\") == -1 {
+			dst = append(dst, s)
+			continue
+		}
+		start := 0
+		for i := 0; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '.':
+				dst = append(dst, UnescapeKey(s[start:i]))
+				start = i + 1
+			}
+		}
+		dst = append(dst, UnescapeKey(s[start:]))
+	}
+	return dst
+}
+
 // indirect all interfaces/pointers.
 func indirect(v reflect.Value) reflect.Value {
 loop:
@@ -75,51 +134,3 @@ loop:
 	}
 	return v
 }
-
-// Create unique incrementing ObjectId.
-//
-//   +---+---+---+---+---+---+---+---+---+---+---+---+
-//   |       A       |     B     |   C   |     D     |
-//   +---+---+---+---+---+---+---+---+---+---+---+---+
-//     0   1   2   3   4   5   6   7   8   9  10  11
-//   A = unix time (big endian), B = machine ID (first 3 bytes of md5 host name),
-//   C = PID, D = incrementing counter (big endian)
-func NewObjectId() (ObjectId, error) {
-	buf := bytes.NewBuffer(make([]byte, 0, 12))
-
-	// A, unix time (big endian).
-	if err := binary.Write(buf, binary.BigEndian, int32(time.Now().Unix()));
-		err != nil {
-
-		return nil, err
-	}
-
-	// B, machine Id hash.
-	name, err := os.Hostname()
-	if err != nil {
-		return nil, err
-	}
-	hash := md5.New()
-	if _, err := hash.Write([]byte(name)); err != nil {
-		return nil, err
-	}
-	if _, err := buf.Write(hash.Sum(nil)[:3]); err != nil {
-		return nil, err
-	}
-
-	// C, PID (process Id).
-	if err := binary.Write(buf, binary.BigEndian, int16(os.Getpid()));
-		err != nil {
-
-		return nil, err
-	}
-
-	// D, incrementing counter.
-	cnt := atomic.AddInt32(&lastCount, 1) % 16777215
-	cntbuf := make([]byte, 4)
-	binary.BigEndian.PutUint32(cntbuf, uint32(cnt))
-	if _, err := buf.Write(cntbuf[1:]); err != nil {
-		return nil, err
-	}
-	return ObjectId(buf.Bytes()), nil
-}
@@ -5,27 +5,10 @@ package bson
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 )
 
-func TestNewObjectId(t *testing.T) {
-	oid0, err := NewObjectId()
-	if err != nil {
-		t.Fatal(err)
-	}
-	if len(oid0) != 12 {
-		t.Fatal(len(oid0))
-	}
-	oid1, err := NewObjectId()
-	if err != nil {
-		t.Fatal(err)
-	}
-	// ObjectIds should be increasing.
-	if bytes.Compare(oid0, oid1) >= 0 {
-		t.Fatal()
-	}
-}
-
 func TestReadOne(t *testing.T) {
 	foo := Map{"abc": "cba"}
 	bar := Map{"123": "321"}
@@ -47,3 +30,120 @@ func TestReadOne(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+func TestReadOneBuf(t *testing.T) {
+	small := Map{"a": String("b")}.MustEncode()
+	large := Map{"a": String("bbbbbbbbbbbbbbbbbbbbbbbb")}.MustEncode()
+	rd := bytes.NewBuffer(nil)
+	rd.Write(small)
+	rd.Write(large)
+	rd.Write(small)
+	buf := make([]byte, 0, len(small))
+	for _, exp := range []BSON{small, large, small} {
+		bs, err := ReadOneBuf(rd, buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(bs, exp) {
+			t.Fatal(bs, exp)
+		}
+		buf = bs
+	}
+	if cap(buf) != len(large) {
+		t.Fatal("buffer not reused", cap(buf))
+	}
+
+	// Too small.
+	if _, err := ReadOneBuf(bytes.NewBuffer([]byte{4, 0, 0, 0}), buf); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
+
+func TestNewBinary(t *testing.T) {
+	bin, err := NewBinary([]byte{0x00, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(bin, []byte{0x00, 0x01}) != 0 {
+		t.Fatal(bin)
+	}
+	if _, err := NewBinary(make([]byte, maxDocLen+1)); err == nil {
+		t.Fatal("Expected error for oversized Binary.")
+	}
+}
+
+func TestNewRegexp(t *testing.T) {
+	re, err := NewRegexp("foo", "xi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.Pattern != "foo" || re.Options != "ix" {
+		t.Fatal(re)
+	}
+	if _, err := NewRegexp("foo", "g"); err == nil {
+		t.Fatal("Expected error for option 'g'.")
+	}
+	if _, err := NewRegexp("fo\x00o", ""); err == nil {
+		t.Fatal("Expected error for null byte in pattern.")
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		dot []string
+		exp []string
+	}{
+		{[]string{"a"}, []string{"a"}},
+		{[]string{"a.b", "c"}, []string{"a", "b", "c"}},
+		{[]string{`a\.b`}, []string{"a.b"}},
+		{[]string{`a\\.b`}, []string{`a\`, "b"}},
+		{[]string{"a..b"}, []string{"a", "", "b"}},
+	}
+	for _, test := range tests {
+		if dst := splitPath(test.dot); !reflect.DeepEqual(dst, test.exp) {
+			t.Fatal(test.dot, dst)
+		}
+	}
+}
+
+func TestEscapeKey(t *testing.T) {
+	tests := []struct {
+		key string
+		exp string
+	}{
+		{"a", "a"},
+		{"a.b", `a\.b`},
+		{`a\b`, `a\\b`},
+		{"$a$", "$a$"},
+	}
+	for _, test := range tests {
+		if dst := EscapeKey(test.key); dst != test.exp {
+			t.Fatal(test.key, dst)
+		}
+		if dst := UnescapeKey(test.exp); dst != test.key {
+			t.Fatal(test.exp, dst)
+		}
+		if dst := splitPath([]string{test.exp}); len(dst) != 1 ||
+			dst[0] != test.key {
+
+			t.Fatal(test.exp, dst)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	ts := NewTimestamp(0x80000001, 0xFFFFFFFE)
+	if ts.T() != 0x80000001 || ts.I() != 0xFFFFFFFE {
+		t.Fatal(ts.T(), ts.I())
+	}
+	if ts.Time().Unix() != 0x80000001 {
+		t.Fatal(ts.Time())
+	}
+	m, err := Map{"ts": ts}.MustEncode().Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["ts"] != ts {
+		t.Fatal(m)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MappedFile reads a file of documents, one after the other, by mapping it in
+// to memory. Documents are slices of the mapping rather than copies, so
+// multi-GB files can be scanned without reading them in to the heap. On
+// systems without mmap the file is read in to memory instead. For example:
+//   mf, err := bson.OpenMapped("dump/db/coll.bson")
+//   if err != nil {
+//       return err
+//   }
+//   defer mf.Close()
+//   for mf.Scan() {
+//       var a bson.String
+//       ok, err := mf.Bytes().Reach(&a, "a")
+//       ...
+//   }
+//   if err := mf.Err(); err != nil {
+//       return err
+//   }
+// Documents are read-only, writing to them may crash the program, and they
+// must not be used after Close.
+type MappedFile struct {
+	data  []byte
+	unmap func() error
+	off   int // Offset of the next document.
+	bs    BSON
+	err   error
+}
+
+// OpenMapped maps the file at path in to memory.
+func OpenMapped(path string) (*MappedFile, error) {
+	data, unmap, err := mmapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &MappedFile{data: data, unmap: unmap}, nil
+}
+
+// Len returns the length of the file.
+func (this *MappedFile) Len() int64 {
+	return int64(len(this.data))
+}
+
+// Scan moves to the next document, which is available from Bytes. Returns
+// false at the end of the file or on error, see Err.
+func (this *MappedFile) Scan() bool {
+	if this.err != nil {
+		return false
+	}
+	if this.off == len(this.data) {
+		this.bs = nil
+		this.err = io.EOF
+		return false
+	}
+	bs, err := this.At(int64(this.off))
+	if err != nil {
+		this.bs = nil
+		this.err = err
+		return false
+	}
+	this.bs = bs
+	this.off += len(bs)
+	return true
+}
+
+// Bytes returns the document Scan moved to.
+func (this *MappedFile) Bytes() BSON {
+	return this.bs
+}
+
+// Offset returns the offset of the document Scan moved to.
+func (this *MappedFile) Offset() int64 {
+	return int64(this.off - len(this.bs))
+}
+
+// Err returns the error which stopped Scan, or nil if it was the end of the
+// file.
+func (this *MappedFile) Err() error {
+	if this.err == io.EOF {
+		return nil
+	}
+	return this.err
+}
+
+// At returns the document at a offset in the file, such as one returned by
+// Offset. It doesn't change the position of Scan.
+func (this *MappedFile) At(off int64) (BSON, error) {
+	if this.unmap == nil {
+		return nil, errors.New("MappedFile closed.")
+	}
+	if off < 0 || off >= int64(len(this.data)) {
+		return nil, fmt.Errorf("Offset %v out of range.", off)
+	}
+	docLen, err := rawDocLen(this.data, int(off))
+	if err != nil {
+		return nil, fmt.Errorf("Doc at offset %v, %w", off, err)
+	}
+	end := int(off) + docLen
+	return BSON(this.data[off:end:end]), nil
+}
+
+// Close unmaps the file.
+func (this *MappedFile) Close() error {
+	if this.unmap == nil {
+		return errors.New("MappedFile already closed.")
+	}
+	err := this.unmap()
+	this.data, this.unmap, this.bs = nil, nil, nil
+	return err
+}
@@ -0,0 +1,19 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package bson
+
+import (
+	"os"
+)
+
+// mmapFile reads the file at path in to memory, on systems without mmap.
+func mmapFile(path string) ([]byte, func() error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMappedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.bson")
+	var want []BSON
+	var b []byte
+	for i := 0; i < 10; i++ {
+		bs := Map{"i": Int32(i)}.MustEncode()
+		want = append(want, bs)
+		b = append(b, bs...)
+	}
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mf, err := OpenMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Len() != int64(len(b)) {
+		t.Fatalf("Expected len %v, got %v.", len(b), mf.Len())
+	}
+	var offsets []int64
+	for i := 0; mf.Scan(); i++ {
+		if !bytes.Equal(mf.Bytes(), want[i]) {
+			t.Fatalf("Expected %v, got %v, doc %v.", want[i], mf.Bytes(), i)
+		}
+		offsets = append(offsets, mf.Offset())
+	}
+	if err := mf.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != len(want) {
+		t.Fatalf("Expected %v docs, got %v.", len(want), len(offsets))
+	}
+	bs, err := mf.At(offsets[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bs, want[3]) {
+		t.Fatalf("Expected %v, got %v.", want[3], bs)
+	}
+	if cap(bs) != len(bs) {
+		t.Fatal("doc capacity extends past end")
+	}
+	if _, err := mf.At(1); err == nil {
+		t.Fatal("Expected error at offset which isn't a document.")
+	}
+	if err := mf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mf.At(0); err == nil {
+		t.Fatal("Expected error after close.")
+	}
+}
+
+func TestMappedFileTruncated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "empty.bson")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mf, err := OpenMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf.Scan() || mf.Err() != nil {
+		t.Fatalf("Expected end of empty file, got %v.", mf.Err())
+	}
+	mf.Close()
+
+	path = filepath.Join(dir, "truncated.bson")
+	bs := Map{"a": Int32(1)}.MustEncode()
+	b := append(append([]byte(nil), bs...), bs[:len(bs)-1]...)
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mf, err = OpenMapped(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mf.Close()
+	if !mf.Scan() {
+		t.Fatal(mf.Err())
+	}
+	if mf.Scan() || !errors.Is(mf.Err(), ErrTruncated) {
+		t.Fatalf("Expected truncated, got %v.", mf.Err())
+	}
+}
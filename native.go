@@ -0,0 +1,146 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+// Native converts the BSON to a map with standard Go types, for libraries
+// which don't know about the BSON types (e.g. text/template). The types are:
+//   Float       -> float64
+//   String      -> string
+//   Map, Slice  -> map[string]interface{}
+//   Array       -> []interface{}
+//   Binary      -> []byte
+//   Undefined   -> nil
+//   ObjectId    -> string (hex)
+//   Bool        -> bool
+//   UTCDateTime -> time.Time
+//   Null        -> nil
+//   Javascript  -> string
+//   Symbol      -> string
+//   Int32       -> int32
+//   Int64       -> int64
+// The other types have no standard Go type, they're converted to a
+// map[string]interface{} which is the same as their canonical Extended JSON:
+//   Regexp          -> {"$regularExpression": {"pattern": "", "options": ""}}
+//   DBPointer       -> {"$dbPointer": {"$ref": "", "$id": "" (hex)}}
+//   JavascriptScope -> {"$code": "", "$scope": map[string]interface{}}
+//   Timestamp       -> {"$timestamp": {"t": uint32, "i": uint32}}
+//   MinKey          -> {"$minKey": 1}
+//   MaxKey          -> {"$maxKey": 1}
+// The order of a Slice is lost.
+func (this BSON) Native() (map[string]interface{}, error) {
+	m, err := this.MapNoNest()
+	if err != nil {
+		return nil, err
+	}
+	return nativeMap(m)
+}
+
+// Native converts the Map to standard Go types, the same as BSON Native.
+func (this Map) Native() (map[string]interface{}, error) {
+	return nativeMap(this)
+}
+
+// Native converts the Slice to standard Go types, the same as BSON Native.
+func (this Slice) Native() (map[string]interface{}, error) {
+	dst := make(map[string]interface{}, len(this))
+	for _, pair := range this {
+		v, err := native(pair.Val)
+		if err != nil {
+			return nil, err
+		}
+		dst[pair.Key] = v
+	}
+	return dst, nil
+}
+
+// Native converts the Array to standard Go types, the same as BSON Native.
+func (this Array) Native() ([]interface{}, error) {
+	dst := make([]interface{}, len(this))
+	for i, e := range this {
+		v, err := native(e)
+		if err != nil {
+			return nil, err
+		}
+		dst[i] = v
+	}
+	return dst, nil
+}
+
+func nativeMap(m Map) (map[string]interface{}, error) {
+	dst := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		nv, err := native(v)
+		if err != nil {
+			return nil, err
+		}
+		dst[k] = nv
+	}
+	return dst, nil
+}
+
+// native converts a value to a standard Go type.
+func native(v interface{}) (interface{}, error) {
+	switch vt := v.(type) {
+	case Float:
+		return float64(vt), nil
+	case String:
+		return string(vt), nil
+	case Map:
+		return vt.Native()
+	case Slice:
+		return vt.Native()
+	case BSON:
+		return vt.Native()
+	case Array:
+		return vt.Native()
+	case Binary:
+		return []byte(vt), nil
+	case Undefined, Null:
+		return nil, nil
+	case ObjectId:
+		return vt.Hex(), nil
+	case Bool:
+		return bool(vt), nil
+	case UTCDateTime:
+		return vt.Time(), nil
+	case Javascript:
+		return string(vt), nil
+	case Symbol:
+		return string(vt), nil
+	case Int32:
+		return int32(vt), nil
+	case Int64:
+		return int64(vt), nil
+	case Regexp:
+		return map[string]interface{}{
+			"$regularExpression": map[string]interface{}{
+				"pattern": vt.Pattern,
+				"options": vt.Options,
+			},
+		}, nil
+	case DBPointer:
+		return map[string]interface{}{
+			"$dbPointer": map[string]interface{}{
+				"$ref": vt.Name,
+				"$id":  vt.ObjectId.Hex(),
+			},
+		}, nil
+	case JavascriptScope:
+		scope, err := native(vt.Scope)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$code": vt.Javascript, "$scope": scope},
+			nil
+	case Timestamp:
+		return map[string]interface{}{
+			"$timestamp": map[string]interface{}{"t": vt.T(), "i": vt.I()},
+		}, nil
+	case MinKey:
+		return map[string]interface{}{"$minKey": 1}, nil
+	case MaxKey:
+		return map[string]interface{}{"$maxKey": 1}, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNative(t *testing.T) {
+	oid := ObjectId{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7, 0x99, 0x43,
+		0x90, 0x11}
+	src := Map{
+		"float":  Float(1.5),
+		"string": String("a"),
+		"nest":   Slice{{"b", Array{Int32(1), Map{"c": Null{}}}}},
+		"bin":    Binary{0x01},
+		"oid":    oid,
+		"bool":   Bool(true),
+		"date":   UTCDateTime(1000),
+		"sym":    Symbol("s"),
+		"int64":  Int64(2),
+		"ts":     NewTimestamp(1, 2),
+		"re":     Regexp{Pattern: "^a", Options: "i"},
+		"ptr":    DBPointer{Name: "c", ObjectId: oid},
+		"code":   JavascriptScope{Javascript: "x", Scope: Map{"y": Int32(1)}},
+		"min":    MinKey{},
+		"max":    MaxKey{},
+	}
+	exp := map[string]interface{}{
+		"float":  1.5,
+		"string": "a",
+		"nest": map[string]interface{}{
+			"b": []interface{}{int32(1), map[string]interface{}{"c": nil}},
+		},
+		"bin":   []byte{0x01},
+		"oid":   "507f1f77bcf86cd799439011",
+		"bool":  true,
+		"date":  time.Unix(1, 0).UTC(),
+		"sym":   "s",
+		"int64": int64(2),
+		"ts": map[string]interface{}{
+			"$timestamp": map[string]interface{}{"t": uint32(1), "i": uint32(2)},
+		},
+		"re": map[string]interface{}{
+			"$regularExpression": map[string]interface{}{
+				"pattern": "^a",
+				"options": "i",
+			},
+		},
+		"ptr": map[string]interface{}{
+			"$dbPointer": map[string]interface{}{
+				"$ref": "c",
+				"$id":  "507f1f77bcf86cd799439011",
+			},
+		},
+		"code": map[string]interface{}{
+			"$code":  "x",
+			"$scope": map[string]interface{}{"y": int32(1)},
+		},
+		"min": map[string]interface{}{"$minKey": 1},
+		"max": map[string]interface{}{"$maxKey": 1},
+	}
+	bs := src.MustEncode()
+	dst, err := bs.Native()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, exp) {
+		t.Fatal(dst, exp)
+	}
+	if dst, err = src.Native(); err != nil || !reflect.DeepEqual(dst, exp) {
+		t.Fatal(err, dst)
+	}
+
+	// The result can go through encoding/json.
+	if _, err := json.Marshal(dst); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(bytes.NewReader(bs))
+	if dst, err = dec.DecodeNative(); err != nil || !reflect.DeepEqual(dst, exp) {
+		t.Fatal(err, dst)
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lastCount is used to get a incrementing value for a legacy ObjectId. This
+// must only be incremented atomically.
+var lastCount int32
+
+var (
+	// processUnique is random and generated once per process.
+	processUnique     [5]byte
+	processUniqueErr  error
+	processUniqueOnce sync.Once
+
+	// randomCount is the counter for ObjectIds. It starts at a random value.
+	// This must only be incremented atomically.
+	randomCount uint32
+)
+
+// initProcessUnique generates the per-process random value and the initial
+// counter value.
+func initProcessUnique() {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		processUniqueErr = err
+		return
+	}
+	copy(processUnique[:], b[:5])
+	randomCount = uint32(b[5])<<16 | uint32(b[6])<<8 | uint32(b[7])
+}
+
+// ObjectIdGenerator generates ObjectIds for NewObjectId.
+type ObjectIdGenerator interface {
+	NewObjectId() (ObjectId, error)
+}
+
+// ObjectIdGeneratorFunc is a func which is a ObjectIdGenerator.
+type ObjectIdGeneratorFunc func() (ObjectId, error)
+
+// NewObjectId calls the func.
+func (this ObjectIdGeneratorFunc) NewObjectId() (ObjectId, error) {
+	return this()
+}
+
+// The ObjectIdGenerators provided by this package. RandomObjectIds is the
+// default.
+var (
+	RandomObjectIds ObjectIdGenerator = ObjectIdGeneratorFunc(newRandomObjectId)
+	LegacyObjectIds ObjectIdGenerator = ObjectIdGeneratorFunc(NewLegacyObjectId)
+)
+
+var (
+	// objectIdGenerator is used by NewObjectId.
+	objectIdGenerator    = RandomObjectIds
+	objectIdGeneratorMux sync.RWMutex
+)
+
+// SetObjectIdGenerator sets the generator used by NewObjectId, and by anything
+// which calls it such as the AssignObjectId hook. Returns the previous
+// generator so that it can be restored. For example, in a test which needs
+// predictable ObjectIds:
+//   defer SetObjectIdGenerator(SetObjectIdGenerator(myGen))
+func SetObjectIdGenerator(gen ObjectIdGenerator) ObjectIdGenerator {
+	objectIdGeneratorMux.Lock()
+	defer objectIdGeneratorMux.Unlock()
+	prev := objectIdGenerator
+	objectIdGenerator = gen
+	return prev
+}
+
+// NewObjectId returns a new ObjectId from the generator set with
+// SetObjectIdGenerator. By default this is RandomObjectIds.
+func NewObjectId() (ObjectId, error) {
+	objectIdGeneratorMux.RLock()
+	gen := objectIdGenerator
+	objectIdGeneratorMux.RUnlock()
+	return gen.NewObjectId()
+}
+
+// Create unique incrementing ObjectId. This is the layout in the current
+// ObjectId spec, which doesn't depend on the host name or PID. Those are often
+// the same across containers.
+//
+//   +---+---+---+---+---+---+---+---+---+---+---+---+
+//   |       A       |         B         |     C     |
+//   +---+---+---+---+---+---+---+---+---+---+---+---+
+//     0   1   2   3   4   5   6   7   8   9  10  11
+//   A = unix time (big endian), B = random value generated once per process,
+//   C = counter starting at a random value (big endian)
+func newRandomObjectId() (ObjectId, error) {
+	processUniqueOnce.Do(initProcessUnique)
+	if processUniqueErr != nil {
+		return nil, processUniqueErr
+	}
+	oid := make(ObjectId, 12)
+	binary.BigEndian.PutUint32(oid, uint32(time.Now().Unix()))
+	copy(oid[4:9], processUnique[:])
+	cnt := atomic.AddUint32(&randomCount, 1)
+	oid[9] = byte(cnt >> 16)
+	oid[10] = byte(cnt >> 8)
+	oid[11] = byte(cnt)
+	return oid, nil
+}
+
+// Create unique incrementing ObjectId with the layout from the old ObjectId
+// spec.
+//
+//   +---+---+---+---+---+---+---+---+---+---+---+---+
+//   |       A       |     B     |   C   |     D     |
+//   +---+---+---+---+---+---+---+---+---+---+---+---+
+//     0   1   2   3   4   5   6   7   8   9  10  11
+//   A = unix time (big endian), B = machine ID (first 3 bytes of md5 host name),
+//   C = PID, D = incrementing counter (big endian)
+func NewLegacyObjectId() (ObjectId, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 12))
+
+	// A, unix time (big endian).
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], uint32(time.Now().Unix()))
+	if _, err := buf.Write(tmp[:]); err != nil {
+		return nil, err
+	}
+
+	// B, machine Id hash.
+	name, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	hash := md5.New()
+	if _, err := hash.Write([]byte(name)); err != nil {
+		return nil, err
+	}
+	if _, err := buf.Write(hash.Sum(nil)[:3]); err != nil {
+		return nil, err
+	}
+
+	// C, PID (process Id).
+	binary.BigEndian.PutUint16(tmp[:], uint16(os.Getpid()))
+	if _, err := buf.Write(tmp[:2]); err != nil {
+		return nil, err
+	}
+
+	// D, incrementing counter.
+	cnt := atomic.AddInt32(&lastCount, 1) % 16777215
+	cntbuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(cntbuf, uint32(cnt))
+	if _, err := buf.Write(cntbuf[1:]); err != nil {
+		return nil, err
+	}
+	return ObjectId(buf.Bytes()), nil
+}
+
+// NewObjectIdFromBytes returns a ObjectId copied from b. Returns error if b is
+// not 12 bytes.
+func NewObjectIdFromBytes(b []byte) (ObjectId, error) {
+	if len(b) != 12 {
+		return nil, fmt.Errorf("ObjectId must be 12 bytes, got %v.", len(b))
+	}
+	oid := make(ObjectId, 12)
+	copy(oid, b)
+	return oid, nil
+}
+
+// ObjectIdFromHex returns the ObjectId encoded as 24 hex digits, which is the
+// form returned by Hex. Returns error if s isn't a valid ObjectId.
+func ObjectIdFromHex(s string) (ObjectId, error) {
+	if len(s) != 24 {
+		return nil, fmt.Errorf("ObjectId hex must be 24 digits, got %v.", len(s))
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("ObjectId hex invalid, %v.", err)
+	}
+	return ObjectId(b), nil
+}
+
+// Hex returns the ObjectId as 24 lower case hex digits, the same as the mongo
+// shell shows inside ObjectId("...").
+func (this ObjectId) Hex() string {
+	return hex.EncodeToString(this)
+}
+
+// NewObjectIdFromTime returns a ObjectId with the time t and the other bytes
+// zeroed. It isn't unique, it's for use as a boundary in range queries. For
+// example, to find documents created since t:
+//   Map{"_id": Map{"$gte": NewObjectIdFromTime(t)}}
+func NewObjectIdFromTime(t time.Time) ObjectId {
+	oid := make(ObjectId, 12)
+	binary.BigEndian.PutUint32(oid, uint32(t.Unix()))
+	return oid
+}
+
+// Time returns the time the ObjectId was created, to the second. Returns the
+// zero time if the ObjectId isn't 12 bytes.
+func (this ObjectId) Time() time.Time {
+	if len(this) != 12 {
+		return time.Time{}
+	}
+	return time.Unix(int64(binary.BigEndian.Uint32(this)), 0)
+}
+
+// String returns the ObjectId as hex, the same as Hex.
+func (this ObjectId) String() string {
+	return this.Hex()
+}
+
+// MarshalText returns the ObjectId as hex. An empty ObjectId is empty text.
+func (this ObjectId) MarshalText() ([]byte, error) {
+	if len(this) == 0 {
+		return []byte{}, nil
+	}
+	if len(this) != 12 {
+		return nil, fmt.Errorf("ObjectId must be 12 bytes, got %v.", len(this))
+	}
+	return []byte(this.Hex()), nil
+}
+
+// UnmarshalText parses a ObjectId from hex. Empty text is an empty ObjectId.
+func (this *ObjectId) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*this = nil
+		return nil
+	}
+	oid, err := ObjectIdFromHex(string(b))
+	if err != nil {
+		return err
+	}
+	*this = oid
+	return nil
+}
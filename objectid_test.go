@@ -0,0 +1,194 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewObjectId(t *testing.T) {
+	oid0, err := NewObjectId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(oid0) != 12 {
+		t.Fatal(len(oid0))
+	}
+	oid1, err := NewObjectId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ObjectIds should be increasing.
+	if bytes.Compare(oid0, oid1) >= 0 {
+		t.Fatal()
+	}
+	// Same process unique value, different counter.
+	if bytes.Compare(oid0[4:9], oid1[4:9]) != 0 ||
+		bytes.Compare(oid0[9:], oid1[9:]) == 0 {
+
+		t.Fatal(oid0, oid1)
+	}
+}
+
+func TestNewLegacyObjectId(t *testing.T) {
+	oid0, err := NewLegacyObjectId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oid1, err := NewLegacyObjectId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Same machine and PID, different counter.
+	if bytes.Compare(oid0[4:9], oid1[4:9]) != 0 ||
+		bytes.Compare(oid0[9:], oid1[9:]) == 0 {
+
+		t.Fatal(oid0, oid1)
+	}
+}
+
+func TestNewObjectIdFromBytes(t *testing.T) {
+	b := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09,
+		0x0A, 0x0B}
+	oid, err := NewObjectIdFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(oid, b) != 0 {
+		t.Fatal(oid)
+	}
+	if _, err := NewObjectIdFromBytes(b[:11]); err == nil {
+		t.Fatal("Expected error for 11 byte ObjectId.")
+	}
+}
+
+func TestObjectIdHex(t *testing.T) {
+	oid := ObjectId{0x50, 0x7f, 0x1f, 0x77, 0xbc, 0xf8, 0x6c, 0xd7, 0x99, 0x43,
+		0x90, 0x11}
+	if oid.Hex() != "507f1f77bcf86cd799439011" {
+		t.Fatal(oid.Hex())
+	}
+	for _, s := range []string{"507f1f77bcf86cd799439011",
+		"507F1F77BCF86CD799439011"} {
+
+		dst, err := ObjectIdFromHex(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(dst, oid) != 0 {
+			t.Fatal(dst)
+		}
+	}
+	for _, s := range []string{"", "507f1f77bcf86cd79943901",
+		"507f1f77bcf86cd79943901g"} {
+
+		if _, err := ObjectIdFromHex(s); err == nil {
+			t.Fatal("Expected error.", s)
+		}
+	}
+}
+
+func TestObjectIdTime(t *testing.T) {
+	now := time.Unix(time.Now().Unix(), 0)
+	oid, err := NewObjectId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := oid.Time().Sub(now); d < 0 || d > time.Minute {
+		t.Fatal(oid.Time(), now)
+	}
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	boundary := NewObjectIdFromTime(tm)
+	if !boundary.Time().Equal(tm.Truncate(time.Second)) {
+		t.Fatal(boundary.Time())
+	}
+	if bytes.Compare(boundary[4:], make([]byte, 8)) != 0 {
+		t.Fatal(boundary)
+	}
+	if !(ObjectId{0x01}).Time().IsZero() {
+		t.Fatal("Expected zero time for invalid ObjectId.")
+	}
+}
+
+func TestSetObjectIdGenerator(t *testing.T) {
+	var n byte
+	gen := ObjectIdGeneratorFunc(func() (ObjectId, error) {
+		n++
+		return ObjectId{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, n}, nil
+	})
+	prev := SetObjectIdGenerator(gen)
+	defer SetObjectIdGenerator(prev)
+	for i := byte(1); i <= 2; i++ {
+		oid, err := NewObjectId()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if oid[11] != i {
+			t.Fatal(oid)
+		}
+	}
+
+	// Hooks use the generator.
+	doc, err := AssignObjectId()(Map{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oid := doc.(Map)["_id"].(ObjectId); oid[11] != 3 {
+		t.Fatal(oid)
+	}
+
+	if SetObjectIdGenerator(LegacyObjectIds) == nil {
+		t.Fatal("Expected previous generator.")
+	}
+	if _, err := NewObjectId(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestObjectIdMarshal(t *testing.T) {
+	type doc struct {
+		Id    ObjectId
+		Empty ObjectId
+	}
+	oid, err := ObjectIdFromHex("507f1f77bcf86cd799439011")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(oid); s != "507f1f77bcf86cd799439011" {
+		t.Fatal(s)
+	}
+	b, err := json.Marshal(doc{Id: oid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"Id":"507f1f77bcf86cd799439011","Empty":null}` {
+		t.Fatal(string(b))
+	}
+	var dst doc
+	if err := json.Unmarshal(b, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare(dst.Id, oid) != 0 || dst.Empty != nil {
+		t.Fatal(dst)
+	}
+	if err := json.Unmarshal([]byte(`{"Id":"xyz"}`), &dst); err == nil {
+		t.Fatal("Expected error for invalid hex.")
+	}
+	if _, err := json.Marshal(ObjectId{0x01}); err == nil {
+		t.Fatal("Expected error for 1 byte ObjectId.")
+	}
+
+	// Text.
+	b, err = oid.MarshalText()
+	if err != nil || string(b) != oid.Hex() {
+		t.Fatal(err, string(b))
+	}
+	if err := dst.Id.UnmarshalText(nil); err != nil || dst.Id != nil {
+		t.Fatal(err, dst.Id)
+	}
+}
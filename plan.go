@@ -0,0 +1,85 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"sync"
+)
+
+// structPlan is what's needed to encode or decode a struct type, so that the
+// struct tags are only parsed once per type.
+type structPlan struct {
+	fields []fieldPlan
+	keys   map[string]bool // Keys of the fields, including inline structs.
+	rest   bool            // There's a inline map or Slice for other keys.
+}
+
+// fieldPlan is the parsed struct tag of a field.
+type fieldPlan struct {
+	index     int
+	name      string // Go field name.
+	key       string // Document key.
+	typ       reflect.Type
+	inline    bool // inline or remain.
+	omitEmpty bool
+	omitDeep  bool
+	omitZero  bool
+	minsize   bool
+	required  bool
+	truncate  bool
+	transform string // Transform name, or "" for none.
+}
+
+// structPlans caches *structPlan by reflect.Type.
+var structPlans sync.Map
+
+// planStruct returns the plan for the struct type.
+func planStruct(t reflect.Type) *structPlan {
+	if p, ok := structPlans.Load(t); ok {
+		return p.(*structPlan)
+	}
+	p := &structPlan{keys: map[string]bool{}}
+	for i := 0; i < t.NumField(); i++ {
+		sv := t.Field(i)
+		key, opts, ok := structTag(sv)
+		if !ok {
+			continue
+		}
+		f := fieldPlan{
+			index:     i,
+			name:      sv.Name,
+			key:       key,
+			typ:       sv.Type,
+			inline:    hasOpt(opts, "inline") || hasOpt(opts, "remain"),
+			omitEmpty: hasOpt(opts, "omitempty"),
+			omitDeep:  hasOpt(opts, "omitempty=deep"),
+			omitZero:  hasOpt(opts, "omitzero"),
+			minsize:   hasOpt(opts, "minsize"),
+			required:  hasOpt(opts, "required"),
+			truncate:  hasOpt(opts, "truncate"),
+		}
+		f.transform, _ = optValue(opts, "transform")
+		p.fields = append(p.fields, f)
+		if !f.inline {
+			p.keys[key] = true
+			continue
+		}
+		ft := sv.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			p.rest = true
+			continue
+		}
+		inner := planStruct(ft)
+		for k := range inner.keys {
+			p.keys[k] = true
+		}
+		p.rest = p.rest || inner.rest
+	}
+	actual, _ := structPlans.LoadOrStore(t, p)
+	return actual.(*structPlan)
+}
@@ -0,0 +1,67 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanStruct(t *testing.T) {
+	type plan struct {
+		Ignore string `bson:"-"`
+		A      int    `bson:"a,omitempty,minsize"`
+		Base   `bson:",inline"`
+		Rest   Map `bson:",remain"`
+		hidden int
+	}
+	p := planStruct(reflect.TypeOf(plan{}))
+	if p != planStruct(reflect.TypeOf(plan{})) {
+		t.Fatal("Plan not cached.")
+	}
+	if len(p.fields) != 3 {
+		t.Fatal(p.fields)
+	}
+	a := p.fields[0]
+	if a.index != 1 || a.key != "a" || !a.omitEmpty || !a.minsize || a.inline {
+		t.Fatal(a)
+	}
+	if !p.fields[1].inline || !p.fields[2].inline || !p.rest {
+		t.Fatal(p)
+	}
+	exp := map[string]bool{"a": true, "_id": true, "created": true}
+	if !reflect.DeepEqual(p.keys, exp) {
+		t.Fatal(p.keys, exp)
+	}
+}
+
+func BenchmarkEncodeStruct(b *testing.B) {
+	type bench struct {
+		A int64  `bson:"a"`
+		B string `bson:"b,omitempty"`
+		C []int  `bson:"c"`
+		D Base   `bson:"d"`
+	}
+	src := bench{A: 1, B: "foo", C: []int{1, 2, 3}, D: Base{Id: 1}}
+	for i := 0; i < b.N; i++ {
+		if _, err := EncodeStruct(src); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeStruct(b *testing.B) {
+	type bench struct {
+		A int64  `bson:"a"`
+		B string `bson:"b,omitempty"`
+		D Base   `bson:"d"`
+	}
+	bs := MustEncodeStruct(bench{A: 1, B: "foo", D: Base{Id: 1}})
+	var dst bench
+	for i := 0; i < b.N; i++ {
+		if err := DecodeStruct(bs, &dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBuffer is the max capacity (bytes) of a buffer which is put back in
+// the pool, so that one large document doesn't pin memory.
+const maxPooledBuffer = 1024 * 1024
+
+// bufPool holds the buffers documents are encoded in. Nested documents are
+// written to the buffer of the outermost document, and the encoded bytes are
+// copied out, so callers never see a pooled buffer.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// getBuffer returns a empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer puts the buffer back in the pool. The buffer must not be used
+// after.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBuffer {
+		return
+	}
+	bufPool.Put(buf)
+}
+
+// releaseBuffer returns a copy of the bytes in the buffer, and puts the buffer
+// back in the pool.
+func releaseBuffer(buf *bytes.Buffer) []byte {
+	b := append([]byte(nil), buf.Bytes()...)
+	putBuffer(buf)
+	return b
+}
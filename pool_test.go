@@ -0,0 +1,46 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"testing"
+)
+
+// The encoded bytes must not share memory with a pooled buffer.
+func TestEncodeBufferReuse(t *testing.T) {
+	a := Slice{
+		{"a", Array{Map{"b": String("c")}}},
+		{"d", JavascriptScope{"x", Map{"y": Int32(1)}}},
+	}
+	bsA, err := a.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := append([]byte(nil), bsA...)
+	for i := 0; i < 10; i++ {
+		if _, err := (Map{"z": String("zzzzzzzzzzzzzzzzzzzzzzz")}).Encode(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(bsA, exp) {
+		t.Fatal("encoded bytes changed")
+	}
+	if c := Compare(bsA, a); c != 0 {
+		t.Fatal(c)
+	}
+}
+
+func BenchmarkEncodeNested(b *testing.B) {
+	doc := Map{
+		"a": Map{"b": Map{"c": Int32(1)}},
+		"d": Array{String("e"), Map{"f": Float(1)}},
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := doc.Encode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// projection is a tree of projected paths. A node without children is a leaf,
+// meaning the whole value at that path is included or excluded.
+type projection map[string]projection
+
+// Project returns a new document trimmed according to spec. The spec is a Map
+// of dotted paths (escaped the same as with Reach) to 1 or true (include) or 0
+// or false (exclude), the same as a MongoDB projection. If paths are included
+// then only those paths are kept, if paths are excluded then everything except
+// those paths is kept. Include and exclude may not be mixed, except that "_id"
+// may be excluded from an inclusion. As with MongoDB, "_id" is kept by an
+// inclusion unless excluded.
+//
+// The returned document is the same type as doc. Map and Slice values are
+// shared with doc, not copied. Documents nested in arrays are projected.
+func Project(doc Doc, spec Map) (Doc, error) {
+	proj, include, err := parseProjection(spec)
+	if err != nil {
+		return nil, err
+	}
+	switch doct := doc.(type) {
+	case Map:
+		return projectMap(doct, proj, include)
+	case Slice:
+		return projectSlice(doct, proj, include)
+	case BSON:
+		s, err := doct.Slice()
+		if err != nil {
+			return nil, err
+		}
+		s, err = projectSlice(s, proj, include)
+		if err != nil {
+			return nil, err
+		}
+		return s.Encode()
+	}
+	return nil, fmt.Errorf("cannot project %T.", doc)
+}
+
+// parseProjection parses a projection spec. Returns the projection tree and
+// true if it's an inclusion.
+func parseProjection(spec Map) (projection, bool, error) {
+	proj := projection{}
+	include, exclude, excludeId := false, false, false
+	for path, v := range spec {
+		in, err := projectionFlag(v)
+		if err != nil {
+			return nil, false, fmt.Errorf("%v, %v", path, err)
+		}
+		if path == "_id" && !in {
+			excludeId = true
+		} else if in {
+			include = true
+		} else {
+			exclude = true
+		}
+		if err := proj.add(splitPath([]string{path})); err != nil {
+			return nil, false, fmt.Errorf("%v, %v", path, err)
+		}
+	}
+	if include && exclude {
+		return nil, false, errors.New("Projection cannot mix include and exclude.")
+	}
+	if include {
+		if excludeId {
+			delete(proj, "_id")
+		} else if _, ok := proj["_id"]; !ok {
+			proj["_id"] = projection{}
+		}
+	}
+	return proj, include, nil
+}
+
+// add adds the path to the projection tree.
+func (this projection) add(dot []string) error {
+	cur := this
+	for i, name := range dot {
+		next, ok := cur[name]
+		if ok && (len(next) == 0 || i == len(dot)-1) {
+			return errors.New("projection path collision.")
+		}
+		if !ok {
+			next = projection{}
+			cur[name] = next
+		}
+		cur = next
+	}
+	return nil
+}
+
+// projectionFlag returns true if v means include, false if exclude.
+func projectionFlag(v interface{}) (bool, error) {
+	switch vt := v.(type) {
+	case Bool:
+		return bool(vt), nil
+	case bool:
+		return vt, nil
+	case Int32:
+		return vt != 0, nil
+	case Int64:
+		return vt != 0, nil
+	case Float:
+		return vt != 0, nil
+	case int:
+		return vt != 0, nil
+	case int32:
+		return vt != 0, nil
+	case int64:
+		return vt != 0, nil
+	case float64:
+		return vt != 0, nil
+	}
+	return false, fmt.Errorf("unsupported projection value %T.", v)
+}
+
+// projectMap applies the projection to a Map.
+func projectMap(m Map, proj projection, include bool) (Map, error) {
+	dst := Map{}
+	if include {
+		for name, sub := range proj {
+			v, ok := m[name]
+			if !ok {
+				continue
+			}
+			if len(sub) == 0 {
+				dst[name] = v
+				continue
+			}
+			pv, ok, err := projectVal(v, sub, include)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				dst[name] = pv
+			}
+		}
+		return dst, nil
+	}
+	for name, v := range m {
+		sub, ok := proj[name]
+		if !ok {
+			dst[name] = v
+			continue
+		}
+		if len(sub) == 0 {
+			continue
+		}
+		pv, ok, err := projectVal(v, sub, include)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			dst[name] = pv
+		}
+	}
+	return dst, nil
+}
+
+// projectSlice applies the projection to a Slice. Order is preserved.
+func projectSlice(s Slice, proj projection, include bool) (Slice, error) {
+	dst := Slice{}
+	for _, pair := range s {
+		sub, ok := proj[pair.Key]
+		if !ok {
+			if !include {
+				dst = append(dst, pair)
+			}
+			continue
+		}
+		if len(sub) == 0 {
+			if include {
+				dst = append(dst, pair)
+			}
+			continue
+		}
+		pv, ok, err := projectVal(pair.Val, sub, include)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			dst = append(dst, Pair{Key: pair.Key, Val: pv})
+		}
+	}
+	return dst, nil
+}
+
+// projectVal applies a nested projection to a value. Returns false if the
+// value should be dropped.
+func projectVal(v interface{}, proj projection, include bool) (interface{}, bool,
+	error) {
+
+	switch vt := v.(type) {
+	case Map:
+		m, err := projectMap(vt, proj, include)
+		return m, err == nil, err
+	case Slice:
+		s, err := projectSlice(vt, proj, include)
+		return s, err == nil, err
+	case BSON:
+		s, err := vt.Slice()
+		if err != nil {
+			return nil, false, err
+		}
+		s, err = projectSlice(s, proj, include)
+		if err != nil {
+			return nil, false, err
+		}
+		b, err := s.Encode()
+		return b, err == nil, err
+	case Array:
+		a := make(Array, 0, len(vt))
+		for _, e := range vt {
+			pv, ok, err := projectVal(e, proj, include)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				a = append(a, pv)
+			}
+		}
+		return a, true, nil
+	}
+	// Not a document. A nested path can't match it.
+	return v, !include, nil
+}
+
+// DecodePaths decodes only the values at the paths, which are dotted the same
+// as with Reach, walking the BSON once. Other values are skipped without being
+// decoded, which is much cheaper than decoding a large document to read a few
+// values. The result has the documents on the paths, with only the keys on the
+// paths. For example:
+//   m, err := bson.DecodePaths(bs, "name", "address.city", "items.0")
+// A number in a path indexes a array, as with Reach, and the decoded array has
+// only the elements which were indexed, in order. Any other key in a path
+// applies to every document in the array, as with Project. A path which is a
+// prefix of another includes the whole value.
+func DecodePaths(bs BSON, paths ...string) (Map, error) {
+	proj := projection{}
+	for _, path := range paths {
+		proj.allow(splitPath([]string{path}))
+	}
+	d := rawDecoder{}
+	return d.paths(bs, 0, proj, "")
+}
+
+// allow adds the path to the projection tree. Unlike add, a path which is a
+// prefix of another includes the whole value rather than colliding.
+func (this projection) allow(dot []string) {
+	cur := this
+	for i, name := range dot {
+		next, ok := cur[name]
+		if ok && len(next) == 0 {
+			// Whole value already included.
+			return
+		}
+		if i == len(dot)-1 {
+			cur[name] = projection{}
+			return
+		}
+		if !ok {
+			next = projection{}
+			cur[name] = next
+		}
+		cur = next
+	}
+}
+
+// merge returns the union of the projection trees. A leaf includes the whole
+// value, so it's the union of a leaf and anything.
+func (this projection) merge(other projection) projection {
+	if len(this) == 0 {
+		return this
+	}
+	dst := make(projection, len(this)+len(other))
+	for k, sub := range this {
+		dst[k] = sub
+	}
+	for k, osub := range other {
+		sub, ok := dst[k]
+		switch {
+		case !ok:
+			dst[k] = osub
+		case len(sub) == 0 || len(osub) == 0:
+			dst[k] = projection{}
+		default:
+			dst[k] = sub.merge(osub)
+		}
+	}
+	return dst
+}
+
+// paths decodes the values in the projection of the document at offset off.
+func (this *rawDecoder) paths(bs []byte, off int, proj projection,
+	path string) (Map, error) {
+
+	elems, err := rawElements(bs, off)
+	if err != nil {
+		if path == "" {
+			return nil, err
+		}
+		return nil, prefixDecodeError(path, err)
+	}
+	m := Map{}
+	for _, e := range elems {
+		sub, ok := proj[e.Name]
+		if !ok {
+			continue
+		}
+		v, ok, err := this.pathsValue(bs, e, sub, catpath(path, e.Name))
+		if err != nil {
+			return nil, decodeError(path, e.Name, e.Start, e.Type, err)
+		}
+		if ok {
+			m[e.Name] = v
+		}
+	}
+	return m, nil
+}
+
+// pathsValue decodes the projection of a value. Returns false if a nested
+// path can't match the value.
+func (this *rawDecoder) pathsValue(bs []byte, e rawElement, proj projection,
+	path string) (interface{}, bool, error) {
+
+	if len(proj) == 0 {
+		v, err := this.value(e.Type, bs[e.Value:e.End], path, e.Value)
+		return v, err == nil, err
+	}
+	switch e.Type {
+	case _EMBEDDED_DOCUMENT:
+		m, err := this.paths(bs, e.Value, proj, path)
+		return m, err == nil, err
+	case _ARRAY:
+		elems, err := rawElements(bs, e.Value)
+		if err != nil {
+			return nil, false, prefixDecodeError(path, err)
+		}
+		// Numbers index the array, other keys apply to every element.
+		indexes := map[int]projection{}
+		keys := projection{}
+		for k, sub := range proj {
+			if i, err := strconv.Atoi(k); err == nil {
+				indexes[i] = sub
+			} else {
+				keys[k] = sub
+			}
+		}
+		a := Array{}
+		for _, ae := range elems {
+			i, err := strconv.Atoi(ae.Name)
+			isub, indexed := indexes[i]
+			var sub projection
+			switch {
+			case err == nil && indexed:
+				sub = isub.merge(keys)
+			case len(keys) > 0:
+				sub = keys
+			default:
+				continue
+			}
+			v, ok, err := this.pathsValue(bs, ae, sub,
+				catpath(path, ae.Name))
+			if err != nil {
+				return nil, false, decodeError(path, ae.Name, ae.Start,
+					ae.Type, err)
+			}
+			if ok {
+				a = append(a, v)
+			}
+		}
+		return a, true, nil
+	}
+	// Not a document. A nested path can't match it.
+	return nil, false, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+var projectSrc = Map{
+	"_id": Int32(1),
+	"foo": String("bar"),
+	"nest": Map{
+		"a": Int32(1),
+		"b": Int32(2),
+	},
+	"items": Array{
+		Map{"a": Int32(1), "b": Int32(2)},
+		Map{"a": Int32(3), "b": Int32(4)},
+	},
+}
+
+var projectTests = []struct {
+	spec Map
+	exp  Map
+}{
+	// Include.
+	{
+		spec: Map{"foo": 1, "nest.a": true},
+		exp: Map{
+			"_id":  Int32(1),
+			"foo":  String("bar"),
+			"nest": Map{"a": Int32(1)},
+		},
+	},
+
+	// Include, exclude _id.
+	{
+		spec: Map{"foo": Int32(1), "_id": 0},
+		exp:  Map{"foo": String("bar")},
+	},
+
+	// Include in array.
+	{
+		spec: Map{"items.b": 1, "_id": false},
+		exp: Map{
+			"items": Array{Map{"b": Int32(2)}, Map{"b": Int32(4)}},
+		},
+	},
+
+	// Exclude.
+	{
+		spec: Map{"foo": 0, "nest.b": 0, "items.a": 0},
+		exp: Map{
+			"_id":   Int32(1),
+			"nest":  Map{"a": Int32(1)},
+			"items": Array{Map{"b": Int32(2)}, Map{"b": Int32(4)}},
+		},
+	},
+}
+
+func TestProjectMap(t *testing.T) {
+	for _, pt := range projectTests {
+		dst, err := Project(projectSrc, pt.spec)
+		if err != nil {
+			t.Fatal(err, pt.spec)
+		}
+		if !reflect.DeepEqual(dst, pt.exp) {
+			t.Fatal(dst, pt.exp)
+		}
+	}
+}
+
+func TestProjectBSON(t *testing.T) {
+	src := Slice{
+		{"a", Int32(1)},
+		{"b", Slice{{"c", Int32(2)}, {"d", Int32(3)}}},
+		{"e", Int32(4)},
+	}
+	exp := Slice{
+		{"b", Slice{{"d", Int32(3)}}},
+		{"e", Int32(4)},
+	}
+	dst, err := Project(src.MustEncode(), Map{"a": 0, "b.c": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := dst.MustEncode().Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+}
+
+func TestProjectError(t *testing.T) {
+	if _, err := Project(projectSrc, Map{"foo": 1, "nest": 0}); err == nil {
+		t.Fatal("Expected error mixing include and exclude.")
+	}
+	if _, err := Project(projectSrc, Map{"nest": 1, "nest.a": 1}); err == nil {
+		t.Fatal("Expected error for path collision.")
+	}
+}
+
+func TestDecodePaths(t *testing.T) {
+	bs := projectSrc.MustEncode()
+	for _, paths := range [][]string{
+		{"foo"},
+		{"_id", "nest.a"},
+		{"items.b", "missing", "foo.bar"},
+		{"nest", "items"},
+	} {
+		spec := Map{"_id": 0}
+		for _, path := range paths {
+			spec[path] = 1
+		}
+		exp, err := Project(projectSrc, spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := DecodePaths(bs, paths...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, exp) {
+			t.Fatalf("Expected %v, got %v, %v.", exp, m, paths)
+		}
+	}
+
+	// A prefix of another path includes the whole value.
+	m, err := DecodePaths(bs, "nest.a", "nest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, Map{"nest": projectSrc["nest"]}) {
+		t.Fatalf("Expected whole nest, got %v.", m)
+	}
+}
+
+func TestDecodePathsError(t *testing.T) {
+	bs := Map{"a": Map{"b": Int32(1)}, "c": Int32(2)}.MustEncode()
+	if _, err := DecodePaths(bs[:len(bs)-1], "c"); err == nil {
+		t.Fatal("Expected error for truncated document.")
+	}
+}
+
+func TestDecodePathsArrayIndex(t *testing.T) {
+	bs := Map{
+		"a": Array{Int32(10), Map{"b": Int32(2), "c": Int32(3)}, Int32(30)},
+	}.MustEncode()
+	tests := []struct {
+		paths []string
+		exp   Map
+	}{
+		{[]string{"a.0"}, Map{"a": Array{Int32(10)}}},
+		{[]string{"a.1.b"}, Map{"a": Array{Map{"b": Int32(2)}}}},
+		{[]string{"a.2", "a.0"}, Map{"a": Array{Int32(10), Int32(30)}}},
+		{[]string{"a.1.b", "a.c"},
+			Map{"a": Array{Map{"b": Int32(2), "c": Int32(3)}}}},
+		{[]string{"a.5"}, Map{"a": Array{}}},
+	}
+	for _, test := range tests {
+		m, err := DecodePaths(bs, test.paths...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(m, test.exp) {
+			t.Fatal(test.paths, m)
+		}
+	}
+
+	// The same value as Reach.
+	var v Int32
+	if ok, err := bs.Reach(&v, "a.1.b"); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	m, err := DecodePaths(bs, "a.1.b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Int32
+	if ok, err := m.Reach(&got, "a.0.b"); !ok || err != nil || got != v {
+		t.Fatal(m, err)
+	}
+}
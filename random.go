@@ -0,0 +1,251 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Defaults for RandomOptions.
+const (
+	defaultRandomDepth = 3
+	defaultRandomElems = 8
+	defaultRandomLen   = 16
+)
+
+// randomInts are edge values generated for the integer types.
+var randomInts = []int64{0, 1, -1, math.MinInt32, math.MaxInt32,
+	math.MinInt64, math.MaxInt64}
+
+// randomFloats are edge values generated for Float.
+var randomFloats = []float64{0, math.Copysign(0, -1), math.Inf(1),
+	math.Inf(-1), math.MaxFloat64, -math.MaxFloat64,
+	math.SmallestNonzeroFloat64, -math.SmallestNonzeroFloat64}
+
+// RandomOptions are options for generating random documents.
+type RandomOptions struct {
+	// Max nesting depth of documents and arrays. Defaults to 3.
+	MaxDepth int
+
+	// Max elements in each document or array. Defaults to 8.
+	MaxElems int
+
+	// Max length of strings and binaries. Defaults to 16.
+	MaxLen int
+
+	// Types of values to generate. Defaults to all types. Exclude
+	// TypeEmbeddedDocument and TypeArray for documents without nesting.
+	Types []Type
+}
+
+// RandomSlice returns a random document, for property testing. All documents
+// nested in it are Slices. Keys are unique and nothing contains a null byte
+// which can't be encoded, so the document round trips through BSON and
+// Extended JSON. Numbers are over the full range of their type, and are
+// sometimes edge values such as MinInt64, -0 or Inf. Floats are never NaN.
+func RandomSlice(rnd *rand.Rand, opts RandomOptions) Slice {
+	g := newRandomGen(rnd, opts)
+	return g.slice(g.opts.MaxDepth)
+}
+
+// RandomMap is the same as RandomSlice but all documents are Maps.
+func RandomMap(rnd *rand.Rand, opts RandomOptions) Map {
+	return randomMap(RandomSlice(rnd, opts)).(Map)
+}
+
+// Generate returns a random Map, see RandomMap. This implements
+// quick.Generator so Maps can be arguments of properties checked by
+// quick.Check. The size limits the elements in each document.
+func (this Map) Generate(rnd *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(RandomMap(rnd, randomSizeOptions(size)))
+}
+
+// Generate is the same as Map Generate, but for Slice.
+func (this Slice) Generate(rnd *rand.Rand, size int) reflect.Value {
+	return reflect.ValueOf(RandomSlice(rnd, randomSizeOptions(size)))
+}
+
+// randomSizeOptions returns the options for a quick.Generator size.
+func randomSizeOptions(size int) RandomOptions {
+	opts := RandomOptions{}
+	if size > 0 && size < defaultRandomElems {
+		opts.MaxElems = size
+	}
+	return opts
+}
+
+// randomGen generates random values.
+type randomGen struct {
+	rnd  *rand.Rand
+	opts RandomOptions
+}
+
+func newRandomGen(rnd *rand.Rand, opts RandomOptions) *randomGen {
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = defaultRandomDepth
+	}
+	if opts.MaxElems <= 0 {
+		opts.MaxElems = defaultRandomElems
+	}
+	if opts.MaxLen <= 0 {
+		opts.MaxLen = defaultRandomLen
+	}
+	if len(opts.Types) == 0 {
+		for t := range typeNames {
+			opts.Types = append(opts.Types, t)
+		}
+		// Map order is random, sort so a seed always generates the same.
+		sort.Slice(opts.Types, func(i, j int) bool {
+			return opts.Types[i] < opts.Types[j]
+		})
+	}
+	return &randomGen{rnd: rnd, opts: opts}
+}
+
+// slice generates a document with unique keys.
+func (this *randomGen) slice(depth int) Slice {
+	n := this.rnd.Intn(this.opts.MaxElems + 1)
+	s := make(Slice, 0, n)
+	for i := 0; i < n; i++ {
+		key := this.string() + strconv.Itoa(i)
+		s = append(s, Pair{Key: key, Val: this.value(depth)})
+	}
+	return s
+}
+
+// value generates a value. Documents and arrays are only generated if depth is
+// greater than 0.
+func (this *randomGen) value(depth int) interface{} {
+	types := make([]Type, 0, len(this.opts.Types))
+	for _, t := range this.opts.Types {
+		if depth > 0 || (t != TypeEmbeddedDocument && t != TypeArray) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		return Null{}
+	}
+	switch types[this.rnd.Intn(len(types))] {
+	case TypeFloat:
+		return Float(this.float())
+	case TypeString:
+		return String(this.string())
+	case TypeEmbeddedDocument:
+		return this.slice(depth - 1)
+	case TypeArray:
+		a := make(Array, this.rnd.Intn(this.opts.MaxElems+1))
+		for i := range a {
+			a[i] = this.value(depth - 1)
+		}
+		return a
+	case TypeBinary:
+		b := make(Binary, this.rnd.Intn(this.opts.MaxLen+1))
+		this.rnd.Read(b)
+		return b
+	case TypeUndefined:
+		return Undefined{}
+	case TypeObjectId:
+		return this.objectId()
+	case TypeBool:
+		return Bool(this.rnd.Intn(2) == 0)
+	case TypeUTCDateTime:
+		return UTCDateTime(this.int64())
+	case TypeNull:
+		return Null{}
+	case TypeRegexp:
+		var options []byte
+		for i := 0; i < len(regexpOptions); i++ {
+			if this.rnd.Intn(2) == 0 {
+				options = append(options, regexpOptions[i])
+			}
+		}
+		return Regexp{Pattern: this.string(), Options: string(options)}
+	case TypeDBPointer:
+		return DBPointer{Name: this.string(), ObjectId: this.objectId()}
+	case TypeJavascript:
+		return Javascript(this.string())
+	case TypeSymbol:
+		return Symbol(this.string())
+	case TypeJavascriptScope:
+		return JavascriptScope{Javascript: this.string(), Scope: this.slice(0)}
+	case TypeInt32:
+		return Int32(this.int64())
+	case TypeTimestamp:
+		return Timestamp(this.int64())
+	case TypeInt64:
+		return Int64(this.int64())
+	case TypeMinKey:
+		return MinKey{}
+	case TypeMaxKey:
+		return MaxKey{}
+	}
+	return Null{}
+}
+
+// int64 generates a int64 over the full range, or sometimes a edge value.
+// Truncated to 32 bits the edge values are the same for int32.
+func (this *randomGen) int64() int64 {
+	if this.rnd.Intn(4) == 0 {
+		return randomInts[this.rnd.Intn(len(randomInts))]
+	}
+	return int64(this.rnd.Uint64())
+}
+
+// float generates a float which isn't NaN. Usually it's a normal distribution,
+// sometimes it's a edge value or random bits.
+func (this *randomGen) float() float64 {
+	switch this.rnd.Intn(4) {
+	case 0:
+		return randomFloats[this.rnd.Intn(len(randomFloats))]
+	case 1:
+		for {
+			f := math.Float64frombits(this.rnd.Uint64())
+			if !math.IsNaN(f) {
+				return f
+			}
+		}
+	}
+	return this.rnd.NormFloat64()
+}
+
+// string generates a string without null bytes.
+func (this *randomGen) string() string {
+	r := make([]rune, this.rnd.Intn(this.opts.MaxLen+1))
+	for i := range r {
+		r[i] = rune(1 + this.rnd.Intn(0x2FF))
+	}
+	return string(r)
+}
+
+func (this *randomGen) objectId() ObjectId {
+	oid := make(ObjectId, 12)
+	this.rnd.Read(oid)
+	return oid
+}
+
+// randomMap converts a generated value so that all documents are Maps.
+func randomMap(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case Slice:
+		m := make(Map, len(vt))
+		for _, pair := range vt {
+			m[pair.Key] = randomMap(pair.Val)
+		}
+		return m
+	case Array:
+		a := make(Array, len(vt))
+		for i, e := range vt {
+			a[i] = randomMap(e)
+		}
+		return a
+	case JavascriptScope:
+		return JavascriptScope{Javascript: vt.Javascript,
+			Scope: randomMap(vt.Scope).(Map)}
+	}
+	return v
+}
@@ -0,0 +1,71 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+func TestRandomQuick(t *testing.T) {
+	f := func(m Map, s Slice) bool {
+		m1, err := m.MustEncode().Map()
+		if err != nil || !reflect.DeepEqual(m, m1) {
+			return false
+		}
+		s1, err := s.MustEncode().Slice()
+		return err == nil && reflect.DeepEqual(s, s1)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRandomOptions(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	opts := RandomOptions{MaxElems: 3, MaxLen: 2, Types: []Type{TypeString}}
+	for i := 0; i < 100; i++ {
+		s := RandomSlice(rnd, opts)
+		if len(s) > 3 {
+			t.Fatal(s)
+		}
+		for _, pair := range s {
+			v, ok := pair.Val.(String)
+			if !ok || len([]rune(v)) > 2 {
+				t.Fatal(s)
+			}
+		}
+	}
+
+	// Same seed, same document.
+	a := RandomMap(rand.New(rand.NewSource(2)), RandomOptions{})
+	b := RandomMap(rand.New(rand.NewSource(2)), RandomOptions{})
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal(a, b)
+	}
+}
+
+func TestRandomRange(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	opts := RandomOptions{Types: []Type{TypeInt32, TypeInt64, TypeFloat}}
+	var neg32, min64, inf bool
+	for i := 0; i < 100; i++ {
+		for _, pair := range RandomSlice(rnd, opts) {
+			switch v := pair.Val.(type) {
+			case Int32:
+				neg32 = neg32 || v < 0
+			case Int64:
+				min64 = min64 || v == math.MinInt64
+			case Float:
+				inf = inf || math.IsInf(float64(v), 0)
+			}
+		}
+	}
+	if !neg32 || !min64 || !inf {
+		t.Fatal("Expected negative Int32, MinInt64 and Inf.", neg32, min64, inf)
+	}
+}
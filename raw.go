@@ -0,0 +1,305 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// rawElement is the location of a element in raw BSON. Offsets are relative to
+// the start of the outermost document.
+type rawElement struct {
+	Type  byte
+	Name  string
+	Start int // Offset of the type byte.
+	Value int // Offset of the value.
+	End   int // Offset after the value.
+}
+
+// rawElements returns the elements of the document which starts at offset off
+// in bs. Values are not decoded.
+func rawElements(bs []byte, off int) ([]rawElement, error) {
+	docLen, err := rawDocLen(bs, off)
+	if err != nil {
+		return nil, decodeError("", "", off, 0, err)
+	}
+	end := off + docLen - 1
+	var elems []rawElement
+	for pos := off + 4; pos < end; {
+		e := rawElement{Type: bs[pos], Start: pos}
+		nul := bytes.IndexByte(bs[pos+1:end], 0x00)
+		if nul == -1 {
+			return nil, decodeError("", "", pos, e.Type,
+				fmt.Errorf("%w, not terminated.", ErrInvalidKey))
+		}
+		e.Name = string(bs[pos+1 : pos+1+nul])
+		e.Value = pos + 1 + nul + 1
+		n, err := rawValueLen(e.Type, bs[e.Value:end])
+		if err != nil {
+			return nil, decodeError("", e.Name, pos, e.Type, err)
+		}
+		e.End = e.Value + n
+		elems = append(elems, e)
+		pos = e.End
+	}
+	return elems, nil
+}
+
+// rawDocLen returns the length of the document at offset off. Returns error if
+// the length is invalid or the document isn't null terminated.
+func rawDocLen(bs []byte, off int) (int, error) {
+	if off < 0 || len(bs)-off < 5 {
+		return 0, fmt.Errorf("Doc %w.", ErrTruncated)
+	}
+	docLen := int(int32(binary.LittleEndian.Uint32(bs[off:])))
+	if docLen < 5 {
+		return 0, fmt.Errorf("Doc length %v invalid.", docLen)
+	}
+	if docLen > len(bs)-off {
+		return 0, fmt.Errorf("Doc %w.", ErrTruncated)
+	}
+	if bs[off+docLen-1] != 0x00 {
+		return 0, errors.New("Doc not null terminated.")
+	}
+	return docLen, nil
+}
+
+// rawValueLen returns the length of a value of type t at the start of b.
+func rawValueLen(t byte, b []byte) (int, error) {
+	var n int
+	switch t {
+	case _UNDEFINED, _NULL_VALUE, _MIN_KEY, _MAX_KEY:
+		n = 0
+	case _BOOLEAN:
+		n = 1
+	case _32BIT_INTEGER:
+		n = 4
+	case _FLOATING_POINT, _UTC_DATETIME, _TIMESTAMP, _64BIT_INTEGER:
+		n = 8
+	case _OBJECT_ID:
+		n = 12
+	case _STRING, _JAVASCRIPT, _SYMBOL:
+		if len(b) < 4 {
+			return 0, fmt.Errorf("String %w.", ErrTruncated)
+		}
+		n = 4 + int(int32(binary.LittleEndian.Uint32(b)))
+		if n < 5 {
+			return 0, errors.New("String length invalid.")
+		}
+	case _EMBEDDED_DOCUMENT, _ARRAY, _JAVASCRIPT_SCOPE:
+		if len(b) < 4 {
+			return 0, fmt.Errorf("Doc %w.", ErrTruncated)
+		}
+		n = int(int32(binary.LittleEndian.Uint32(b)))
+		if n < 5 {
+			return 0, errors.New("Doc length invalid.")
+		}
+	case _BINARY_DATA:
+		if len(b) < 4 {
+			return 0, fmt.Errorf("Binary %w.", ErrTruncated)
+		}
+		n = 5 + int(int32(binary.LittleEndian.Uint32(b)))
+		if n < 5 {
+			return 0, errors.New("Binary length invalid.")
+		}
+	case _DBPOINTER:
+		if len(b) < 4 {
+			return 0, fmt.Errorf("DBPointer %w.", ErrTruncated)
+		}
+		n = 4 + int(int32(binary.LittleEndian.Uint32(b))) + 12
+		if n < 17 {
+			return 0, errors.New("DBPointer length invalid.")
+		}
+	case _REGEXP:
+		// Two cstrings.
+		i := bytes.IndexByte(b, 0x00)
+		if i == -1 {
+			return 0, fmt.Errorf("Regexp %w.", ErrTruncated)
+		}
+		j := bytes.IndexByte(b[i+1:], 0x00)
+		if j == -1 {
+			return 0, fmt.Errorf("Regexp %w.", ErrTruncated)
+		}
+		n = i + 1 + j + 1
+	default:
+		return 0, fmt.Errorf("%w '%X'.", ErrUnsupportedType, t)
+	}
+	if n > len(b) {
+		return 0, fmt.Errorf("Element %w.", ErrTruncated)
+	}
+	switch t {
+	case _STRING, _JAVASCRIPT, _SYMBOL, _DBPOINTER:
+		end := n
+		if t == _DBPOINTER {
+			end -= 12
+		}
+		if b[end-1] != 0x00 {
+			return 0, errors.New("String not null terminated.")
+		}
+	}
+	return n, nil
+}
+
+// rawFind finds the element at the path. Also returns the offsets of the
+// documents which contain the element, outermost first. Returns false if the
+// element doesn't exist, in which case the documents are the ones which exist
+// along the path.
+func rawFind(bs []byte, dot ...string) (rawElement, []int, bool, error) {
+	docs := []int{0}
+	off := 0
+	for i, name := range dot {
+		elems, err := rawElements(bs, off)
+		if err != nil {
+			return rawElement{}, docs, false, prefixDecodeError(joinPath(dot[:i]),
+				err)
+		}
+		found := false
+		var e rawElement
+		for _, e = range elems {
+			if e.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return rawElement{}, docs, false, nil
+		}
+		if i == len(dot)-1 {
+			return e, docs, true, nil
+		}
+		if e.Type != _EMBEDDED_DOCUMENT && e.Type != _ARRAY {
+			return rawElement{}, docs, false, nil
+		}
+		off = e.Value
+		docs = append(docs, off)
+	}
+	return rawElement{}, docs, false, nil
+}
+
+// rawLookup finds the element at the path. The element is the last one which
+// could be found without decoding, the remainder of the path is returned if it
+// continues in to a value which isn't a document (e.g. the Pattern of a
+// Regexp). Returns false if not found.
+func rawLookup(bs []byte, dot ...string) (rawElement, []string, bool, error) {
+	off := 0
+	for i, name := range dot {
+		elems, err := rawElements(bs, off)
+		if err != nil {
+			return rawElement{}, nil, false, prefixDecodeError(joinPath(dot[:i]),
+				err)
+		}
+		found := false
+		var e rawElement
+		for _, e = range elems {
+			if e.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return rawElement{}, nil, false, nil
+		}
+		if i == len(dot)-1 ||
+			(e.Type != _EMBEDDED_DOCUMENT && e.Type != _ARRAY) {
+
+			return e, dot[i+1:], true, nil
+		}
+		off = e.Value
+	}
+	return rawElement{}, nil, false, nil
+}
+
+// rawReach returns the value at the path. Only the element at the path is
+// decoded, embedded documents are left as BSON. Returns false if not found.
+func rawReach(bs []byte, dot ...string) (interface{}, bool, error) {
+	if len(dot) == 0 {
+		return BSON(bs), true, nil
+	}
+	e, rest, ok, err := rawLookup(bs, dot...)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	v, err := rawValue(bs, e)
+	if err != nil {
+		return nil, false, prefixDecodeError(joinPath(dot[:len(dot)-len(rest)]),
+			err)
+	}
+	v, ok = reach(v, rest...)
+	return v, ok, nil
+}
+
+// rawValue decodes the value of the element. Embedded documents are BSON.
+func rawValue(bs []byte, e rawElement) (interface{}, error) {
+	doc := make(BSON, 4, 4+e.End-e.Start+1)
+	doc = append(doc, bs[e.Start:e.End]...)
+	doc = append(doc, 0x00)
+	binary.LittleEndian.PutUint32(doc, uint32(len(doc)))
+	m, err := doc.MapNoNest()
+	if err != nil {
+		return nil, err
+	}
+	return m[e.Name], nil
+}
+
+// Splice returns a copy of the BSON with the value at the path replaced,
+// without decoding or re-encoding the rest of the document. If the value
+// doesn't exist it's appended to the containing document, which must exist.
+// The lengths of all enclosing documents are adjusted. This is much cheaper
+// than decoding and re-encoding a large document to change a small part.
+func (this BSON) Splice(val interface{}, dot ...string) (BSON, error) {
+	if len(dot) == 0 {
+		return nil, errors.New("path must not be empty.")
+	}
+	e, docs, ok, err := rawFind(this, dot...)
+	if err != nil {
+		return nil, err
+	}
+	if !ok && len(docs) != len(dot) {
+		return nil, fmt.Errorf("%v, containing document %w.",
+			joinPath(dot[:len(docs)]), ErrNotFound)
+	}
+	if !ok {
+		// Insert before the null byte which terminates the containing doc.
+		off := docs[len(docs)-1]
+		docLen, err := rawDocLen(this, off)
+		if err != nil {
+			return nil, err
+		}
+		e.Start = off + docLen - 1
+		e.End = e.Start
+	}
+
+	// Encode the new element.
+	name := dot[len(dot)-1]
+	buf := bytes.NewBuffer(nil)
+	if err := defaultEncoder.begin().encodeVal(buf, joinPath(dot), name, val); err != nil {
+		return nil, err
+	}
+
+	// Splice the new element in.
+	dst := make(BSON, 0, len(this)-(e.End-e.Start)+buf.Len())
+	dst = append(dst, this[:e.Start]...)
+	dst = append(dst, buf.Bytes()...)
+	dst = append(dst, this[e.End:]...)
+
+	// Adjust the lengths of the enclosing documents.
+	delta := buf.Len() - (e.End - e.Start)
+	for _, off := range docs {
+		docLen := int32(binary.LittleEndian.Uint32(dst[off:]))
+		binary.LittleEndian.PutUint32(dst[off:], uint32(docLen+int32(delta)))
+	}
+	return dst, nil
+}
+
+// joinPath joins path components with dots.
+func joinPath(dot []string) string {
+	path := ""
+	for _, name := range dot {
+		path = catpath(path, name)
+	}
+	return path
+}
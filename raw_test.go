@@ -0,0 +1,105 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplice(t *testing.T) {
+	src := Map{
+		"a": String("foo"),
+		"b": Map{
+			"c": Int32(1),
+			"d": Array{Map{"e": String("bar")}},
+		},
+	}
+	bs := src.MustEncode()
+
+	// Replace nested value with a larger one.
+	bs, err := bs.Splice(String("longer value"), "b", "d", "0", "e")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace with a smaller one.
+	bs, err = bs.Splice(Bool(true), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Append to nested document.
+	bs, err = bs.Splice(Int64(2), "b", "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := Map{
+		"a": Bool(true),
+		"b": Map{
+			"c": Int32(1),
+			"d": Array{Map{"e": String("longer value")}},
+			"f": Int64(2),
+		},
+	}
+	dst, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, exp) {
+		t.Fatal(dst, exp)
+	}
+	if len(bs) != len(exp.MustEncode()) {
+		t.Fatal(len(bs), len(exp.MustEncode()))
+	}
+
+	// Original not modified.
+	if dst, err := src.MustEncode().Map(); err != nil ||
+		!reflect.DeepEqual(dst, src) {
+
+		t.Fatal(dst, err)
+	}
+
+	if _, err := bs.Splice(Int32(1), "x", "y"); err == nil {
+		t.Fatal("Expected error for missing containing document.")
+	}
+}
+
+func TestBSONReach(t *testing.T) {
+	m := Map{
+		"a": Map{"b": Int32(1), "c": Array{String("x"), Map{"d": Int64(2)}}},
+		"r": Regexp{Pattern: "p", Options: "i"},
+	}
+	bs := m.MustEncode()
+	var i int
+	if ok, err := bs.Reach(&i, "a", "b"); !ok || err != nil || i != 1 {
+		t.Fatal(ok, err, i)
+	}
+	if ok, err := bs.Reach(&i, "a.c.1.d"); !ok || err != nil || i != 2 {
+		t.Fatal(ok, err, i)
+	}
+	var s string
+	if ok, err := bs.Reach(&s, "r", "Pattern"); !ok || err != nil || s != "p" {
+		t.Fatal(ok, err, s)
+	}
+	var nest Map
+	if ok, err := bs.Reach(&nest, "a"); !ok || err != nil ||
+		!reflect.DeepEqual(nest, m["a"]) {
+
+		t.Fatal(ok, err, nest)
+	}
+	var a Array
+	if ok, err := bs.Reach(&a, "a", "c"); !ok || err != nil ||
+		!reflect.DeepEqual(a, m["a"].(Map)["c"]) {
+
+		t.Fatal(ok, err, a)
+	}
+	if bs.Has("a", "x") || bs.Has("a", "b", "c") || !bs.Has("a", "c", "0") {
+		t.Fatal("Has mismatch.")
+	}
+	if ok, err := BSON(bs[:len(bs)-1]).Reach(&i, "a"); ok || err == nil {
+		t.Fatal("Expected error for truncated BSON.")
+	}
+}
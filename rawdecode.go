@@ -0,0 +1,342 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unsafe"
+)
+
+// pairSize is charged to the budget for each element, with the key, for the
+// Pair or Map entry the element is decoded to.
+var pairSize = int(unsafe.Sizeof(Pair{}))
+
+// errBudget is returned when decoding needs more than the budget.
+var errBudget = errors.New("Decode exceeded memory budget.")
+
+// rawDecoder decodes BSON which is in memory, rather than read from a stream,
+// so that memory for the values can come from somewhere other than the heap.
+// The results are the same as BSON Map or Slice.
+type rawDecoder struct {
+	arena *Arena // Memory comes from the arena if not nil.
+	alias bool   // Strings and binaries alias the BSON.
+	slice bool   // Documents are decoded to Slice, otherwise Map.
+	stack []Pair // Pairs of the documents being decoded.
+
+	// If limit then budget is the bytes which may still be allocated.
+	limit  bool
+	budget int
+
+	// If nest > 0 then documents deeper than nest are left encoded as BSON.
+	// The depth is of the document being decoded, the outermost is 1.
+	nest  int
+	depth int
+
+	// If salvage then errors are recorded in errs instead of returned.
+	salvage bool
+	errs    []error
+}
+
+// doc decodes the document at the start of bs to a Map or Slice. The base is
+// the offset of the document in the outermost one, for errors.
+func (this *rawDecoder) doc(bs []byte, path string, base int) (interface{},
+	error) {
+
+	start := len(this.stack)
+	this.depth++
+	defer func() {
+		this.stack = this.stack[:start]
+		this.depth--
+	}()
+	if err := this.elems(bs, path, base); err != nil {
+		return nil, err
+	}
+	pairs := this.stack[start:]
+	if this.slice {
+		if this.arena != nil {
+			s := this.arena.newSlice(len(pairs))
+			copy(s, pairs)
+			return s, nil
+		}
+		return append(Slice(nil), pairs...), nil
+	}
+	var m Map
+	if this.arena != nil {
+		m = this.arena.newMap()
+	} else {
+		m = make(Map, len(pairs))
+	}
+	for _, pair := range pairs {
+		m[pair.Key] = pair.Val
+	}
+	return m, nil
+}
+
+// array decodes a Array. Elements are ordered by index.
+func (this *rawDecoder) array(bs []byte, path string, base int) (Array,
+	error) {
+
+	start := len(this.stack)
+	defer func() {
+		this.stack = this.stack[:start]
+	}()
+	if err := this.elems(bs, path, base); err != nil {
+		return nil, err
+	}
+	pairs := this.stack[start:]
+
+	// BSON index names may not be ordered. Sort numerically.
+	sort.SliceStable(pairs, func(i, j int) bool {
+		a, aerr := strconv.Atoi(pairs[i].Key)
+		b, berr := strconv.Atoi(pairs[j].Key)
+		if aerr != nil || berr != nil {
+			return pairs[i].Key < pairs[j].Key
+		}
+		return a < b
+	})
+	var a Array
+	if this.arena != nil {
+		a = this.arena.newArray(len(pairs))
+	} else {
+		a = make(Array, len(pairs))
+	}
+	for i, pair := range pairs {
+		a[i] = pair.Val
+	}
+	return a, nil
+}
+
+// elems decodes the elements of the document at the start of bs and pushes
+// them on the stack. If salvaging, errors are recorded and decoding continues
+// from the next element which can be found.
+func (this *rawDecoder) elems(bs []byte, path string, base int) error {
+	docLen, err := rawDocLen(bs, 0)
+	if err != nil {
+		if !this.salvage {
+			return decodeError(path, "", base, 0, err)
+		}
+		this.errs = append(this.errs, decodeError(path, "", base, 0, err))
+
+		// Assume the document is the rest of bs.
+		docLen = len(bs)
+		if len(bs) == 0 || bs[len(bs)-1] != 0x00 {
+			docLen++
+		}
+	}
+	end := docLen - 1
+	for pos := 4; pos < end; {
+		pair, next, err := this.elem(bs, pos, end, path, base)
+		if err == nil {
+			this.stack = append(this.stack, pair)
+			pos = next
+			continue
+		}
+		if !this.salvage {
+			return err
+		}
+		this.errs = append(this.errs, err)
+		if next == 0 {
+			next = resync(bs, pos+1, end)
+		}
+		pos = next
+	}
+	return nil
+}
+
+// elem decodes the element at pos, in a document which ends at end. Returns
+// the position after the element. On error the position is 0 if the length of
+// the element isn't known.
+func (this *rawDecoder) elem(bs []byte, pos, end int, path string,
+	base int) (Pair, int, error) {
+
+	off := base + pos
+	t := bs[pos]
+	if t == 0x00 {
+		return Pair{}, 0, decodeError(path, "", base, 0, fmt.Errorf(
+			"Doc length %v but ended after %v bytes.", end+1, pos+1))
+	}
+	nul := bytes.IndexByte(bs[pos+1:end], 0x00)
+	if nul == -1 {
+		return Pair{}, 0, decodeError(path, "", off, t,
+			fmt.Errorf("%w, not terminated.", ErrInvalidKey))
+	}
+	if err := this.alloc(nul + pairSize); err != nil {
+		return Pair{}, 0, decodeError(path, "", off, t, err)
+	}
+	name := this.str(bs[pos+1 : pos+1+nul])
+	pos += 1 + nul + 1
+	n, err := rawValueLen(t, bs[pos:end])
+	if err != nil {
+		return Pair{}, 0, decodeError(path, name, off, t, err)
+	}
+	v, err := this.value(t, bs[pos:pos+n], catpath(path, name), base+pos)
+	if err != nil {
+		return Pair{}, pos + n, decodeError(path, name, off, t, err)
+	}
+	return Pair{name, v}, pos + n, nil
+}
+
+// resync returns the first position from pos which starts a run of elements
+// that ends exactly at end, or end if there's none.
+func resync(bs []byte, pos, end int) int {
+	for ; pos < end; pos++ {
+		if validElems(bs, pos, end) {
+			return pos
+		}
+	}
+	return end
+}
+
+// validElems returns true if the elements from pos end exactly at end. Values
+// aren't decoded, only their lengths are checked.
+func validElems(bs []byte, pos, end int) bool {
+	for pos < end {
+		t := bs[pos]
+		if t == 0x00 {
+			return false
+		}
+		nul := bytes.IndexByte(bs[pos+1:end], 0x00)
+		if nul == -1 {
+			return false
+		}
+		pos += 1 + nul + 1
+		n, err := rawValueLen(t, bs[pos:end])
+		if err != nil {
+			return false
+		}
+		pos += n
+	}
+	return pos == end
+}
+
+// value decodes a value of type t. The b is exactly the value, checked by
+// rawValueLen, and off is its offset in the outermost document.
+func (this *rawDecoder) value(t byte, b []byte, path string, off int) (
+	interface{}, error) {
+
+	switch t {
+	case _STRING, _EMBEDDED_DOCUMENT, _ARRAY, _BINARY_DATA, _REGEXP,
+		_DBPOINTER, _JAVASCRIPT, _SYMBOL, _JAVASCRIPT_SCOPE:
+
+		if err := this.alloc(len(b)); err != nil {
+			return nil, err
+		}
+	}
+	switch t {
+	case _FLOATING_POINT:
+		return Float(math.Float64frombits(binary.LittleEndian.Uint64(b))), nil
+	case _STRING:
+		return String(this.str(b[4 : len(b)-1])), nil
+	case _EMBEDDED_DOCUMENT:
+		if this.nest > 0 && this.depth >= this.nest {
+			return BSON(this.bytes(b)), nil
+		}
+		return this.doc(b, path, off)
+	case _ARRAY:
+		return this.array(b, path, off)
+	case _BINARY_DATA:
+		return Binary(this.bytes(b[5:])), nil
+	case _UNDEFINED:
+		return Undefined{}, nil
+	case _OBJECT_ID:
+		return ObjectId(this.bytes(b)), nil
+	case _BOOLEAN:
+		return Bool(b[0] == 0x01), nil
+	case _UTC_DATETIME:
+		return UTCDateTime(binary.LittleEndian.Uint64(b)), nil
+	case _NULL_VALUE:
+		return Null{}, nil
+	case _REGEXP:
+		i := bytes.IndexByte(b, 0x00)
+		return Regexp{
+			Pattern: this.str(b[:i]),
+			Options: this.str(b[i+1 : len(b)-1]),
+		}, nil
+	case _DBPOINTER:
+		return DBPointer{
+			Name:     this.str(b[4 : len(b)-13]),
+			ObjectId: ObjectId(this.bytes(b[len(b)-12:])),
+		}, nil
+	case _JAVASCRIPT:
+		return Javascript(this.str(b[4 : len(b)-1])), nil
+	case _SYMBOL:
+		return Symbol(this.str(b[4 : len(b)-1])), nil
+	case _JAVASCRIPT_SCOPE:
+		if len(b) < 4+5 {
+			return nil, fmt.Errorf("JavascriptScope %w.", ErrTruncated)
+		}
+		n, err := rawValueLen(_STRING, b[4:])
+		if err != nil {
+			return nil, err
+		}
+		scopeLen, err := rawDocLen(b, 4+n)
+		if err != nil {
+			return nil, err
+		}
+		if 4+n+scopeLen != len(b) {
+			return nil, fmt.Errorf("JavascriptScope length %v invalid.", len(b))
+		}
+		var scope interface{}
+		if this.nest > 0 && this.depth >= this.nest {
+			scope = BSON(this.bytes(b[4+n:]))
+		} else if scope, err = this.doc(b[4+n:], path, off+4+n); err != nil {
+			return nil, err
+		}
+		return JavascriptScope{
+			Javascript: this.str(b[8 : 4+n-1]),
+			Scope:      scope.(Doc),
+		}, nil
+	case _32BIT_INTEGER:
+		return Int32(binary.LittleEndian.Uint32(b)), nil
+	case _TIMESTAMP:
+		return Timestamp(binary.LittleEndian.Uint64(b)), nil
+	case _64BIT_INTEGER:
+		return Int64(binary.LittleEndian.Uint64(b)), nil
+	case _MIN_KEY:
+		return MinKey{}, nil
+	case _MAX_KEY:
+		return MaxKey{}, nil
+	}
+	return nil, fmt.Errorf("%w '%X'.", ErrUnsupportedType, t)
+}
+
+// alloc takes n bytes from the budget, or returns error if there isn't enough.
+func (this *rawDecoder) alloc(n int) error {
+	if !this.limit {
+		return nil
+	}
+	if n > this.budget {
+		return errBudget
+	}
+	this.budget -= n
+	return nil
+}
+
+// str returns the bytes as a string.
+func (this *rawDecoder) str(b []byte) string {
+	if this.alias {
+		if len(b) == 0 {
+			return ""
+		}
+		return unsafe.String(&b[0], len(b))
+	}
+	return string(b)
+}
+
+// bytes returns a copy of the bytes, or the bytes themselves if aliasing.
+func (this *rawDecoder) bytes(b []byte) []byte {
+	if this.alias {
+		return b[:len(b):len(b)]
+	}
+	if this.arena != nil {
+		return this.arena.bytes(b)
+	}
+	return append([]byte(nil), b...)
+}
@@ -7,12 +7,24 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"time"
 )
 
 // Reach in to document to get a value.
 // If dst is nil or a pointer to nil then a new object will be allocated.
 //
+// The path may be given as separate keys, as a dotted string, or a mix of the
+// two. These are the same:
+//   doc.Reach(&dst, "foo", "bar", "baz")
+//   doc.Reach(&dst, "foo.bar.baz")
+// A key which contains a dot is escaped with a backslash (e.g. "foo\\.bar" is
+// the single key "foo.bar"), and a backslash is escaped with a backslash.
+// EscapeKey does this.
+//
+// Array elements are reached by index. For example "items.3.price" reaches the
+// price of the fourth item.
+//
 // Returns true if object found, false if object not present.
 // Return error if there is a coercion problem.
 //
@@ -25,17 +37,17 @@ import (
 //   UTCDateTime -> int64, time.Time
 //   Javascript  -> string
 //   Symbol      -> string
-//   Int32       -> int32, int64
+//   Int32       -> int8, int16, int32, int64, int (if no overflow)
 //   Timestamp   -> int64, time.Time
-//   Int64       -> int64
+//   Int64       -> int64, int (if no overflow)
 //
 // To disable coercion use only bson types.
 func (this Map) Reach(dst interface{}, dot ...string) (bool, error) {
 	if dst == nil {
 		return false, errors.New("dst must not be nil.")
 	}
-	src := reach(this, dot...)
-	if src == nil {
+	src, ok := reach(this, splitPath(dot)...)
+	if !ok {
 		return false, nil
 	}
 	return assign(dst, src)
@@ -46,25 +58,291 @@ func (this Slice) Reach(dst interface{}, dot ...string) (bool, error) {
 	if dst == nil {
 		return false, errors.New("dst must not be nil.")
 	}
-	src := reach(this, dot...)
-	if src == nil {
+	src, ok := reach(this, splitPath(dot)...)
+	if !ok {
 		return false, nil
 	}
 	return assign(dst, src)
 }
 
-func reach(cur interface{}, dot ...string) interface{} {
+// Reach is the same as map Reach but traverses the raw BSON. Only the value at
+// the path is decoded, which is much cheaper than decoding the whole document
+// to reach one value.
+func (this BSON) Reach(dst interface{}, dot ...string) (bool, error) {
+	if dst == nil {
+		return false, errors.New("dst must not be nil.")
+	}
+	src, ok, err := rawReach(this, splitPath(dot)...)
+	if err != nil || !ok {
+		return false, err
+	}
+	return assign(dst, src)
+}
+
+// MustReach is the same as Reach but panics if the value isn't found or can't
+// be coerced. This is for required values.
+func (this Map) MustReach(dst interface{}, dot ...string) {
+	mustReach(this, dst, dot...)
+}
+
+// Same as map MustReach.
+func (this Slice) MustReach(dst interface{}, dot ...string) {
+	mustReach(this, dst, dot...)
+}
+
+// Same as map MustReach.
+func (this BSON) MustReach(dst interface{}, dot ...string) {
+	mustReach(this, dst, dot...)
+}
+
+// ReachOr is the same as Reach but sets dst to def if the value isn't found.
+// This is for optional values. The def must be assignable to what dst points
+// to, or be a BSON type which can be coerced as with Reach.
+func (this Map) ReachOr(dst, def interface{}, dot ...string) error {
+	return reachOr(this, dst, def, dot...)
+}
+
+// Same as map ReachOr.
+func (this Slice) ReachOr(dst, def interface{}, dot ...string) error {
+	return reachOr(this, dst, def, dot...)
+}
+
+// Same as map ReachOr.
+func (this BSON) ReachOr(dst, def interface{}, dot ...string) error {
+	return reachOr(this, dst, def, dot...)
+}
+
+// Reacher is a document which can be reached in to. Map, Slice and BSON are
+// Reachers.
+type Reacher interface {
+	Reach(dst interface{}, dot ...string) (bool, error)
+}
+
+// ReachAs is the same as Reach but returns the value instead of assigning it
+// to a dst. For example:
+//   port, ok, err := ReachAs[int](doc, "server", "port")
+func ReachAs[T any](doc Reacher, dot ...string) (T, bool, error) {
+	var dst T
+	ok, err := doc.Reach(&dst, dot...)
+	return dst, ok, err
+}
+
+func mustReach(doc Reacher, dst interface{}, dot ...string) {
+	ok, err := doc.Reach(dst, dot...)
+	if err != nil {
+		panic(fmt.Errorf("%v, %v", joinPath(dot), err))
+	}
+	if !ok {
+		panic(fmt.Errorf("%v, %w.", joinPath(dot), ErrNotFound))
+	}
+}
+
+func reachOr(doc Reacher, dst, def interface{}, dot ...string) error {
+	ok, err := doc.Reach(dst, dot...)
+	if err != nil || ok {
+		return err
+	}
+	_, err = assign(dst, def)
+	return err
+}
+
+// Has returns true if the path exists in the document. A value which is Null
+// exists. The path is the same as with Reach.
+func (this Map) Has(dot ...string) bool {
+	_, ok := reach(this, splitPath(dot)...)
+	return ok
+}
+
+// Same as map Has.
+func (this Slice) Has(dot ...string) bool {
+	_, ok := reach(this, splitPath(dot)...)
+	return ok
+}
+
+// Same as map Has. Returns false if the BSON is invalid.
+func (this BSON) Has(dot ...string) bool {
+	_, ok, err := rawReach(this, splitPath(dot)...)
+	return ok && err == nil
+}
+
+// Exists is the same as Has. It's true for a value which is Null, which
+// Reach can't tell apart from a missing value when dst is a Go type.
+func (this Map) Exists(dot ...string) bool {
+	return this.Has(dot...)
+}
+
+// Same as map Exists.
+func (this Slice) Exists(dot ...string) bool {
+	return this.Has(dot...)
+}
+
+// Same as map Exists.
+func (this BSON) Exists(dot ...string) bool {
+	return this.Has(dot...)
+}
+
+// TypeAt returns the BSON type of the value at the path. Returns false if the
+// path doesn't exist, or the value isn't a type which can be encoded.
+func (this Map) TypeAt(dot ...string) (Type, bool) {
+	v, ok := reach(this, splitPath(dot)...)
+	if !ok {
+		return 0, false
+	}
+	return typeOf(v)
+}
+
+// Same as map TypeAt.
+func (this Slice) TypeAt(dot ...string) (Type, bool) {
+	v, ok := reach(this, splitPath(dot)...)
+	if !ok {
+		return 0, false
+	}
+	return typeOf(v)
+}
+
+// Same as map TypeAt. The value isn't decoded unless the path continues in to
+// a value which isn't a document. Returns false if the BSON is invalid.
+func (this BSON) TypeAt(dot ...string) (Type, bool) {
+	dot = splitPath(dot)
+	if len(dot) == 0 {
+		return TypeEmbeddedDocument, true
+	}
+	e, rest, ok, err := rawLookup(this, dot...)
+	if err != nil || !ok {
+		return 0, false
+	}
+	if len(rest) == 0 {
+		return Type(e.Type), true
+	}
+	v, ok, err := rawReach(this, dot...)
+	if err != nil || !ok {
+		return 0, false
+	}
+	return typeOf(v)
+}
+
+// Set sets the value at the path, which is the same as with Reach.
+// Intermediate documents are created as Maps if they don't exist. Returns error
+// if the path passes through a value which is not a Map or Slice.
+func (this Map) Set(val interface{}, dot ...string) error {
+	if len(dot) == 0 {
+		return errors.New("path must not be empty.")
+	}
+	_, err := set(this, "", val, splitPath(dot)...)
+	return err
+}
+
+// Delete removes the value at the path. Returns true if the value existed.
+func (this Map) Delete(dot ...string) bool {
+	_, ok := del(this, splitPath(dot)...)
+	return ok
+}
+
+// set sets the value at the path in the document. Returns the document, which
+// is a new value if a Slice was appended to.
+func set(cur interface{}, path string, val interface{}, dot ...string) (
+	interface{}, error) {
+
+	if len(dot) == 0 {
+		return val, nil
+	}
+	name := dot[0]
+	path = catpath(path, name)
+	switch curt := cur.(type) {
+	case Map:
+		next, ok := curt[name]
+		if !ok && len(dot) > 1 {
+			next = Map{}
+		}
+		v, err := set(next, path, val, dot[1:]...)
+		if err != nil {
+			return nil, err
+		}
+		curt[name] = v
+		return curt, nil
+	case Slice:
+		for i := range curt {
+			if curt[i].Key == name {
+				v, err := set(curt[i].Val, path, val, dot[1:]...)
+				if err != nil {
+					return nil, err
+				}
+				curt[i].Val = v
+				return curt, nil
+			}
+		}
+		var next interface{}
+		if len(dot) > 1 {
+			next = Map{}
+		}
+		v, err := set(next, path, val, dot[1:]...)
+		if err != nil {
+			return nil, err
+		}
+		return append(curt, Pair{Key: name, Val: v}), nil
+	}
+	return nil, fmt.Errorf("%v, cannot set in %T.", path, cur)
+}
+
+// del deletes the value at the path in the document. Returns the document,
+// which is a new value if deleted from a Slice.
+func del(cur interface{}, dot ...string) (interface{}, bool) {
+	if len(dot) == 0 {
+		return cur, false
+	}
+	name := dot[0]
+	switch curt := cur.(type) {
+	case Map:
+		next, ok := curt[name]
+		if !ok {
+			return curt, false
+		}
+		if len(dot) == 1 {
+			delete(curt, name)
+			return curt, true
+		}
+		v, ok := del(next, dot[1:]...)
+		curt[name] = v
+		return curt, ok
+	case Slice:
+		for i := range curt {
+			if curt[i].Key != name {
+				continue
+			}
+			if len(dot) == 1 {
+				return append(curt[:i:i], curt[i+1:]...), true
+			}
+			v, ok := del(curt[i].Val, dot[1:]...)
+			curt[i].Val = v
+			return curt, ok
+		}
+	}
+	return cur, false
+}
+
+// reach returns the value at the path. Returns false if not found.
+func reach(cur interface{}, dot ...string) (interface{}, bool) {
 	path := ""
-	for _, name := range dot {
+	for i, name := range dot {
 		path = catpath(path, name)
 		switch curt := cur.(type) {
-		case Float, String, Array, Binary, Undefined, ObjectId, Bool, UTCDateTime,
+		case Float, String, Binary, Undefined, ObjectId, Bool, UTCDateTime,
 			Null, Javascript, Symbol, Int32, Timestamp, Int64, MinKey, MaxKey:
-			return nil
+			return nil, false
+		case BSON:
+			// Document left encoded, such as from MapNoNest.
+			v, ok, err := rawReach(curt, dot[i:]...)
+			return v, ok && err == nil
+		case Array:
+			i, err := strconv.Atoi(name)
+			if err != nil || i < 0 || i >= len(curt) {
+				return nil, false
+			}
+			cur = curt[i]
 		case Map:
 			a, ok := curt[name]
 			if !ok {
-				return nil
+				return nil, false
 			}
 			cur = a
 		case Slice:
@@ -72,12 +350,12 @@ func reach(cur interface{}, dot ...string) interface{} {
 			for _, v := range curt {
 				if v.Key == name {
 					ok = true
-					cur = v
+					cur = v.Val
 					break
 				}
 			}
 			if !ok {
-				return nil
+				return nil, false
 			}
 		case Regexp:
 			if name == "Pattern" {
@@ -85,7 +363,7 @@ func reach(cur interface{}, dot ...string) interface{} {
 			} else if name == "Options" {
 				cur = curt.Options
 			} else {
-				return nil
+				return nil, false
 			}
 		case DBPointer:
 			if name == "Name" {
@@ -93,7 +371,7 @@ func reach(cur interface{}, dot ...string) interface{} {
 			} else if name == "ObjectId" {
 				cur = curt.ObjectId
 			} else {
-				return nil
+				return nil, false
 			}
 		case JavascriptScope:
 			if name == "Javascript" {
@@ -101,13 +379,13 @@ func reach(cur interface{}, dot ...string) interface{} {
 			} else if name == "Scope" {
 				cur = curt.Scope
 			} else {
-				return nil
+				return nil, false
 			}
 		default:
-			return nil
+			return nil, false
 		}
 	}
-	return cur
+	return cur, true
 }
 
 func assignError(dst reflect.Value, src interface{}) error {
@@ -124,6 +402,14 @@ func assign(dst, src interface{}) (bool, error) {
 		}
 		dstrv.SetFloat(float64(srct))
 	case String:
+		if dstrv.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(string(srct))
+			if err != nil {
+				return false, err
+			}
+			dstrv.SetInt(int64(d))
+			break
+		}
 		if dstrv.Kind() != reflect.String {
 			return false, assignError(dstrv, src)
 		}
@@ -142,6 +428,25 @@ func assign(dst, src interface{}) (bool, error) {
 		default:
 			return false, assignError(dstrv, src)
 		}
+	case BSON:
+		switch dstrv.Interface().(type) {
+		case BSON:
+			dstrv.Set(reflect.ValueOf(srct))
+		case Map:
+			m, err := srct.Map()
+			if err != nil {
+				return false, err
+			}
+			dstrv.Set(reflect.ValueOf(m))
+		case Slice:
+			s, err := srct.Slice()
+			if err != nil {
+				return false, err
+			}
+			dstrv.Set(reflect.ValueOf(s))
+		default:
+			return false, assignError(dstrv, src)
+		}
 	case Array:
 		switch dstrv.Interface().(type) {
 		case Array:
@@ -155,14 +460,18 @@ func assign(dst, src interface{}) (bool, error) {
 			}
 		}
 	case Binary:
-		if dstrv.Kind() != reflect.Slice && dstrv.Elem().Kind() != reflect.Uint8 {
+		if dstrv.Kind() != reflect.Slice ||
+			dstrv.Type().Elem().Kind() != reflect.Uint8 {
+
 			return false, assignError(dstrv, src)
 		}
 		dstrv.SetBytes([]byte(srct))
 	case Undefined:
 		// Nothing to do.
 	case ObjectId:
-		if dstrv.Kind() != reflect.Slice && dstrv.Elem().Kind() != reflect.Uint8 {
+		if dstrv.Kind() != reflect.Slice ||
+			dstrv.Type().Elem().Kind() != reflect.Uint8 {
+
 			return false, assignError(dstrv, src)
 		}
 		dstrv.SetBytes([]byte(srct))
@@ -174,9 +483,7 @@ func assign(dst, src interface{}) (bool, error) {
 	case UTCDateTime:
 		switch dstrv.Interface().(type) {
 		case time.Time:
-			// BSON time is milliseconds since unix epoch.
-			// Go time is nanoseconds since unix epoch.
-			dstrv.Set(reflect.ValueOf(time.Unix(0, int64(srct)*1e3)))
+			dstrv.Set(reflect.ValueOf(srct.Time()))
 		default:
 			if dstrv.Kind() != reflect.Int64 {
 				return false, assignError(dstrv, src)
@@ -217,16 +524,19 @@ func assign(dst, src interface{}) (bool, error) {
 			return false, assignError(dstrv, src)
 		}
 	case Int32:
-		if dstrv.Kind() != reflect.Int32 && dstrv.Kind() != reflect.Int64 {
+		switch dstrv.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		default:
+			return false, assignError(dstrv, src)
+		}
+		if dstrv.OverflowInt(int64(srct)) {
 			return false, assignError(dstrv, src)
 		}
 		dstrv.SetInt(int64(srct))
 	case Timestamp:
 		switch dstrv.Interface().(type) {
 		case time.Time:
-			// BSON time is milliseconds since unix epoch.
-			// Go time is nanoseconds since unix epoch.
-			dstrv.Set(reflect.ValueOf(time.Unix(0, int64(srct)*1e3)))
+			dstrv.Set(reflect.ValueOf(srct.Time()))
 		default:
 			if dstrv.Kind() != reflect.Int64 {
 				return false, assignError(dstrv, src)
@@ -234,7 +544,10 @@ func assign(dst, src interface{}) (bool, error) {
 			dstrv.SetInt(int64(srct))
 		}
 	case Int64:
-		if dstrv.Kind() != reflect.Int64 {
+		if dstrv.Kind() != reflect.Int64 && dstrv.Kind() != reflect.Int {
+			return false, assignError(dstrv, src)
+		}
+		if dstrv.OverflowInt(int64(srct)) {
 			return false, assignError(dstrv, src)
 		}
 		dstrv.SetInt(int64(srct))
@@ -242,6 +555,13 @@ func assign(dst, src interface{}) (bool, error) {
 		// Nothing to do.
 	case MaxKey:
 		// Nothing to do.
+	default:
+		// Not a BSON type, such as the Pattern of a Regexp.
+		srcrv := reflect.ValueOf(src)
+		if !srcrv.IsValid() || !srcrv.Type().AssignableTo(dstrv.Type()) {
+			return false, assignError(dstrv, src)
+		}
+		dstrv.Set(srcrv)
 	}
 	return true, nil
 }
@@ -0,0 +1,74 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"sync"
+)
+
+// EncodeFunc converts a value of a registered type to a value which can be
+// encoded, usually a BSON type.
+type EncodeFunc func(src interface{}) (interface{}, error)
+
+// DecodeFunc decodes a BSON value to dst, which is a pointer to a value of the
+// registered type. Embedded documents are BSON.
+type DecodeFunc func(src, dst interface{}) error
+
+// encoders and decoders are the registered funcs, keyed by reflect.Type.
+var encoders, decoders sync.Map
+
+// RegisterEncoder registers a func which encodes values of type t. This is for
+// types which can't implement Marshaler, such as types from other packages. A
+// nil fn removes the func. For example:
+//   bson.RegisterEncoder(reflect.TypeOf(decimal.Decimal{}),
+//       func(src interface{}) (interface{}, error) {
+//           return bson.String(src.(decimal.Decimal).String()), nil
+//       })
+func RegisterEncoder(t reflect.Type, fn EncodeFunc) {
+	if fn == nil {
+		encoders.Delete(t)
+		return
+	}
+	encoders.Store(t, fn)
+}
+
+// RegisterDecoder registers a func which decodes to values of type t. A nil fn
+// removes the func.
+func RegisterDecoder(t reflect.Type, fn DecodeFunc) {
+	if fn == nil {
+		decoders.Delete(t)
+		return
+	}
+	decoders.Store(t, fn)
+}
+
+// registeredEncode converts src with the registered EncodeFunc for its type.
+// Returns false if there's none.
+func registeredEncode(path string, src interface{}) (interface{}, bool, error) {
+	fn, ok := encoders.Load(reflect.TypeOf(src))
+	if !ok {
+		return nil, false, nil
+	}
+	v, err := fn.(EncodeFunc)(src)
+	if err != nil {
+		return nil, true, prefixEncodeError(path, err)
+	}
+	return v, true, nil
+}
+
+// registeredDecode decodes src to dst with the registered DecodeFunc for the
+// type of dst, which must be addressable. Returns false if there's none.
+func registeredDecode(path string, src interface{}, dst reflect.Value) (bool,
+	error) {
+
+	fn, ok := decoders.Load(dst.Type())
+	if !ok {
+		return false, nil
+	}
+	if err := fn.(DecodeFunc)(src, dst.Addr().Interface()); err != nil {
+		return true, prefixDecodeError(path, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,66 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	ipType := reflect.TypeOf(net.IP{})
+	RegisterEncoder(ipType, func(src interface{}) (interface{}, error) {
+		return String(src.(net.IP).String()), nil
+	})
+	RegisterDecoder(ipType, func(src, dst interface{}) error {
+		s, ok := src.(String)
+		if !ok {
+			return fmt.Errorf("cannot decode %T to net.IP.", src)
+		}
+		ip := net.ParseIP(string(s))
+		if ip == nil {
+			return fmt.Errorf("invalid IP %v.", s)
+		}
+		*dst.(*net.IP) = ip
+		return nil
+	})
+	defer RegisterEncoder(ipType, nil)
+	defer RegisterDecoder(ipType, nil)
+
+	type host struct {
+		Addr  net.IP
+		Addrs []net.IP
+	}
+	src := host{Addr: net.ParseIP("10.0.0.1"), Addrs: []net.IP{net.ParseIP("::1")}}
+	bs, err := EncodeStruct(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{"Addr": String("10.0.0.1"), "Addrs": Array{String("::1")}}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+	var dst host
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !dst.Addr.Equal(src.Addr) || len(dst.Addrs) != 1 || !dst.Addrs[0].Equal(src.Addrs[0]) {
+		t.Fatal(dst, src)
+	}
+	if err := DecodeStruct(Map{"Addr": String("x")}.MustEncode(), &dst); err == nil {
+		t.Fatal("Expected DecodeFunc error.")
+	}
+
+	// Unregistered, net.IP can't be coerced.
+	RegisterEncoder(ipType, nil)
+	if _, err = EncodeStruct(src); err == nil {
+		t.Fatal("Expected error for unregistered type.")
+	}
+}
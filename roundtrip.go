@@ -0,0 +1,142 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// CheckRoundTrip returns error if the document doesn't survive a round trip.
+// The document is encoded, then decoded to a Slice and to a Map which are
+// encoded again. The Slice must encode exactly the same. The Map must encode
+// the same once the keys of both are sorted. The error has the path of the
+// first element which differs. For example:
+//   Slice round trip differs at a.b.
+// This is a invariant for property tests, such as with RandomMap or quick.
+func CheckRoundTrip(doc Doc) error {
+	bs, err := doc.Encode()
+	if err != nil {
+		return err
+	}
+	s, err := bs.Slice()
+	if err != nil {
+		return err
+	}
+	sbs, err := s.Encode()
+	if err != nil {
+		return err
+	}
+	if path, ok := rawDiff(bs, 0, sbs, 0, ""); !ok {
+		return roundTripError("Slice", path)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		return err
+	}
+	want, err := canonicalDoc(s).Encode()
+	if err != nil {
+		return err
+	}
+	got, err := canonicalDoc(m).Encode()
+	if err != nil {
+		return err
+	}
+	if path, ok := rawDiff(want, 0, got, 0, ""); !ok {
+		return roundTripError("Map", path)
+	}
+	return nil
+}
+
+func roundTripError(doc, path string) error {
+	if path == "" {
+		return fmt.Errorf("%v round trip differs.", doc)
+	}
+	return fmt.Errorf("%v round trip differs at %v.", doc, path)
+}
+
+// canonicalDoc returns a Slice with the keys of the document, and of all the
+// documents nested in it, sorted.
+func canonicalDoc(doc Doc) Slice {
+	var s Slice
+	switch dt := doc.(type) {
+	case Map:
+		s = make(Slice, 0, len(dt))
+		for k, v := range dt {
+			s = append(s, Pair{Key: k, Val: v})
+		}
+	case Slice:
+		s = make(Slice, 0, len(dt))
+		s = append(s, dt...)
+	default:
+		return nil
+	}
+	for i := range s {
+		s[i].Val = canonicalVal(s[i].Val)
+	}
+	s.SortKeys()
+	return s
+}
+
+func canonicalVal(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case Map, Slice:
+		return canonicalDoc(vt.(Doc))
+	case Array:
+		a := make(Array, len(vt))
+		for i, e := range vt {
+			a[i] = canonicalVal(e)
+		}
+		return a
+	case JavascriptScope:
+		if vt.Scope == nil {
+			return vt
+		}
+		if _, ok := vt.Scope.(BSON); ok {
+			return vt
+		}
+		return JavascriptScope{Javascript: vt.Javascript,
+			Scope: canonicalDoc(vt.Scope)}
+	}
+	return v
+}
+
+// rawDiff compares the documents at offsets aoff in a and boff in b. Returns
+// false and the path of the first element which differs, or the path of the
+// document if it has fewer elements.
+func rawDiff(a []byte, aoff int, b []byte, boff int, path string) (string,
+	bool) {
+
+	ae, err := rawElements(a, aoff)
+	if err != nil {
+		return path, false
+	}
+	be, err := rawElements(b, boff)
+	if err != nil {
+		return path, false
+	}
+	for i := 0; i < len(ae) || i < len(be); i++ {
+		switch {
+		case i >= len(ae):
+			return catpath(path, be[i].Name), false
+		case i >= len(be):
+			return catpath(path, ae[i].Name), false
+		}
+		x, y := ae[i], be[i]
+		p := catpath(path, x.Name)
+		if x.Name != y.Name || x.Type != y.Type {
+			return p, false
+		}
+		if x.Type == _EMBEDDED_DOCUMENT || x.Type == _ARRAY {
+			if p, ok := rawDiff(a, x.Value, b, y.Value, p); !ok {
+				return p, false
+			}
+			continue
+		}
+		if !bytes.Equal(a[x.Value:x.End], b[y.Value:y.End]) {
+			return p, false
+		}
+	}
+	return "", true
+}
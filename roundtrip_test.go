@@ -0,0 +1,30 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCheckRoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		if err := CheckRoundTrip(RandomMap(rnd, RandomOptions{})); err != nil {
+			t.Fatal(i, err)
+		}
+		if err := CheckRoundTrip(RandomSlice(rnd, RandomOptions{})); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+
+	// Duplicate keys are lost when decoded to a Map.
+	doc := Slice{
+		{"x", Slice{{"a", Int32(1)}, {"a", Int32(2)}}},
+	}
+	err := CheckRoundTrip(doc)
+	if err == nil || err.Error() != "Map round trip differs at x.a." {
+		t.Fatal(err)
+	}
+}
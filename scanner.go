@@ -0,0 +1,122 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Scanner reads documents from a stream, like bufio.Scanner reads lines. The
+// buffer is reused for each document. For example:
+//   sc := bson.NewScanner(rd)
+//   for sc.Scan() {
+//       m, err := sc.Map()
+//       ...
+//   }
+//   if err := sc.Err(); err != nil {
+//       return err
+//   }
+type Scanner struct {
+	rd  io.Reader
+	buf []byte
+	max int32
+	bs  BSON
+	err error
+}
+
+// NewScanner returns a Scanner which reads from rd.
+func NewScanner(rd io.Reader) *Scanner {
+	return &Scanner{rd: rd, max: maxDocLen}
+}
+
+// Buffer sets the initial buffer and the largest document which may be read,
+// which can't be larger than the maximum BSON document size. Must be called
+// before Scan.
+func (this *Scanner) Buffer(buf []byte, max int) {
+	this.buf = buf[:0]
+	if max > 0 && max < maxDocLen {
+		this.max = int32(max)
+	}
+}
+
+// Scan reads the next document, which is available from Bytes, Map or Slice.
+// Returns false at the end of the stream or on error, see Err.
+func (this *Scanner) Scan() bool {
+	if this.err != nil {
+		return false
+	}
+	bs, err := readOne(this.rd, this.buf, this.max)
+	if err != nil {
+		this.bs = nil
+		this.err = err
+		return false
+	}
+	this.buf = bs
+	this.bs = bs
+	return true
+}
+
+// Bytes returns the document read by Scan. It's overwritten by the next Scan,
+// so must be copied if it's kept.
+func (this *Scanner) Bytes() BSON {
+	return this.bs
+}
+
+// Map decodes the document read by Scan.
+func (this *Scanner) Map() (Map, error) {
+	return this.bs.Map()
+}
+
+// Slice decodes the document read by Scan.
+func (this *Scanner) Slice() (Slice, error) {
+	return this.bs.Slice()
+}
+
+// Err returns the error which stopped Scan, or nil if it was the end of the
+// stream.
+func (this *Scanner) Err() error {
+	if this.err == io.EOF {
+		return nil
+	}
+	return this.err
+}
+
+// SplitBSON is a bufio.SplitFunc which splits a stream in to documents, so a
+// bufio.Scanner can read BSON. For example:
+//   sc := bufio.NewScanner(rd)
+//   sc.Split(bson.SplitBSON)
+//   sc.Buffer(nil, 16*1024*1024)
+//   for sc.Scan() {
+//       m, err := bson.BSON(sc.Bytes()).Map()
+//       ...
+//   }
+// The bufio.Scanner maximum token size is 64KB unless it's set with Buffer.
+func SplitBSON(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if len(data) < 4 {
+		if atEOF {
+			return 0, nil, fmt.Errorf("Doc %w.", ErrTruncated)
+		}
+		return 0, nil, nil
+	}
+	docLen := int32(binary.LittleEndian.Uint32(data))
+	if docLen > maxDocLen {
+		return 0, nil, fmt.Errorf("%w.", ErrDocTooLarge)
+	}
+	if docLen < 5 {
+		return 0, nil, errors.New("Doc smaller than minimum size.")
+	}
+	if len(data) < int(docLen) {
+		if atEOF {
+			return 0, nil, fmt.Errorf("Doc %w.", ErrTruncated)
+		}
+		return 0, nil, nil
+	}
+	return int(docLen), data[:docLen], nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < 3; i++ {
+		buf.Write(Map{"i": Int32(i)}.MustEncode())
+	}
+	b := buf.Bytes()
+	sc := NewScanner(bytes.NewReader(b))
+	var i Int32
+	for ; sc.Scan(); i++ {
+		m, err := sc.Map()
+		if err != nil || m["i"] != i {
+			t.Fatal(err, m)
+		}
+	}
+	if err := sc.Err(); err != nil || i != 3 {
+		t.Fatal(err, i)
+	}
+	if sc.Scan() {
+		t.Fatal("scanned after end")
+	}
+
+	// Max document size.
+	sc = NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 64), 5)
+	if sc.Scan() || !errors.Is(sc.Err(), ErrDocTooLarge) {
+		t.Fatal(sc.Err())
+	}
+
+	// Truncated.
+	sc = NewScanner(bytes.NewReader(b[:len(b)-1]))
+	for sc.Scan() {
+	}
+	if !errors.Is(sc.Err(), ErrTruncated) {
+		t.Fatal(sc.Err())
+	}
+}
+
+func TestSplitBSON(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	for i := 0; i < 3; i++ {
+		buf.Write(Map{"s": String(strings.Repeat("x", i*10000))}.MustEncode())
+	}
+	b := buf.Bytes()
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Split(SplitBSON)
+	n := 0
+	for ; sc.Scan(); n++ {
+		m, err := BSON(sc.Bytes()).Map()
+		if err != nil || len(m["s"].(String)) != n*10000 {
+			t.Fatal(err, n)
+		}
+	}
+	if err := sc.Err(); err != nil || n != 3 {
+		t.Fatal(err, n)
+	}
+
+	// Truncated.
+	sc = bufio.NewScanner(bytes.NewReader(b[:len(b)-1]))
+	sc.Split(SplitBSON)
+	for sc.Scan() {
+	}
+	if !errors.Is(sc.Err(), ErrTruncated) {
+		t.Fatal(sc.Err())
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Schema describes the values allowed at a path. The zero value allows any
+// value. For example:
+//   min := 0.0
+//   schema := &bson.Schema{
+//       Required: []string{"_id", "name"},
+//       Fields: map[string]*bson.Schema{
+//           "_id":  {Types: []bson.Type{bson.TypeObjectId}},
+//           "name": {Types: []bson.Type{bson.TypeString}},
+//           "age":  {Types: []bson.Type{bson.TypeInt32}, Min: &min},
+//           "tags": {Items: &bson.Schema{Types: []bson.Type{bson.TypeString}}},
+//       },
+//   }
+type Schema struct {
+	// Types allowed. Any type if empty.
+	Types []Type
+
+	// Min and Max are the range of Int32, Int64 and Float values. Nil for no
+	// limit.
+	Min *float64
+	Max *float64
+
+	// MinLen and MaxLen are the range of lengths of String (in characters),
+	// Binary, Array and embedded document (number of keys) values. Nil for no
+	// limit.
+	MinLen *int
+	MaxLen *int
+
+	// Required keys of a embedded document.
+	Required []string
+
+	// Fields are the schemas of keys of a embedded document.
+	Fields map[string]*Schema
+
+	// Closed makes keys which aren't in Fields a violation.
+	Closed bool
+
+	// Items is the schema of Array elements.
+	Items *Schema
+}
+
+// Violation is a value which doesn't match a Schema.
+type Violation struct {
+	Path string
+	Msg  string
+}
+
+// String returns the path and message, the same as errors from this package.
+func (this Violation) String() string {
+	if this.Path == "" {
+		return this.Msg
+	}
+	return this.Path + ", " + this.Msg
+}
+
+// Validate returns all the values in the document which don't match the
+// schema, ordered by path. Returns error if the document is invalid BSON.
+func (this *Schema) Validate(doc Doc) ([]Violation, error) {
+	var vs []Violation
+	if err := this.validate("", doc, &vs); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(vs, func(i, j int) bool {
+		return vs[i].Path < vs[j].Path
+	})
+	return vs, nil
+}
+
+// validate appends the violations of the value and the values nested in it.
+func (this *Schema) validate(path string, v interface{}, vs *[]Violation) error {
+	add := func(format string, a ...interface{}) {
+		*vs = append(*vs, Violation{path, fmt.Sprintf(format, a...)})
+	}
+	if bs, ok := v.(BSON); ok {
+		s, err := bs.SliceNoNest()
+		if err != nil {
+			return fmt.Errorf("%v, %v", path, err)
+		}
+		v = s
+	}
+	t, ok := typeOf(v)
+	if !ok {
+		add("%T not a BSON type.", v)
+		return nil
+	}
+	if len(this.Types) != 0 && !hasType(this.Types, t) {
+		add("type %v not allowed.", t)
+		return nil
+	}
+
+	// Range.
+	f, isNum := 0.0, true
+	switch vt := v.(type) {
+	case Int32:
+		f = float64(vt)
+	case Int64:
+		f = float64(vt)
+	case Float:
+		f = float64(vt)
+	default:
+		isNum = false
+	}
+	if isNum && this.Min != nil && f < *this.Min {
+		add("%v less than minimum %v.", v, *this.Min)
+	}
+	if isNum && this.Max != nil && f > *this.Max {
+		add("%v greater than maximum %v.", v, *this.Max)
+	}
+
+	// Length.
+	n := -1
+	switch vt := v.(type) {
+	case String:
+		n = utf8.RuneCountInString(string(vt))
+	case Binary:
+		n = len(vt)
+	case Array:
+		n = len(vt)
+	case Map:
+		n = len(vt)
+	case Slice:
+		n = len(vt)
+	}
+	if n != -1 && this.MinLen != nil && n < *this.MinLen {
+		add("length %v less than minimum %v.", n, *this.MinLen)
+	}
+	if n != -1 && this.MaxLen != nil && n > *this.MaxLen {
+		add("length %v greater than maximum %v.", n, *this.MaxLen)
+	}
+
+	// Nested.
+	switch vt := v.(type) {
+	case Map, Slice:
+		return this.validateDoc(path, sortedPairs(vt), vs)
+	case Array:
+		if this.Items == nil {
+			return nil
+		}
+		for i, e := range vt {
+			if err := this.Items.validate(catpath(path, strconv.Itoa(i)), e,
+				vs); err != nil {
+
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateDoc checks the keys of a embedded document.
+func (this *Schema) validateDoc(path string, s Slice, vs *[]Violation) error {
+	have := make(map[string]bool, len(s))
+	for _, pair := range s {
+		have[pair.Key] = true
+	}
+	for _, key := range this.Required {
+		if !have[key] {
+			*vs = append(*vs, Violation{catpath(path, key), "required."})
+		}
+	}
+	for _, pair := range s {
+		fs, ok := this.Fields[pair.Key]
+		if !ok {
+			if this.Closed {
+				*vs = append(*vs, Violation{catpath(path, pair.Key),
+					"not allowed."})
+			}
+			continue
+		}
+		if err := fs.validate(catpath(path, pair.Key), pair.Val, vs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedPairs returns the pairs of a Slice as is, or of a Map sorted by key.
+func sortedPairs(doc interface{}) Slice {
+	m, ok := doc.(Map)
+	if !ok {
+		return doc.(Slice)
+	}
+	s := make(Slice, 0, len(m))
+	for k, v := range m {
+		s = append(s, Pair{k, v})
+	}
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].Key < s[j].Key
+	})
+	return s
+}
+
+// hasType returns true if t is in types.
+func hasType(types []Type, t Type) bool {
+	for _, e := range types {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	min, max := 0.0, 150.0
+	one, three := 1, 3
+	schema := &Schema{
+		Required: []string{"_id", "name"},
+		Closed:   true,
+		Fields: map[string]*Schema{
+			"_id":  {Types: []Type{TypeObjectId}},
+			"name": {Types: []Type{TypeString}, MinLen: &one, MaxLen: &three},
+			"age":  {Types: []Type{TypeInt32, TypeInt64}, Min: &min, Max: &max},
+			"address": {
+				Required: []string{"city"},
+				Fields: map[string]*Schema{
+					"city": {Types: []Type{TypeString}},
+				},
+			},
+			"tags": {
+				Types: []Type{TypeArray},
+				Items: &Schema{Types: []Type{TypeString}},
+			},
+		},
+	}
+
+	// Valid.
+	doc := Map{
+		"_id":     ObjectId("0123456789ab"),
+		"name":    String("bob"),
+		"age":     Int32(30),
+		"address": Map{"city": String("x"), "zip": String("y")},
+		"tags":    Array{String("a")},
+	}
+	vs, err := schema.Validate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vs) != 0 {
+		t.Fatal(vs)
+	}
+
+	// All the violations, also in BSON.
+	doc = Map{
+		"name":    String("alice"),
+		"age":     Int64(200),
+		"address": Slice{{"zip", String("y")}},
+		"tags":    Array{String("a"), Int32(1)},
+		"extra":   Bool(true),
+	}
+	exp := []Violation{
+		{"_id", "required."},
+		{"address.city", "required."},
+		{"age", "200 greater than maximum 150."},
+		{"extra", "not allowed."},
+		{"name", "length 5 greater than maximum 3."},
+		{"tags.1", "type Int32 not allowed."},
+	}
+	vs, err = schema.Validate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vs, exp) {
+		t.Fatal(vs)
+	}
+	bs, err := doc.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vs, err = schema.Validate(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vs, exp) {
+		t.Fatal(vs)
+	}
+	if s := vs[0].String(); s != "_id, required." {
+		t.Fatal(s)
+	}
+
+	// Invalid BSON.
+	if _, err := schema.Validate(BSON{1, 2}); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
+
+func TestSchemaJSONSchema(t *testing.T) {
+	min, one := 0.0, 1
+	schema := &Schema{
+		Required: []string{"_id"},
+		Closed:   true,
+		Fields: map[string]*Schema{
+			"_id": {Types: []Type{TypeObjectId}},
+			"age": {Types: []Type{TypeInt32, TypeNull}, Min: &min},
+			"tags": {Types: []Type{TypeArray}, MinLen: &one,
+				Items: &Schema{Types: []Type{TypeString}}},
+		},
+	}
+	exp := map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"required":             []string{"_id"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"_id": map[string]interface{}{"type": "object"},
+			"age": map[string]interface{}{
+				"type":    []string{"integer", "null"},
+				"minimum": 0.0,
+			},
+			"tags": map[string]interface{}{
+				"type":     "array",
+				"minItems": 1,
+				"items":    map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	if js := schema.JSONSchema(); !reflect.DeepEqual(js, exp) {
+		t.Fatal(js)
+	}
+	expMongo := Map{
+		"required":             Array{String("_id")},
+		"additionalProperties": Bool(false),
+		"properties": Map{
+			"_id": Map{"bsonType": String("objectId")},
+			"age": Map{
+				"bsonType": Array{String("int"), String("null")},
+				"minimum":  Float(0),
+			},
+			"tags": Map{
+				"bsonType": String("array"),
+				"minItems": Int64(1),
+				"items":    Map{"bsonType": String("string")},
+			},
+		},
+	}
+	if ms := schema.MongoJSONSchema(); !reflect.DeepEqual(ms, expMongo) {
+		t.Fatal(ms)
+	}
+}
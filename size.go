@@ -0,0 +1,157 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// EncodedSize returns the number of bytes the document encodes to, the same
+// as len(doc.Encode()). BSON types are measured without encoding them, other
+// values (structs, Go types which are coerced, etc) are encoded to measure
+// them. This is for checking a size limit before encoding.
+func EncodedSize(doc Doc) (int, error) {
+	enc := defaultEncoder.begin()
+	switch doct := doc.(type) {
+	case Map:
+		return enc.docSize("", doct)
+	case Slice:
+		return enc.docSize("", doct)
+	case BSON:
+		return len(doct), nil
+	}
+	return 0, fmt.Errorf("Cannot size %T.", doc)
+}
+
+// growBuffer grows buf to fit the encoded doc, so the buffer isn't reallocated
+// as the doc is written. Errors are left for the write to return.
+func (this *Encoder) growBuffer(buf *bytes.Buffer, path string, doc Doc) {
+	if n, err := this.docSize(path, doc); err == nil {
+		buf.Grow(n)
+	}
+}
+
+// docSize returns the encoded size of a Map or Slice.
+func (this *Encoder) docSize(path string, doc Doc) (int, error) {
+	leave, err := this.enter(path, reflect.ValueOf(doc))
+	if err != nil {
+		return 0, err
+	}
+	defer leave()
+
+	// Size, fields and null byte.
+	n := 4 + 1
+	switch doct := doc.(type) {
+	case Map:
+		for name, v := range doct {
+			m, err := this.elemSize(catpath(path, name), name, v)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+	case Slice:
+		for _, pair := range doct {
+			m, err := this.elemSize(catpath(path, pair.Key), pair.Key, pair.Val)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+	}
+	return n, nil
+}
+
+// elemSize returns the encoded size of a element, which is the type byte, the
+// name and the value.
+func (this *Encoder) elemSize(path, name string, v interface{}) (int, error) {
+	n := 1 + len(name) + 1
+	switch vt := v.(type) {
+	case Map:
+		m, err := this.docSize(path, vt)
+		return n + m, err
+	case Slice:
+		m, err := this.docSize(path, vt)
+		return n + m, err
+	case BSON:
+		return n + len(vt), nil
+	case Array:
+		leave, err := this.enter(path, reflect.ValueOf(vt))
+		if err != nil {
+			return 0, err
+		}
+		defer leave()
+		n += 4 + 1
+		for i, e := range vt {
+			name := strconv.Itoa(i)
+			m, err := this.elemSize(catpath(path, name), name, e)
+			if err != nil {
+				return 0, err
+			}
+			n += m
+		}
+		return n, nil
+	case Float:
+		if !math.IsNaN(float64(vt)) && !math.IsInf(float64(vt), 0) {
+			return n + 8, nil
+		}
+	case String:
+		return n + stringSize(string(vt)), nil
+	case Javascript:
+		return n + stringSize(string(vt)), nil
+	case Symbol:
+		return n + stringSize(string(vt)), nil
+	case Binary:
+		return n + 4 + 1 + len(vt), nil
+	case ObjectId:
+		if len(vt) == 12 {
+			return n + 12, nil
+		}
+	case Bool:
+		return n + 1, nil
+	case Int32:
+		return n + 4, nil
+	case Int64, UTCDateTime, Timestamp:
+		return n + 8, nil
+	case Null, Undefined, MinKey, MaxKey:
+		return n, nil
+	case Regexp:
+		return n + len(vt.Pattern) + 1 + len(vt.Options) + 1, nil
+	case DBPointer:
+		if len(vt.ObjectId) == 12 {
+			return n + stringSize(vt.Name) + 12, nil
+		}
+	case JavascriptScope:
+		scope := 4 + 1
+		var err error
+		switch st := vt.Scope.(type) {
+		case nil:
+		case Map, Slice:
+			scope, err = this.docSize(path, st)
+		case BSON:
+			scope = len(st)
+		default:
+			return 0, encodeErrorf(path, "cannot encode scope %T.", vt.Scope)
+		}
+		return n + 4 + stringSize(vt.Javascript) + scope, err
+	}
+
+	// Coerced or invalid, encode it to find out.
+	buf := getBuffer()
+	defer putBuffer(buf)
+	if err := this.encodeVal(buf, path, name, v); err != nil {
+		return 0, err
+	}
+	return buf.Len(), nil
+}
+
+// stringSize returns the encoded size of a string, which is the length, the
+// bytes and the null byte.
+func stringSize(s string) int {
+	return 4 + len(s) + 1
+}
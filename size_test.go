@@ -0,0 +1,80 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEncodedSize(t *testing.T) {
+	nested := Map{"a": Int32(1)}
+	docs := []Doc{
+		Map{},
+		Slice{
+			{"float", Float(1.5)},
+			{"nan", Float(math.NaN())},
+			{"string", String("foo")},
+			{"map", nested},
+			{"slice", Slice{{"b", Int64(2)}}},
+			{"bson", nested.MustEncode()},
+			{"array", Array{String("x"), Array{Bool(true)}, nested}},
+			{"binary", Binary{1, 2, 3}},
+			{"undefined", Undefined{}},
+			{"objectid", ObjectId("0123456789ab")},
+			{"bool", Bool(true)},
+			{"utcdatetime", UTCDateTime(1)},
+			{"null", Null{}},
+			{"regexp", Regexp{"a.*", "i"}},
+			{"dbpointer", DBPointer{"db.c", ObjectId("0123456789ab")}},
+			{"javascript", Javascript("f()")},
+			{"symbol", Symbol("s")},
+			{"scope", JavascriptScope{"g()", nested}},
+			{"noscope", JavascriptScope{"g()", nil}},
+			{"int32", Int32(1)},
+			{"timestamp", Timestamp(1)},
+			{"int64", Int64(1)},
+			{"minkey", MinKey{}},
+			{"maxkey", MaxKey{}},
+			{"int", 1},
+			{"time", time.Unix(0, 0)},
+			{"struct", struct{ A string }{"b"}},
+		},
+	}
+	for _, doc := range docs {
+		bs, err := doc.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, err := EncodedSize(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != len(bs) {
+			t.Fatal(n, len(bs))
+		}
+
+		// The encode buffer is grown to fit.
+		buf := new(bytes.Buffer)
+		defaultEncoder.begin().growBuffer(buf, "", doc)
+		if buf.Cap() < len(bs) {
+			t.Fatal("Expected buffer to fit document.", buf.Cap(), len(bs))
+		}
+	}
+
+	// Errors are the same as encoding.
+	if _, err := EncodedSize(Map{"a": ObjectId("short")}); err == nil {
+		t.Fatal("Expected error.")
+	}
+	if _, err := EncodedSize(Map{"a": make(chan int)}); err == nil {
+		t.Fatal("Expected error.")
+	}
+	m := Map{}
+	m["m"] = m
+	if _, err := EncodedSize(m); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
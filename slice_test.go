@@ -25,7 +25,8 @@ var sliceTest = []Slice{
 		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}}}},
 	Slice{{"Javascript", Javascript("foo")}},
 	Slice{{"Symbol", Symbol("foo")}},
-	Slice{{"JavascriptScope", JavascriptScope{"foo", Map{"bar": String("baz")}}}},
+	Slice{{"JavascriptScope", JavascriptScope{"foo",
+		Slice{{"bar", String("baz")}, {"qux", Int32(1)}}}}},
 	Slice{{"Int32", Int32(123)}},
 	Slice{{"Timestamp", Timestamp(123)}},
 	Slice{{"Int64", Int64(123)}},
@@ -71,3 +72,85 @@ func TestSliceNoNest(t *testing.T) {
 		t.Fatal(dst)
 	}
 }
+
+func TestSliceSort(t *testing.T) {
+	s := Slice{{"b", Int32(1)}, {"a", Int32(2)}, {"b", Int32(0)}}
+	s.SortKeys()
+	exp := Slice{{"a", Int32(2)}, {"b", Int32(1)}, {"b", Int32(0)}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s)
+	}
+	s.Sort(func(a, b Pair) bool {
+		return a.Val.(Int32) < b.Val.(Int32)
+	})
+	exp = Slice{{"b", Int32(0)}, {"b", Int32(1)}, {"a", Int32(2)}}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s)
+	}
+}
+
+func TestSliceGetSetDelete(t *testing.T) {
+	s := Slice{{"a", Int32(1)}, {"b", Int32(2)}}
+	if v, ok := s.Get("b"); !ok || v != Int32(2) {
+		t.Fatal(v, ok)
+	}
+	if _, ok := s.Get("c"); ok {
+		t.Fatal("Expected 'c' to be missing.")
+	}
+	s.Set("a", Int32(3))
+	s.Set("c", Int32(4))
+	s.InsertAt(0, "first", Int32(0))
+	s.InsertAt(len(s), "last", Int32(5))
+	if !s.Delete("b") || s.Delete("b") {
+		t.Fatal(s)
+	}
+	exp := Slice{
+		{"first", Int32(0)},
+		{"a", Int32(3)},
+		{"c", Int32(4)},
+		{"last", Int32(5)},
+	}
+	if !reflect.DeepEqual(s, exp) {
+		t.Fatal(s, exp)
+	}
+}
+
+func TestJavascriptScopeDoc(t *testing.T) {
+	scope := Slice{{"b", Int32(1)}, {"a", Int32(2)}}
+	for _, doc := range []Doc{scope, Map{"b": Int32(1)}, scope.MustEncode(),
+		nil} {
+
+		src := Slice{{"js", JavascriptScope{"foo", doc}}}
+		bs, err := src.Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := bs.Slice()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := scope
+		if _, ok := doc.(Map); ok {
+			exp = scope[:1]
+		} else if doc == nil {
+			exp = Slice{}
+		}
+		if js := s[0].Val.(JavascriptScope); !reflect.DeepEqual(js.Scope, exp) {
+			t.Fatal(js.Scope, exp)
+		}
+		if doc == nil {
+			continue
+		}
+		var i int
+		if ok, err := s.Reach(&i, "js", "Scope", "b"); !ok || err != nil || i != 1 {
+			t.Fatal(ok, err, i)
+		}
+	}
+
+	// Scope of a raw document can be reached.
+	src := Map{"js": JavascriptScope{"foo", scope.MustEncode()}}
+	var i int
+	if ok, err := src.Reach(&i, "js.Scope.b"); !ok || err != nil || i != 1 {
+		t.Fatal(ok, err, i)
+	}
+}
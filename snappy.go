@@ -0,0 +1,188 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// snappyCodec is the snappy block format, without the framing format. It's
+// compatible with other snappy implementations, but the encoder is simpler so
+// it may compress less. See:
+//   https://github.com/google/snappy/blob/main/format_description.txt
+// The data is the uncompressed length as a uvarint, then elements which are
+// either a literal or a copy of earlier bytes. The low 2 bits of the tag byte
+// are the element type.
+type snappyCodec struct{}
+
+const (
+	snappyLiteral = 0x00
+	snappyCopy1   = 0x01 // Length 4-11, 11 bit offset.
+	snappyCopy2   = 0x02 // Length 1-64, 16 bit offset.
+	snappyCopy4   = 0x03 // Length 1-64, 32 bit offset.
+
+	// snappyMinMatch is the shortest copy the encoder looks for.
+	snappyMinMatch = 4
+
+	// snappyMaxOffset is the furthest copy the encoder makes.
+	snappyMaxOffset = 1<<16 - 1
+
+	// snappyTableBits is the size of the encoder hash table.
+	snappyTableBits = 14
+)
+
+var errSnappyCorrupt = errors.New("Snappy data corrupt.")
+
+func (snappyCodec) Encode(dst, src []byte) ([]byte, error) {
+	dst = binary.AppendUvarint(dst, uint64(len(src)))
+	var table [1 << snappyTableBits]int32
+	lit := 0 // Start of the bytes not yet emitted.
+	for i := 0; i+snappyMinMatch <= len(src); {
+		h := snappyHash(binary.LittleEndian.Uint32(src[i:]))
+		cand := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if cand < 0 || i-cand > snappyMaxOffset ||
+			binary.LittleEndian.Uint32(src[cand:]) !=
+				binary.LittleEndian.Uint32(src[i:]) {
+
+			i++
+			continue
+		}
+		n := snappyMinMatch
+		for i+n < len(src) && src[cand+n] == src[i+n] {
+			n++
+		}
+		dst = snappyAppendLiteral(dst, src[lit:i])
+		dst = snappyAppendCopy(dst, i-cand, n)
+		i += n
+		lit = i
+	}
+	return snappyAppendLiteral(dst, src[lit:]), nil
+}
+
+// snappyHash hashes 4 bytes to a index in the encoder table.
+func snappyHash(u uint32) uint32 {
+	return (u * 0x1e35a7bd) >> (32 - snappyTableBits)
+}
+
+// snappyAppendLiteral appends a literal element, if lit isn't empty.
+func snappyAppendLiteral(dst, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+	n := uint32(len(lit) - 1)
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|snappyLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|snappyLiteral, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2|snappyLiteral, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2|snappyLiteral, byte(n), byte(n>>8),
+			byte(n>>16))
+	default:
+		dst = append(dst, 63<<2|snappyLiteral, byte(n), byte(n>>8),
+			byte(n>>16), byte(n>>24))
+	}
+	return append(dst, lit...)
+}
+
+// snappyAppendCopy appends copy elements for a match of n bytes at offset.
+func snappyAppendCopy(dst []byte, offset, n int) []byte {
+	for n > 0 {
+		m := n
+		if m > 64 {
+			m = 64
+			if n-m < snappyMinMatch {
+				// Leave enough for a copy, not a short tail.
+				m = 60
+			}
+		}
+		if m >= 4 && m <= 11 && offset < 1<<11 {
+			dst = append(dst, byte(offset>>8)<<5|byte(m-4)<<2|snappyCopy1,
+				byte(offset))
+		} else {
+			dst = append(dst, byte(m-1)<<2|snappyCopy2, byte(offset),
+				byte(offset>>8))
+		}
+		n -= m
+	}
+	return dst
+}
+
+func (snappyCodec) Decode(dst, src []byte) ([]byte, error) {
+	rawLen, n := binary.Uvarint(src)
+	if n <= 0 || rawLen > maxBlockLen {
+		return nil, errSnappyCorrupt
+	}
+	start := len(dst)
+	end := start + int(rawLen)
+	for i := n; i < len(src); {
+		tag := src[i]
+		i++
+		var length, offset int
+		switch tag & 0x03 {
+		case snappyLiteral:
+			length = int(tag >> 2)
+			if length >= 60 {
+				w := length - 59
+				if i+w > len(src) {
+					return nil, errSnappyCorrupt
+				}
+				length = 0
+				for j := w - 1; j >= 0; j-- {
+					length = length<<8 | int(src[i+j])
+				}
+				i += w
+			}
+			length++
+			if length > len(src)-i || length > end-len(dst) {
+				return nil, errSnappyCorrupt
+			}
+			dst = append(dst, src[i:i+length]...)
+			i += length
+			continue
+		case snappyCopy1:
+			if i+1 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+			length = 4 + int(tag>>2&0x07)
+			offset = int(tag>>5)<<8 | int(src[i])
+			i++
+		case snappyCopy2:
+			if i+2 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[i:]))
+			i += 2
+		case snappyCopy4:
+			if i+4 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[i:]))
+			i += 4
+		}
+		if offset <= 0 || offset > len(dst)-start || length > end-len(dst) {
+			return nil, errSnappyCorrupt
+		}
+		from := len(dst) - offset
+		if offset >= length {
+			dst = append(dst, dst[from:from+length]...)
+			continue
+		}
+		// The copy overlaps the bytes it appends, so copy byte by byte.
+		for ; length > 0; length-- {
+			dst = append(dst, dst[from])
+			from++
+		}
+	}
+	if len(dst) != end {
+		return nil, errSnappyCorrupt
+	}
+	return dst, nil
+}
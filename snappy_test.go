@@ -0,0 +1,65 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestSnappy(t *testing.T) {
+	// Literal "abc" then a overlapping copy of length 8 at offset 3.
+	enc := []byte{0x0b, 0x08, 'a', 'b', 'c', 0x11, 0x03}
+	raw := []byte("abcabcabcab")
+	var c snappyCodec
+	b, err := c.Encode(nil, raw)
+	if err != nil || !bytes.Equal(b, enc) {
+		t.Fatal(err, b)
+	}
+	b, err = c.Decode([]byte("x"), enc)
+	if err != nil || !bytes.Equal(b, append([]byte("x"), raw...)) {
+		t.Fatal(err, string(b))
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 100000)
+	rnd.Read(random)
+	for _, raw := range [][]byte{
+		nil,
+		[]byte("a"),
+		bytes.Repeat([]byte("a"), 100000),
+		bytes.Repeat([]byte("abcdefgh"), 1000),
+		random,
+		append(random[:70000:70000], random[:70000]...),
+		Map{"a": String("foo"), "b": Array{Int32(1), Int32(1)}}.MustEncode(),
+	} {
+		b, err := c.Encode(nil, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dec, err := c.Decode(nil, b)
+		if err != nil || !bytes.Equal(dec, raw) {
+			t.Fatal(err, len(dec), len(raw))
+		}
+	}
+}
+
+func TestSnappyCorrupt(t *testing.T) {
+	var c snappyCodec
+	for _, b := range [][]byte{
+		{},                                   // No length.
+		{0x03, 0x08, 'a', 'b'},               // Literal truncated.
+		{0x02, 0x08, 'a', 'b', 'c'},          // Literal longer than length.
+		{0x04, 0x08, 'a', 'b', 'c'},          // Shorter than length.
+		{0x05, 0x00, 'a', 0x0d},              // Copy truncated.
+		{0x05, 0x00, 'a', 0x0d, 0x02},        // Offset before start.
+		{0x05, 0x00, 'a', 0x0e, 0x00, 0x00},  // Offset zero.
+		{0x04, 0xfc, 0xff, 0xff, 0xff, 0x0f}, // Literal longer than data.
+	} {
+		if _, err := c.Decode(nil, b); err == nil {
+			t.Fatalf("Expected error, %x.", b)
+		}
+	}
+}
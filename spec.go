@@ -0,0 +1,112 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ValidateSpec returns error if the BSON isn't exactly one document which
+// conforms to the BSON spec, as tested by the decode errors of the official
+// bson-corpus. Unlike decoding this also checks:
+//   Keys, strings, regexps and DBPointer names are valid UTF-8.
+//   Booleans are 0x00 or 0x01.
+//   The length in old binary (subtype 0x02) agrees with the binary length.
+//   There are no bytes after the document.
+// Types which this package doesn't support, such as Decimal128, are a error.
+// The errors are *DecodeError.
+func ValidateSpec(bs BSON) error {
+	docLen, err := rawDocLen(bs, 0)
+	if err != nil {
+		return decodeError("", "", 0, 0, err)
+	}
+	if docLen != len(bs) {
+		return decodeError("", "", 0, 0, fmt.Errorf(
+			"Doc length %v but %v bytes.", docLen, len(bs)))
+	}
+	return validateDoc(bs, "", 0)
+}
+
+// validateDoc validates the document at offset off.
+func validateDoc(bs []byte, path string, off int) error {
+	elems, err := rawElements(bs, off)
+	if err != nil {
+		return prefixDecodeError(path, err)
+	}
+	for _, e := range elems {
+		if !utf8.ValidString(e.Name) {
+			return decodeError(path, "", e.Start, e.Type,
+				fmt.Errorf("%w, invalid UTF-8.", ErrInvalidKey))
+		}
+		if err := validateValue(bs, catpath(path, e.Name), e); err != nil {
+			return decodeError(path, e.Name, e.Start, e.Type, err)
+		}
+	}
+	return nil
+}
+
+// validateValue validates the value of a element, whose length has already
+// been checked by rawElements.
+func validateValue(bs []byte, path string, e rawElement) error {
+	b := bs[e.Value:e.End]
+	switch e.Type {
+	case _STRING, _JAVASCRIPT, _SYMBOL:
+		return validateString(b)
+	case _EMBEDDED_DOCUMENT, _ARRAY:
+		return validateDoc(bs, path, e.Value)
+	case _BINARY_DATA:
+		if b[4] == 0x02 {
+			// Old binary has a second length, of the data which follows.
+			if len(b) < 5+4 {
+				return fmt.Errorf("Binary %w.", ErrTruncated)
+			}
+			n := int(int32(binary.LittleEndian.Uint32(b[5:])))
+			if n != len(b)-5-4 {
+				return fmt.Errorf("Binary old length %v invalid.", n)
+			}
+		}
+	case _BOOLEAN:
+		if b[0] > 0x01 {
+			return fmt.Errorf("Bool value %v invalid.", b[0])
+		}
+	case _REGEXP:
+		if !utf8.Valid(b) {
+			return errors.New("Regexp invalid UTF-8.")
+		}
+	case _DBPOINTER:
+		return validateString(b[:len(b)-12])
+	case _JAVASCRIPT_SCOPE:
+		if len(b) < 4+5+5 {
+			return fmt.Errorf("JavascriptScope %w.", ErrTruncated)
+		}
+		n, err := rawValueLen(_STRING, b[4:])
+		if err != nil {
+			return err
+		}
+		if err := validateString(b[4 : 4+n]); err != nil {
+			return err
+		}
+		scopeLen, err := rawDocLen(b, 4+n)
+		if err != nil {
+			return err
+		}
+		if 4+n+scopeLen != len(b) {
+			return fmt.Errorf("JavascriptScope length %v invalid.", len(b))
+		}
+		return validateDoc(bs, path, e.Value+4+n)
+	}
+	return nil
+}
+
+// validateString validates a string, which is a length followed by null
+// terminated UTF-8. The length and terminator have already been checked.
+func validateString(b []byte) error {
+	if !utf8.Valid(b[4 : len(b)-1]) {
+		return errors.New("String invalid UTF-8.")
+	}
+	return nil
+}
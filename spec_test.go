@@ -0,0 +1,109 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// specFile is a test file in the bson-corpus format. The files in
+// testdata/bson-corpus have cases from the official bson-corpus, plus some for
+// checks it doesn't cover.
+type specFile struct {
+	Description string `json:"description"`
+	Valid       []struct {
+		Description   string `json:"description"`
+		CanonicalBSON string `json:"canonical_bson"`
+	} `json:"valid"`
+	DecodeErrors []struct {
+		Description string `json:"description"`
+		BSON        string `json:"bson"`
+	} `json:"decodeErrors"`
+}
+
+// readSpecFiles reads the files in testdata/bson-corpus.
+func readSpecFiles(t *testing.T) []specFile {
+	paths, err := filepath.Glob("testdata/bson-corpus/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("Expected bson-corpus files.")
+	}
+	var files []specFile
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var f specFile
+		if err := json.Unmarshal(b, &f); err != nil {
+			t.Fatal(path, err)
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+func TestValidateSpec(t *testing.T) {
+	for _, f := range readSpecFiles(t) {
+		for _, test := range f.Valid {
+			desc := f.Description + ", " + test.Description
+			bs, err := hex.DecodeString(test.CanonicalBSON)
+			if err != nil {
+				t.Fatal(desc, err)
+			}
+			if err := ValidateSpec(bs); err != nil {
+				t.Fatalf("%v, %v", desc, err)
+			}
+			if _, err := BSON(bs).Slice(); err != nil {
+				t.Fatalf("%v, valid but decode failed, %v", desc, err)
+			}
+		}
+		for _, test := range f.DecodeErrors {
+			desc := f.Description + ", " + test.Description
+			bs, err := hex.DecodeString(test.BSON)
+			if err != nil {
+				t.Fatal(desc, err)
+			}
+			err = ValidateSpec(bs)
+			if err == nil {
+				t.Fatalf("Expected error, %v.", desc)
+			}
+			var de *DecodeError
+			if !errors.As(err, &de) {
+				t.Fatalf("Expected DecodeError, got %T, %v.", err, desc)
+			}
+		}
+	}
+}
+
+func TestValidateSpecRoundTrip(t *testing.T) {
+	// Everything this package encodes is valid.
+	bs := Slice{
+		{"a", String("foo")},
+		{"b", Slice{{"c", Array{Int32(1), Bool(true)}}}},
+		{"d", JavascriptScope{Javascript: "x", Scope: Map{"y": Int64(1)}}},
+		{"e", Regexp{Pattern: "^f", Options: "i"}},
+		{"f", DBPointer{Name: "g", ObjectId: NewObjectIdFromTime(time.Now())}},
+		{"h", Binary("i")},
+	}.MustEncode()
+	if err := ValidateSpec(bs); err != nil {
+		t.Fatal(err)
+	}
+
+	// Invalid UTF-8 nested in a JavascriptScope.
+	bs = Slice{
+		{"d", JavascriptScope{Javascript: "x", Scope: Map{"y": String("\xE9")}}},
+	}.MustEncode()
+	if err := ValidateSpec(bs); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
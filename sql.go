@@ -0,0 +1,77 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Value implements driver.Valuer. The ObjectId is stored as 12 bytes. An empty
+// ObjectId is NULL.
+func (this ObjectId) Value() (driver.Value, error) {
+	if len(this) == 0 {
+		return nil, nil
+	}
+	if len(this) != 12 {
+		return nil, fmt.Errorf("ObjectId must be 12 bytes, got %v.", len(this))
+	}
+	return []byte(this), nil
+}
+
+// Scan implements sql.Scanner. The src may be 12 bytes, or 24 hex digits as a
+// string or bytes. NULL is an empty ObjectId.
+func (this *ObjectId) Scan(src interface{}) error {
+	switch srct := src.(type) {
+	case nil:
+		*this = nil
+		return nil
+	case []byte:
+		if len(srct) == 24 {
+			return this.UnmarshalText(srct)
+		}
+		oid, err := NewObjectIdFromBytes(srct)
+		if err != nil {
+			return err
+		}
+		*this = oid
+		return nil
+	case string:
+		return this.UnmarshalText([]byte(srct))
+	}
+	return fmt.Errorf("cannot scan %T in to ObjectId.", src)
+}
+
+// Value implements driver.Valuer. A nil Binary is NULL.
+func (this Binary) Value() (driver.Value, error) {
+	if this == nil {
+		return nil, nil
+	}
+	return []byte(this), nil
+}
+
+// Scan implements sql.Scanner. The src is copied because the driver may reuse
+// it. NULL is a nil Binary.
+func (this *Binary) Scan(src interface{}) error {
+	switch srct := src.(type) {
+	case nil:
+		*this = nil
+		return nil
+	case []byte:
+		bin, err := NewBinary(srct)
+		if err != nil {
+			return err
+		}
+		*this = bin
+		return nil
+	case string:
+		bin, err := NewBinary([]byte(srct))
+		if err != nil {
+			return err
+		}
+		*this = bin
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T in to Binary.", src)
+}
@@ -0,0 +1,72 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+)
+
+var (
+	_ driver.Valuer = ObjectId(nil)
+	_ sql.Scanner   = (*ObjectId)(nil)
+	_ driver.Valuer = Binary(nil)
+	_ sql.Scanner   = (*Binary)(nil)
+)
+
+func TestObjectIdSQL(t *testing.T) {
+	oid, err := ObjectIdFromHex("507f1f77bcf86cd799439011")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := oid.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []interface{}{v, oid.Hex(), []byte(oid.Hex())} {
+		var dst ObjectId
+		if err := dst.Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Compare(dst, oid) != 0 {
+			t.Fatal(src, dst)
+		}
+	}
+	var dst ObjectId
+	if err := dst.Scan(nil); err != nil || dst != nil {
+		t.Fatal(err, dst)
+	}
+	for _, src := range []interface{}{[]byte{0x01}, "xyz", 123} {
+		if err := dst.Scan(src); err == nil {
+			t.Fatal("Expected error.", src)
+		}
+	}
+	if v, err := ObjectId(nil).Value(); v != nil || err != nil {
+		t.Fatal(v, err)
+	}
+}
+
+func TestBinarySQL(t *testing.T) {
+	src := []byte{0x00, 0x01}
+	var bin Binary
+	if err := bin.Scan(src); err != nil {
+		t.Fatal(err)
+	}
+	src[0] = 0x02
+	if bytes.Compare(bin, []byte{0x00, 0x01}) != 0 {
+		t.Fatal("Expected Scan to copy.", bin)
+	}
+	v, err := bin.Value()
+	if err != nil || bytes.Compare(v.([]byte), bin) != 0 {
+		t.Fatal(err, v)
+	}
+	if err := bin.Scan(nil); err != nil || bin != nil {
+		t.Fatal(err, bin)
+	}
+	if err := bin.Scan(1.5); err == nil {
+		t.Fatal("Expected error.")
+	}
+}
@@ -4,8 +4,10 @@
 package bson
 
 import (
+	"math"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // tags is used for struct tag test.
@@ -16,6 +18,22 @@ type tags struct {
 	Omit       string `bson:",omitempty"`
 }
 
+// deep is used for omitempty=deep test.
+type deep struct {
+	Nest  tags              `bson:",omitempty=deep"`
+	Map   Map               `bson:",omitempty=deep"`
+	Ptr   *tags             `bson:",omitempty=deep"`
+	Shall map[string]string `bson:",omitempty"`
+}
+
+// nested is used for nested value test.
+type nested struct {
+	Raw  BSON
+	Nest tags
+	Nil  *tags
+	Omit *tags `bson:",omitempty"`
+}
+
 // unexport is used to test that unexported field is ignored.
 type unexport struct {
 	foo string
@@ -54,6 +72,43 @@ var structTests = []struct{
 		},
 	},
 
+	// Deep omitempty with empty nested fields.
+	{
+		src: deep{
+			Map: Map{"foo": String("")},
+			Ptr: &tags{},
+		},
+		exp: Map{},
+	},
+
+	// Deep omitempty with a non-empty nested field.
+	{
+		src: deep{
+			Nest: tags{Omit: "foo"},
+			Map:  Map{"foo": Map{"bar": Int32(0)}, "baz": Int32(1)},
+			Ptr:  &tags{Rename: "bar"},
+		},
+		exp: Map{
+			"Ptr":  Map{"rename_ok": String("bar")},
+			"Nest": Map{"rename_ok": String(""), "Omit": String("foo")},
+			"Map":  Map{"foo": Map{"bar": Int32(0)}, "baz": Int32(1)},
+		},
+	},
+
+	// BSON and struct fields are embedded documents. A nil pointer is Null,
+	// or omitted with omitempty.
+	{
+		src: nested{
+			Raw:  Map{"foo": Int32(1)}.MustEncode(),
+			Nest: tags{Rename: "bar"},
+		},
+		exp: Map{
+			"Raw":  Map{"foo": Int32(1)},
+			"Nest": Map{"rename_ok": String("bar")},
+			"Nil":  Null{},
+		},
+	},
+
 	// Unexported field.
 	{
 		src: unexport{
@@ -78,3 +133,388 @@ func TestStruct(t *testing.T) {
 		}
 	}
 }
+
+// decode is used for struct decode test.
+type decode struct {
+	Int    int
+	Int8   int8
+	Str    string `bson:"str"`
+	Ignore string `bson:"-"`
+	Bytes  []byte
+	Nest   tags
+	Ptr    *tags
+	Slice  []string
+	Nests  []tags
+	Map    map[string]int64 `bson:",omitempty"`
+	Doc    Map
+	Order  Slice
+	Raw    BSON
+	Any    interface{}
+	Zero   int
+}
+
+func TestDecodeStruct(t *testing.T) {
+	src := decode{
+		Int:    1,
+		Int8:   2,
+		Str:    "foo",
+		Ignore: "bar",
+		Bytes:  []byte{0x00, 0x01},
+		Nest:   tags{Rename: "a", Omit: "b"},
+		Ptr:    &tags{Rename: "c"},
+		Slice:  []string{"d", "e"},
+		Nests:  []tags{{Rename: "f"}},
+		Map:    map[string]int64{"g": 3},
+		Doc:    Map{"h": String("i")},
+		Order:  Slice{{"j", Int32(4)}, {"k", Int32(5)}},
+		Raw:    Map{"l": Int32(6)}.MustEncode(),
+		Any:    "m",
+	}
+	bs, err := EncodeStruct(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.MapNoNest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(m, "Zero")
+	m["extra"] = Int32(7)
+	bs = m.MustEncode()
+	var dst decode
+	fields, err := DecodeStructFields(bs, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.Ignore = ""
+	src.Any = String("m")
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatalf("%#v\n%#v", dst, src)
+	}
+	if !fields["Nest.Rename"] || !fields["Str"] || fields["Zero"] ||
+		fields["Ignore"] || fields["Ptr.OmitRename"] {
+
+		t.Fatal(fields)
+	}
+}
+
+func TestDecodeStructError(t *testing.T) {
+	var dst struct{ Int8 int8 }
+	if err := DecodeStruct(Map{"Int8": Int32(1000)}.MustEncode(), &dst);
+		err == nil {
+
+		t.Fatal("Expected overflow error.")
+	}
+	if err := DecodeStruct(Map{}.MustEncode(), dst); err == nil {
+		t.Fatal("Expected non-pointer error.")
+	}
+}
+
+func TestDecodeGeneric(t *testing.T) {
+	bs := Map{"rename_ok": String("a"), "Nest": Map{"b": Int32(1)}}.MustEncode()
+	tg, err := Decode[tags](bs)
+	if err != nil || tg.Rename != "a" {
+		t.Fatal(err, tg)
+	}
+	ptg, err := Decode[*tags](bs)
+	if err != nil || ptg == nil || ptg.Rename != "a" {
+		t.Fatal(err, ptg)
+	}
+	m, err := Decode[Map](bs)
+	if exp := (Map{"rename_ok": String("a"), "Nest": Map{"b": Int32(1)}}); err != nil ||
+		!reflect.DeepEqual(m, exp) {
+
+		t.Fatal(err, m)
+	}
+	s, err := Decode[Slice](bs)
+	if err != nil || len(s) != 2 {
+		t.Fatal(err, s)
+	}
+	nm, err := Decode[map[string]interface{}](bs)
+	if err != nil || nm["rename_ok"] != String("a") {
+		t.Fatal(err, nm)
+	}
+	if _, err := Decode[int](bs); err == nil {
+		t.Fatal("Expected error decoding to int.")
+	}
+}
+
+func TestDecodeInterfaceNested(t *testing.T) {
+	bs := Map{
+		"a": Map{"b": Int32(1)},
+		"c": Array{Map{"d": Int32(2)}},
+	}.MustEncode()
+	exp := map[string]interface{}{
+		"a": Map{"b": Int32(1)},
+		"c": Array{Map{"d": Int32(2)}},
+	}
+	nm, err := Decode[map[string]interface{}](bs)
+	if err != nil || !reflect.DeepEqual(nm, exp) {
+		t.Fatal(err, nm)
+	}
+	var dst struct {
+		A interface{} `bson:"a"`
+		C interface{} `bson:"c"`
+	}
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst.A, exp["a"]) || !reflect.DeepEqual(dst.C, exp["c"]) {
+		t.Fatal(dst)
+	}
+}
+
+// Base is used for inline test.
+type Base struct {
+	Id      Int32 `bson:"_id"`
+	Created Int64 `bson:"created"`
+}
+
+// inline is used for inline test.
+type inline struct {
+	Base  `bson:",inline"`
+	Name  String         `bson:"name"`
+	Extra map[string]int `bson:",inline"`
+}
+
+func TestStructInline(t *testing.T) {
+	src := inline{Base: Base{Id: 1, Created: 2}, Name: "a",
+		Extra: map[string]int{"x": 3}}
+	bs, err := EncodeStruct(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{"_id": Int32(1), "created": Int64(2), "name": String("a"),
+		"x": Int64(3)}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+	var dst inline
+	fields, err := DecodeStructFields(bs, &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dst, src) {
+		t.Fatal(dst, src)
+	}
+	if !fields["Base.Id"] || !fields["Extra"] {
+		t.Fatal(fields)
+	}
+
+	// Inline map duplicates a field.
+	src.Extra["name"] = 4
+	if _, err := EncodeStruct(src); err == nil {
+		t.Fatal("Expected duplicate key error.")
+	}
+
+	// Only structs and maps can be inline.
+	if _, err := EncodeStruct(struct {
+		A int `bson:",inline"`
+	}{}); err == nil {
+		t.Fatal("Expected inline error.")
+	}
+}
+
+func TestStructMinsize(t *testing.T) {
+	type minsize struct {
+		A int   `bson:",minsize"`
+		B int64 `bson:",minsize,omitempty"`
+		C Int64 `bson:",minsize"`
+		D *int  `bson:",minsize"`
+		E int
+	}
+	d := 4
+	bs, err := EncodeStruct(minsize{A: 1, B: 1 << 40, C: -3, D: &d, E: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{"A": Int32(1), "B": Int64(1 << 40), "C": Int32(-3), "D": Int32(4),
+		"E": Int64(5)}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+}
+
+func TestStructTruncate(t *testing.T) {
+	type truncate struct {
+		A int   `bson:",truncate"`
+		B int32 `bson:",truncate"`
+		C *int8 `bson:",truncate"`
+		D int32
+	}
+	bs := Map{"A": Float(-2.7), "B": Int64(5), "C": Float(3), "D": Int32(6)}.MustEncode()
+	var dst truncate
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.A != -2 || dst.B != 5 || dst.C == nil || *dst.C != 3 || dst.D != 6 {
+		t.Fatal(dst)
+	}
+
+	// Doesn't fit.
+	for _, m := range []Map{{"B": Int64(1 << 40)}, {"C": Float(300)},
+		{"A": Float(math.Inf(1))}} {
+
+		if err := DecodeStruct(m.MustEncode(), &dst); err == nil {
+			t.Fatal("Expected overflow error.", m)
+		}
+	}
+
+	// Without truncate.
+	if err := DecodeStruct(Map{"D": Int64(1)}.MustEncode(), &dst); err == nil {
+		t.Fatal("Expected coercion error.")
+	}
+}
+
+func TestStructRequired(t *testing.T) {
+	type required struct {
+		A    int `bson:"a,required"`
+		Nest *struct {
+			B int `bson:",required"`
+		}
+	}
+	var dst required
+	if err := DecodeStruct(Map{"a": Int64(1)}.MustEncode(), &dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecodeStruct(Map{}.MustEncode(), &dst); err == nil ||
+		err.Error() != "a, required field A missing." {
+
+		t.Fatal(err)
+	}
+	if err := DecodeStruct(Map{"a": Int64(1), "Nest": Map{}}.MustEncode(), &dst);
+		err == nil || err.Error() != "Nest.B, required field B missing." {
+
+		t.Fatal(err)
+	}
+}
+
+func TestStructRemain(t *testing.T) {
+	type remainMap struct {
+		A    Int32 `bson:"a"`
+		Rest Map   `bson:",remain"`
+	}
+	type remainSlice struct {
+		Base `bson:",inline"`
+		Rest Slice `bson:",remain"`
+	}
+	bs := Map{"a": Int32(1), "_id": Int32(2), "b": String("x"),
+		"c": Map{"d": Int32(3)}}.MustEncode()
+
+	var m remainMap
+	if err := DecodeStruct(bs, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.A != 1 || len(m.Rest) != 3 || m.Rest["b"] != String("x") {
+		t.Fatal(m)
+	}
+	var s remainSlice
+	if err := DecodeStruct(bs, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Id != 2 || len(s.Rest) != 3 || s.Rest[0].Key != "a" || s.Rest[1].Key != "b" ||
+		s.Rest[2].Key != "c" {
+
+		t.Fatal(s)
+	}
+
+	// Unknown keys are written back.
+	for _, src := range []interface{}{m, s} {
+		bs1, err := EncodeStruct(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m1, err := bs1.Map()
+		if err != nil {
+			t.Fatal(err)
+		}
+		exp := Map{"a": Int32(1), "_id": Int32(2), "b": String("x"),
+			"c": Map{"d": Int32(3)}}
+		if _, ok := src.(remainSlice); ok {
+			exp["created"] = Int64(0)
+		}
+		if !reflect.DeepEqual(m1, exp) {
+			t.Fatal(m1, exp)
+		}
+	}
+}
+
+// zeroable has a IsZero method with a pointer receiver.
+type zeroable struct {
+	N int
+}
+
+func (this *zeroable) IsZero() bool {
+	return this.N < 0
+}
+
+func TestStructOmitZero(t *testing.T) {
+	type omit struct {
+		A time.Time `bson:",omitempty"`
+		B time.Time `bson:",omitzero"`
+		C zeroable  `bson:",omitzero"`
+		D *int      `bson:",omitzero"`
+		E int       `bson:",omitzero"`
+		F struct {
+			G int
+		} `bson:",omitzero"`
+	}
+	bs, err := EncodeStruct(omit{C: zeroable{N: -1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 0 {
+		t.Fatal(m)
+	}
+
+	// Not zero.
+	zero := 0
+	src := omit{A: time.Unix(1, 0), B: time.Unix(1, 0), D: &zero, E: 1}
+	src.F.G = 1
+	if bs, err = EncodeStruct(src); err != nil {
+		t.Fatal(err)
+	}
+	if m, err = bs.Map(); err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 6 {
+		t.Fatal(m)
+	}
+}
+
+func TestDecodeStructPointers(t *testing.T) {
+	type pointers struct {
+		A *Int32
+		B *Int32
+		C *Int32
+		D *struct {
+			E *String
+		}
+	}
+	one := Int32(1)
+	dst := pointers{A: &one, B: &one, C: &one}
+	bs := Map{"B": Null{}, "C": Int32(0), "D": Map{"E": Null{}}}.MustEncode()
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.A != nil || dst.B != nil || dst.C == nil || *dst.C != 0 ||
+		dst.D == nil || dst.D.E != nil {
+
+		t.Fatal(dst)
+	}
+	if one != 1 {
+		t.Fatal("Pointed to value modified.", one)
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// TransformFunc changes a field value, for example to encrypt, hash or redact
+// it.
+type TransformFunc func(v interface{}) (interface{}, error)
+
+// Transform is a pair of funcs applied to field values. Encode is applied
+// before a value is encoded, Decode after it's decoded (embedded documents are
+// BSON). Either may be nil to leave values as is in that direction.
+type Transform struct {
+	Encode TransformFunc
+	Decode TransformFunc
+}
+
+// transforms are the registered Transforms, keyed by name.
+var transforms sync.Map
+
+// RegisterTransform registers a Transform by name. The transform is applied to
+// struct fields tagged with the name, for example:
+//   SSN string `bson:"ssn,transform=pii"`
+// and to the paths given to TransformEncode and TransformDecode. A Transform
+// with neither func removes it.
+func RegisterTransform(name string, t Transform) {
+	if t.Encode == nil && t.Decode == nil {
+		transforms.Delete(name)
+		return
+	}
+	transforms.Store(name, t)
+}
+
+// lookupTransform returns the Transform registered with the name.
+func lookupTransform(name string) (Transform, error) {
+	t, ok := transforms.Load(name)
+	if !ok {
+		return Transform{}, fmt.Errorf("unknown transform %v.", name)
+	}
+	return t.(Transform), nil
+}
+
+// applyTransform applies the encode or decode func of the named Transform.
+// Errors are a DecodeError or EncodeError for the path.
+func applyTransform(path, name string, v interface{}, decode bool) (interface{},
+	error) {
+
+	t, err := lookupTransform(name)
+	if err == nil {
+		fn := t.Encode
+		if decode {
+			fn = t.Decode
+		}
+		if fn != nil {
+			v, err = fn(v)
+		}
+	}
+	if err == nil {
+		return v, nil
+	}
+	if decode {
+		return nil, prefixDecodeError(path, err)
+	}
+	return nil, prefixEncodeError(path, err)
+}
+
+// TransformEncode returns a Hook for a Encoder which applies the Encode func of
+// the named Transform to the values at the paths. The paths are the same as
+// with Reach. Missing paths are ignored.
+func TransformEncode(name string, paths ...string) Hook {
+	return transformHook(name, paths, false)
+}
+
+// TransformDecode returns a Hook for a Decoder which applies the Decode func of
+// the named Transform to the values at the paths.
+func TransformDecode(name string, paths ...string) Hook {
+	return transformHook(name, paths, true)
+}
+
+// transformHook returns a Hook which applies the Transform to the paths. The
+// document is copied along each path, the rest is shared.
+func transformHook(name string, paths []string, decode bool) Hook {
+	return func(doc Doc) (Doc, error) {
+		var v interface{} = doc
+		for _, p := range paths {
+			var err error
+			fn := func(v interface{}) (interface{}, error) {
+				return applyTransform(p, name, v, decode)
+			}
+			if v, err = transformPath(v, splitPath([]string{p}), fn); err != nil {
+				return nil, err
+			}
+		}
+		return v.(Doc), nil
+	}
+}
+
+// transformPath returns a copy of v with fn applied to the value at the path.
+// Returns v if the path doesn't exist.
+func transformPath(v interface{}, dot []string, fn TransformFunc) (interface{},
+	error) {
+
+	if len(dot) == 0 {
+		return fn(v)
+	}
+	switch vt := v.(type) {
+	case Map:
+		e, ok := vt[dot[0]]
+		if !ok {
+			return v, nil
+		}
+		e, err := transformPath(e, dot[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		m := copyMap(vt)
+		m[dot[0]] = e
+		return m, nil
+	case Slice:
+		for i, pair := range vt {
+			if pair.Key != dot[0] {
+				continue
+			}
+			e, err := transformPath(pair.Val, dot[1:], fn)
+			if err != nil {
+				return nil, err
+			}
+			s := append(Slice(nil), vt...)
+			s[i].Val = e
+			return s, nil
+		}
+	case BSON:
+		s, err := vt.SliceNoNest()
+		if err != nil {
+			return nil, err
+		}
+		return transformPath(s, dot, fn)
+	case Array:
+		i, err := strconv.Atoi(dot[0])
+		if err != nil || i < 0 || i >= len(vt) {
+			return v, nil
+		}
+		e, err := transformPath(vt[i], dot[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		a := append(Array(nil), vt...)
+		a[i] = e
+		return a, nil
+	}
+	return v, nil
+}
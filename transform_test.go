@@ -0,0 +1,135 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package bson
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// rot13 is a reversible transform for testing.
+func rot13(v interface{}) (interface{}, error) {
+	var s string
+	switch vt := v.(type) {
+	case string:
+		s = vt
+	case String:
+		s = string(vt)
+	default:
+		return nil, errors.New("rot13 needs a string.")
+	}
+	b := []byte(s)
+	for i, c := range b {
+		switch {
+		case c >= 'a' && c <= 'z':
+			b[i] = 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			b[i] = 'A' + (c-'A'+13)%26
+		}
+	}
+	return String(b), nil
+}
+
+func TestTransformTag(t *testing.T) {
+	RegisterTransform("rot13", Transform{Encode: rot13, Decode: rot13})
+	defer RegisterTransform("rot13", Transform{})
+	RegisterTransform("redact", Transform{
+		Encode: func(v interface{}) (interface{}, error) {
+			return String("***"), nil
+		},
+	})
+	defer RegisterTransform("redact", Transform{})
+	type person struct {
+		Name   string
+		Secret string `bson:"secret,transform=rot13"`
+		SSN    string `bson:"ssn,transform=redact"`
+	}
+	src := person{Name: "Bob", Secret: "Hello", SSN: "123"}
+	bs, err := EncodeStruct(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := bs.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{"Name": String("Bob"), "secret": String("Uryyb"),
+		"ssn": String("***")}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+	var dst person
+	if err := DecodeStruct(bs, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if exp := (person{Name: "Bob", Secret: "Hello", SSN: "***"}); dst != exp {
+		t.Fatal(dst, exp)
+	}
+
+	// Transform errors and unknown transforms.
+	if _, err := EncodeStruct(struct {
+		A int `bson:",transform=rot13"`
+	}{}); err == nil {
+		t.Fatal("Expected transform error.")
+	}
+	if _, err := EncodeStruct(struct {
+		A int `bson:",transform=nope"`
+	}{}); err == nil {
+		t.Fatal("Expected unknown transform error.")
+	}
+
+	// Removed.
+	RegisterTransform("redact", Transform{})
+	if _, err := EncodeStruct(src); err == nil {
+		t.Fatal("Expected unknown transform error.")
+	}
+}
+
+func TestTransformHook(t *testing.T) {
+	RegisterTransform("rot13", Transform{Encode: rot13, Decode: rot13})
+	defer RegisterTransform("rot13", Transform{})
+	src := Map{
+		"a": String("abc"),
+		"b": Slice{{"c", Array{String("xyz")}}},
+		"d": String("abc"),
+	}
+	buf := bytes.NewBuffer(nil)
+	enc := NewEncoder(buf)
+	enc.Hooks = []Hook{TransformEncode("rot13", "a", "b.c.0", "missing.x")}
+	if err := enc.Encode(src); err != nil {
+		t.Fatal(err)
+	}
+	if src["a"] != String("abc") {
+		t.Fatal("Hook modified the document.", src)
+	}
+	m, err := BSON(buf.Bytes()).Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Map{
+		"a": String("nop"),
+		"b": Map{"c": Array{String("klm")}},
+		"d": String("abc"),
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+
+	// Decode back.
+	dec := NewDecoder(buf)
+	dec.Hooks = []Hook{TransformDecode("rot13", "a", "b.c.0")}
+	if m, err = dec.DecodeMap(); err != nil {
+		t.Fatal(err)
+	}
+	exp = Map{
+		"a": String("abc"),
+		"b": Map{"c": Array{String("xyz")}},
+		"d": String("abc"),
+	}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatal(m, exp)
+	}
+}
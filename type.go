@@ -3,6 +3,13 @@
 
 package bson
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Wire types.
 const (
 	_FLOATING_POINT    = 0x01 // "\x01" e_name double           Floating point
@@ -28,6 +35,113 @@ const (
 	_MAX_KEY           = 0x7F // "\x7F" e_name                  Max key
 )
 
+// Type is the BSON type of a value, the same as the type byte in encoded BSON.
+type Type byte
+
+// BSON types.
+const (
+	TypeFloat            Type = _FLOATING_POINT
+	TypeString           Type = _STRING
+	TypeEmbeddedDocument Type = _EMBEDDED_DOCUMENT
+	TypeArray            Type = _ARRAY
+	TypeBinary           Type = _BINARY_DATA
+	TypeUndefined        Type = _UNDEFINED
+	TypeObjectId         Type = _OBJECT_ID
+	TypeBool             Type = _BOOLEAN
+	TypeUTCDateTime      Type = _UTC_DATETIME
+	TypeNull             Type = _NULL_VALUE
+	TypeRegexp           Type = _REGEXP
+	TypeDBPointer        Type = _DBPOINTER
+	TypeJavascript       Type = _JAVASCRIPT
+	TypeSymbol           Type = _SYMBOL
+	TypeJavascriptScope  Type = _JAVASCRIPT_SCOPE
+	TypeInt32            Type = _32BIT_INTEGER
+	TypeTimestamp        Type = _TIMESTAMP
+	TypeInt64            Type = _64BIT_INTEGER
+	TypeMinKey           Type = _MIN_KEY
+	TypeMaxKey           Type = _MAX_KEY
+)
+
+var typeNames = map[Type]string{
+	TypeFloat:            "Float",
+	TypeString:           "String",
+	TypeEmbeddedDocument: "EmbeddedDocument",
+	TypeArray:            "Array",
+	TypeBinary:           "Binary",
+	TypeUndefined:        "Undefined",
+	TypeObjectId:         "ObjectId",
+	TypeBool:             "Bool",
+	TypeUTCDateTime:      "UTCDateTime",
+	TypeNull:             "Null",
+	TypeRegexp:           "Regexp",
+	TypeDBPointer:        "DBPointer",
+	TypeJavascript:       "Javascript",
+	TypeSymbol:           "Symbol",
+	TypeJavascriptScope:  "JavascriptScope",
+	TypeInt32:            "Int32",
+	TypeTimestamp:        "Timestamp",
+	TypeInt64:            "Int64",
+	TypeMinKey:           "MinKey",
+	TypeMaxKey:           "MaxKey",
+}
+
+// String returns the name of the type, which is the name of the Go type used
+// for it (e.g. "Int32").
+func (this Type) String() string {
+	if name, ok := typeNames[this]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(0x%02X)", byte(this))
+}
+
+// typeOf returns the BSON type a value is encoded as. Returns false if the
+// value isn't a BSON type or a Go type which is encoded as one.
+func typeOf(v interface{}) (Type, bool) {
+	switch v.(type) {
+	case nil, Null:
+		return TypeNull, true
+	case Float, float64:
+		return TypeFloat, true
+	case String, string:
+		return TypeString, true
+	case Map, Slice, BSON:
+		return TypeEmbeddedDocument, true
+	case Array:
+		return TypeArray, true
+	case Binary, []byte:
+		return TypeBinary, true
+	case Undefined:
+		return TypeUndefined, true
+	case ObjectId:
+		return TypeObjectId, true
+	case Bool, bool:
+		return TypeBool, true
+	case UTCDateTime, time.Time:
+		return TypeUTCDateTime, true
+	case Regexp:
+		return TypeRegexp, true
+	case DBPointer:
+		return TypeDBPointer, true
+	case Javascript:
+		return TypeJavascript, true
+	case Symbol:
+		return TypeSymbol, true
+	case JavascriptScope:
+		return TypeJavascriptScope, true
+	case Int32, int8, int16, int32:
+		return TypeInt32, true
+	case Timestamp:
+		return TypeTimestamp, true
+	case Int64, int64, int:
+		return TypeInt64, true
+	case MinKey:
+		return TypeMinKey, true
+	case MaxKey:
+		return TypeMaxKey, true
+	}
+	return 0, false
+}
+
 // BSON type.
 type Float float64
 
@@ -40,6 +154,17 @@ type Array []interface{}
 // BSON type.
 type Binary []byte
 
+// NewBinary returns Binary copied from b. Returns error if b is too large to
+// fit in a document.
+func NewBinary(b []byte) (Binary, error) {
+	if len(b) > maxDocLen {
+		return nil, fmt.Errorf("Binary exceeded maximum size, %v bytes.", len(b))
+	}
+	bin := make(Binary, len(b))
+	copy(bin, b)
+	return bin, nil
+}
+
 // BSON type. Value is ignored.
 type Undefined struct{}
 
@@ -52,6 +177,16 @@ type Bool bool
 // BSON type. Milliseconds since unix epoch.
 type UTCDateTime int64
 
+// NewUTCDateTime returns the UTCDateTime for t, truncated to milliseconds.
+func NewUTCDateTime(t time.Time) UTCDateTime {
+	return UTCDateTime(t.UnixMilli())
+}
+
+// Time returns the UTCDateTime as a time.Time in UTC.
+func (this UTCDateTime) Time() time.Time {
+	return time.UnixMilli(int64(this)).UTC()
+}
+
 // BSON type. Value is ignored.
 type Null struct{}
 
@@ -61,6 +196,33 @@ type Regexp struct {
 	Options string
 }
 
+// regexpOptions are the valid Regexp options, in the order the spec requires.
+const regexpOptions = "ilmsux"
+
+// NewRegexp returns a Regexp. Returns error if the pattern contains a null
+// byte or if the options contain anything other than the characters "ilmsux".
+// Options are stored in alphabetical order as the spec requires.
+func NewRegexp(pattern, options string) (Regexp, error) {
+	if strings.IndexByte(pattern, 0x00) != -1 {
+		return Regexp{}, errors.New("Regexp pattern must not contain null byte.")
+	}
+	var set [len(regexpOptions)]bool
+	for _, c := range options {
+		i := strings.IndexRune(regexpOptions, c)
+		if i == -1 {
+			return Regexp{}, fmt.Errorf("Regexp option '%c' not supported.", c)
+		}
+		set[i] = true
+	}
+	sorted := make([]byte, 0, len(regexpOptions))
+	for i, ok := range set {
+		if ok {
+			sorted = append(sorted, regexpOptions[i])
+		}
+	}
+	return Regexp{Pattern: pattern, Options: string(sorted)}, nil
+}
+
 // BSON type.
 type DBPointer struct {
 	Name     string
@@ -73,18 +235,41 @@ type Javascript string
 // BSON type.
 type Symbol string
 
-// BSON type. The Scope must be a BSON doc.
+// BSON type. The Scope is a Map, Slice or BSON, a nil Scope is encoded as an
+// empty document. When decoding the Scope is the same type as the document
+// it's in, so a Slice keeps the order of the scope.
 type JavascriptScope struct {
 	Javascript string
-	Scope      Map
+	Scope      Doc
 }
 
 // BSON type.
 type Int32 int32
 
-// BSON type.
+// BSON type. The high 32 bits are seconds since unix epoch, the low 32 bits
+// are an increment to order operations within the same second.
 type Timestamp int64
 
+// NewTimestamp returns the Timestamp with seconds t and increment i.
+func NewTimestamp(t, i uint32) Timestamp {
+	return Timestamp(uint64(t)<<32 | uint64(i))
+}
+
+// T returns the seconds since unix epoch.
+func (this Timestamp) T() uint32 {
+	return uint32(uint64(this) >> 32)
+}
+
+// I returns the increment.
+func (this Timestamp) I() uint32 {
+	return uint32(this)
+}
+
+// Time returns the seconds as a time.Time in UTC. The increment is ignored.
+func (this Timestamp) Time() time.Time {
+	return time.Unix(int64(this.T()), 0).UTC()
+}
+
 // BSON type.
 type Int64 int64
 
@@ -0,0 +1,263 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+/*
+Package wire reads and writes MongoDB wire protocol messages, for building
+proxies and protocol analyzers. Documents are BSON from package bson.
+
+Every message starts with a Header. OP_MSG messages are read and written with
+ReadMsg and WriteMsg, other messages can be read with ReadMessage and their
+payload parsed by the caller. For example, a proxy which logs commands:
+
+	for {
+		hdr, msg, err := wire.ReadMsg(client)
+		if err != nil {
+			return err
+		}
+		log.Println(msg.Body)
+		if err := wire.WriteMsg(server, hdr.RequestID, hdr.ResponseTo,
+			msg); err != nil {
+
+			return err
+		}
+	}
+*/
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"github.com/sbunce/bson"
+)
+
+// MaxMessageSize is the largest message which is read, the same as the default
+// maxMessageSizeBytes of the server.
+const MaxMessageSize = 48000000
+
+// HeaderSize is the encoded size of a Header.
+const HeaderSize = 16
+
+// OpCode is the type of a message.
+type OpCode int32
+
+// Message types.
+const (
+	OpReply       OpCode = 1
+	OpUpdate      OpCode = 2001
+	OpInsert      OpCode = 2002
+	OpQuery       OpCode = 2004
+	OpGetMore     OpCode = 2005
+	OpDelete      OpCode = 2006
+	OpKillCursors OpCode = 2007
+	OpCompressed  OpCode = 2012
+	OpMsg         OpCode = 2013
+)
+
+// Header is the start of every message.
+type Header struct {
+	Length     int32 // Size of the message, including the header.
+	RequestID  int32
+	ResponseTo int32 // RequestID of the request, for a reply.
+	OpCode     OpCode
+}
+
+// ReadHeader reads a Header. Returns error if the length isn't valid.
+func ReadHeader(rd io.Reader) (Header, error) {
+	var b [HeaderSize]byte
+	if _, err := io.ReadFull(rd, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Header %w.", bson.ErrTruncated)
+		}
+		return Header{}, err
+	}
+	hdr := Header{
+		Length:     int32(binary.LittleEndian.Uint32(b[0:])),
+		RequestID:  int32(binary.LittleEndian.Uint32(b[4:])),
+		ResponseTo: int32(binary.LittleEndian.Uint32(b[8:])),
+		OpCode:     OpCode(binary.LittleEndian.Uint32(b[12:])),
+	}
+	if hdr.Length < HeaderSize || hdr.Length > MaxMessageSize {
+		return Header{}, fmt.Errorf("Message length %v invalid.", hdr.Length)
+	}
+	return hdr, nil
+}
+
+// AppendHeader appends the encoded Header to b.
+func AppendHeader(b []byte, hdr Header) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(hdr.Length))
+	b = binary.LittleEndian.AppendUint32(b, uint32(hdr.RequestID))
+	b = binary.LittleEndian.AppendUint32(b, uint32(hdr.ResponseTo))
+	return binary.LittleEndian.AppendUint32(b, uint32(hdr.OpCode))
+}
+
+// ReadMessage reads a message of any type. Returns the header and the rest of
+// the message.
+func ReadMessage(rd io.Reader) (Header, []byte, error) {
+	hdr, err := ReadHeader(rd)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	payload := make([]byte, hdr.Length-HeaderSize)
+	if _, err := io.ReadFull(rd, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("Message %w.", bson.ErrTruncated)
+		}
+		return Header{}, nil, err
+	}
+	return hdr, payload, nil
+}
+
+// MsgFlags are the flag bits of a OP_MSG.
+type MsgFlags uint32
+
+const (
+	// ChecksumPresent means the message ends with a CRC32C of the rest of it.
+	ChecksumPresent MsgFlags = 1 << 0
+
+	// MoreToCome means another message follows without a reply.
+	MoreToCome MsgFlags = 1 << 1
+
+	// ExhaustAllowed means the client accepts multiple replies.
+	ExhaustAllowed MsgFlags = 1 << 16
+
+	// msgRequiredFlags are the bits which must be understood.
+	msgRequiredFlags MsgFlags = 0xFFFF
+	msgKnownFlags             = ChecksumPresent | MoreToCome | ExhaustAllowed
+)
+
+// Msg is a OP_MSG. The sections are the Body, which is the kind 0 section, and
+// the Sequences, which are kind 1 sections.
+type Msg struct {
+	// Flags of the message. ChecksumPresent is set by WriteMsg if Checksum is
+	// true, and cleared by ReadMsg after the checksum is checked.
+	Flags MsgFlags
+
+	// Checksum makes WriteMsg append a checksum. ReadMsg sets it if the message
+	// had one.
+	Checksum bool
+
+	Body      bson.BSON
+	Sequences []*bson.DocumentSequence
+}
+
+// castagnoli is the CRC32C table for checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ReadMsg reads a OP_MSG. Returns error if the message is a different type,
+// has a unknown required flag, doesn't have exactly one body, or has a
+// checksum which doesn't match.
+func ReadMsg(rd io.Reader) (Header, *Msg, error) {
+	hdr, payload, err := ReadMessage(rd)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	if hdr.OpCode != OpMsg {
+		return Header{}, nil, fmt.Errorf("OpCode %v not OP_MSG.", hdr.OpCode)
+	}
+	msg, err := ParseMsg(hdr, payload)
+	if err != nil {
+		return Header{}, nil, err
+	}
+	return hdr, msg, nil
+}
+
+// ParseMsg parses the payload of a OP_MSG, as returned by ReadMessage.
+func ParseMsg(hdr Header, payload []byte) (*Msg, error) {
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("OP_MSG %w.", bson.ErrTruncated)
+	}
+	msg := &Msg{Flags: MsgFlags(binary.LittleEndian.Uint32(payload))}
+	if unknown := msg.Flags & msgRequiredFlags &^ msgKnownFlags; unknown != 0 {
+		return nil, fmt.Errorf("OP_MSG required flags 0x%X unknown.",
+			uint32(unknown))
+	}
+	sections := payload[4:]
+	if msg.Flags&ChecksumPresent != 0 {
+		if len(sections) < 4 {
+			return nil, fmt.Errorf("OP_MSG checksum %w.", bson.ErrTruncated)
+		}
+		n := len(sections) - 4
+		want := binary.LittleEndian.Uint32(sections[n:])
+		crc := crc32.Update(0, castagnoli, AppendHeader(nil, hdr))
+		crc = crc32.Update(crc, castagnoli, payload[:len(payload)-4])
+		if crc != want {
+			return nil, errors.New("OP_MSG checksum mismatch.")
+		}
+		sections = sections[:n]
+		msg.Flags &^= ChecksumPresent
+		msg.Checksum = true
+	}
+	rd := bytes.NewReader(sections)
+	for rd.Len() > 0 {
+		kind, _ := rd.ReadByte()
+		switch kind {
+		case 0:
+			if msg.Body != nil {
+				return nil, errors.New("OP_MSG has more than one body.")
+			}
+			body, err := bson.ReadOne(rd)
+			if err != nil {
+				return nil, fmt.Errorf("OP_MSG body, %w", err)
+			}
+			msg.Body = body
+		case 1:
+			seq, err := bson.ReadDocumentSequence(rd)
+			if err != nil {
+				return nil, fmt.Errorf("OP_MSG sequence, %w", err)
+			}
+			msg.Sequences = append(msg.Sequences, seq)
+		default:
+			return nil, fmt.Errorf("OP_MSG section kind %v unknown.", kind)
+		}
+	}
+	if msg.Body == nil {
+		return nil, errors.New("OP_MSG has no body.")
+	}
+	return msg, nil
+}
+
+// WriteMsg writes a OP_MSG. The body is written first, then the sequences.
+func WriteMsg(wr io.Writer, requestID, responseTo int32, msg *Msg) error {
+	if msg.Body == nil {
+		return errors.New("OP_MSG has no body.")
+	}
+	flags := msg.Flags &^ ChecksumPresent
+	if msg.Checksum {
+		flags |= ChecksumPresent
+	}
+	head := make([]byte, HeaderSize, HeaderSize+4+1+len(msg.Body))
+	head = binary.LittleEndian.AppendUint32(head, uint32(flags))
+	buf := bytes.NewBuffer(head)
+	buf.WriteByte(0)
+	buf.Write(msg.Body)
+	for _, seq := range msg.Sequences {
+		buf.WriteByte(1)
+		if _, err := seq.WriteTo(buf); err != nil {
+			return err
+		}
+	}
+	n := buf.Len()
+	if msg.Checksum {
+		n += 4
+	}
+	if n > MaxMessageSize {
+		return fmt.Errorf("Message length %v too large.", n)
+	}
+	b := buf.Bytes()
+	AppendHeader(b[:0], Header{
+		Length:     int32(n),
+		RequestID:  requestID,
+		ResponseTo: responseTo,
+		OpCode:     OpMsg,
+	})
+	if msg.Checksum {
+		b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, castagnoli))
+	}
+	_, err := wr.Write(b)
+	return err
+}
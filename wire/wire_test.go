@@ -0,0 +1,93 @@
+// Copyright 2013 Seth Bunce. All rights reserved. Use of this source code is
+// governed by a BSD-style license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sbunce/bson"
+)
+
+func TestMsg(t *testing.T) {
+	seq := &bson.DocumentSequence{Identifier: "documents"}
+	seq.Add(bson.Map{"a": bson.Int32(1)})
+	seq.Add(bson.Map{"a": bson.Int32(2)})
+	for _, checksum := range []bool{false, true} {
+		msg := &Msg{
+			Flags:     MoreToCome,
+			Checksum:  checksum,
+			Body:      bson.Map{"insert": bson.String("c")}.MustEncode(),
+			Sequences: []*bson.DocumentSequence{seq},
+		}
+		buf := bytes.NewBuffer(nil)
+		if err := WriteMsg(buf, 7, 3, msg); err != nil {
+			t.Fatal(err)
+		}
+		b := append([]byte(nil), buf.Bytes()...)
+		hdr, msg1, err := ReadMsg(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := Header{Length: int32(len(b)), RequestID: 7, ResponseTo: 3,
+			OpCode: OpMsg}
+		if hdr != expect {
+			t.Fatal(hdr)
+		}
+		if !reflect.DeepEqual(msg, msg1) {
+			t.Fatal(msg, msg1)
+		}
+
+		// Corrupt.
+		b[len(b)-5]++
+		_, _, err = ReadMsg(bytes.NewReader(b))
+		if checksum && err == nil {
+			t.Fatal("Expected checksum error.")
+		}
+	}
+}
+
+func TestMsgErrors(t *testing.T) {
+	buf := bytes.NewBuffer(nil)
+	body := bson.Map{}.MustEncode()
+	WriteMsg(buf, 1, 0, &Msg{Body: body})
+	b := buf.Bytes()
+
+	// Unknown required flag.
+	bad := append([]byte(nil), b...)
+	binary.LittleEndian.PutUint32(bad[HeaderSize:], 1<<2)
+	if _, _, err := ReadMsg(bytes.NewReader(bad)); err == nil {
+		t.Fatal("Expected error.")
+	}
+
+	// Unknown optional flag is fine.
+	binary.LittleEndian.PutUint32(bad[HeaderSize:], 1<<20)
+	if _, _, err := ReadMsg(bytes.NewReader(bad)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Truncated.
+	_, _, err := ReadMsg(bytes.NewReader(b[:len(b)-1]))
+	if !errors.Is(err, bson.ErrTruncated) {
+		t.Fatal(err)
+	}
+
+	// Not OP_MSG.
+	bad = AppendHeader(nil, Header{Length: HeaderSize, OpCode: OpQuery})
+	if _, _, err := ReadMsg(bytes.NewReader(bad)); err == nil {
+		t.Fatal("Expected error.")
+	}
+	hdr, payload, err := ReadMessage(bytes.NewReader(bad))
+	if err != nil || hdr.OpCode != OpQuery || len(payload) != 0 {
+		t.Fatal(hdr, payload, err)
+	}
+
+	// No body.
+	if err := WriteMsg(buf, 1, 0, &Msg{}); err == nil {
+		t.Fatal("Expected error.")
+	}
+}